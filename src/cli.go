@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+const cliUsage = `usage:
+  main                                             サーバーを起動する
+  main api-key issue -name <名前> -scopes <スコープ>  APIキーを発行する（read, write-logs, admin をカンマ区切り）
+  main api-key revoke -name <名前>                  APIキーを失効させる
+  main api-key list                                有効なAPIキーを一覧表示する
+  main evaluate [-from 日付] [-to 日付] [-event ID] [-model gmm|kde|histogram] [-weeks 週数 | -since 日付] [-json]
+                                                   過去のログで活動予測を評価する（デフォルト: 昨日までの8週間）`
+
+// runCLI はサブコマンドを実行し、終了コードを返す
+func runCLI(args []string) int {
+	switch args[0] {
+	case "api-key":
+		return runAPIKeyCommand(args[1:])
+	case "evaluate":
+		return runEvaluateCommand(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+}
+
+// runAPIKeyCommand は api-key サブコマンドを実行する
+func runAPIKeyCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("api-key "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "APIキーの名前")
+	scopes := fs.String("scopes", "", "スコープ（カンマ区切り）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch args[0] {
+	case "issue":
+		key, plain, err := service.IssueAPIKey(*name, []string{*scopes}, "cli")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to issue api key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("issued api key %q (scopes: %s)\n", key.Name, key.Scopes)
+		fmt.Println("this key will not be shown again:")
+		fmt.Println(plain)
+	case "revoke":
+		key, err := service.RevokeAPIKey(*name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to revoke api key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("revoked api key %q\n", key.Name)
+	case "list":
+		keys, err := service.ListAPIKeys()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list api keys: %v\n", err)
+			return 1
+		}
+		for _, key := range keys {
+			lastUsed := "-"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.In(lib.JST).Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%-24s %s…  %-24s last used: %s\n", key.Name, key.Prefix, key.Scopes, lastUsed)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+	return 0
+}
+
+// runEvaluateCommand は evaluate サブコマンドを実行する
+func runEvaluateCommand(args []string) int {
+	fs := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	fromStr := fs.String("from", "", "評価する期間の開始日（YYYY-MM-DD）")
+	toStr := fs.String("to", "", "評価する期間の終了日（YYYY-MM-DD）")
+	eventID := fs.Uint("event", 0, "評価するイベントID（0: 全イベント）")
+	predictionModel := fs.String("model", "", "イベントの設定の代わりに使うモデル（gmm, kde, histogram）")
+	weeks := fs.Int("weeks", 0, "直近何週間のログから計算するか")
+	since := fs.String("since", "", "この日以降のログから計算する（YYYY-MM-DD）")
+	asJSON := fs.Bool("json", false, "結果をJSONで出力する")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	to := lib.NowJST().AddDate(0, 0, -1)
+	if *toStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", *toStr, lib.JST)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -to: %s\n", *toStr)
+			return 2
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(service.DefaultEvaluationDays - 1))
+	if *fromStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", *fromStr, lib.JST)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -from: %s\n", *fromStr)
+			return 2
+		}
+		from = t
+	}
+	lookback := service.DefaultLookback()
+	if *weeks != 0 || *since != "" {
+		l, err := service.NewLookback(*weeks, *since)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		lookback = l
+	}
+
+	report, err := service.EvaluatePredictions(from, to, *eventID, lookback, *predictionModel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to evaluate predictions: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printEvaluationReport(report)
+	return 0
+}
+
+// printEvaluationReport は評価結果を表形式で出力する
+func printEvaluationReport(report service.EvaluationReport) {
+	fmt.Printf("period: %s - %s (probability at %s, threshold %.2f)\n\n", report.From, report.To, report.ProbabilityTime, report.ProbabilityThreshold)
+	fmt.Printf("%-24s %-18s %5s %5s %9s %7s %7s %9s\n", "event", "model", "days", "occur", "base_rate", "brier", "skill", "range_hit")
+	for _, e := range report.Events {
+		fmt.Printf("%-24s %-18s %5d %5d %9.2f %7.3f %7.3f %4d/%-4d\n",
+			e.EventName, e.PredictionModel+"/"+e.TimeModel, e.Days, e.Occurrences, e.BaseRate, e.BrierScore, e.BrierSkillScore, e.RangeHits, e.RangeSamples)
+	}
+	o := report.Overall
+	fmt.Printf("%-24s %-18s %5d %5d %9.2f %7.3f %7.3f %4d/%-4d\n",
+		"(overall)", "", o.Days, o.Occurrences, o.BaseRate, o.BrierScore, o.BrierSkillScore, o.RangeHits, o.RangeSamples)
+
+	fmt.Println("\nthresholds (overall):")
+	fmt.Printf("  %9s %8s %5s %9s %7s\n", "threshold", "notified", "hits", "precision", "recall")
+	for _, th := range o.Thresholds {
+		fmt.Printf("  %9.1f %8d %5d %9.2f %7.2f\n", th.Threshold, th.Notified, th.Hits, th.Precision, th.Recall)
+	}
+
+	fmt.Println("\ncalibration (overall):")
+	fmt.Printf("  %-9s %5s %9s %8s\n", "predicted", "count", "mean", "observed")
+	for _, bin := range o.Calibration {
+		fmt.Printf("  %.1f-%.1f   %5d %9.2f %8.2f\n", bin.Lower, bin.Upper, bin.Count, bin.MeanPredicted, bin.ObservedRate)
+	}
+}
@@ -15,15 +15,22 @@ const (
 	msgBatchRegistrationCompleted = "batch registration completed"
 )
 
+// maxIdempotencyKeyLength は重複排除キーの最大長（logs.idempotency_key の varchar(255) に合わせる）
+const maxIdempotencyKeyLength = 255
+
 // RegisterStatusesRequest はStatus一括登録のリクエストボディ
 type RegisterStatusesRequest struct {
 	Names []string `json:"names" binding:"required,min=1"`
 }
 
 // LogEntry はログ登録リクエストの1エントリを表す
+// イベントは event_id または event_code、ステータスは status_id または status のいずれかで指定する
 type LogEntry struct {
-	EventID          string  `json:"event_id" binding:"required"`
-	StatusID         uint    `json:"status_id" binding:"required"`
+	EventID          string  `json:"event_id"`   // 内部のイベントID
+	EventCode        string  `json:"event_code"` // デバイスに設定されたイベントコード（例: 0437ac48be2a81）
+	StatusID         uint    `json:"status_id"`
+	Status           string  `json:"status"`                        // ステータス名（例: "start"）
+	IdempotencyKey   string  `json:"idempotency_key"`               // 再送時に同じ値を送ると重複登録されない（省略可）
 	EventTime        string  `json:"event_time" binding:"required"` // RFC3339形式 JST (例: "2006-01-02T15:04:05+09:00")
 	ParticipateUsers []int64 `json:"participate_users"`             // 参加メンバの stay_watch_id（空可）
 	RoomUsers        []int64 `json:"room_users"`                    // 在室メンバの stay_watch_id（空可）
@@ -31,7 +38,8 @@ type LogEntry struct {
 
 // RegisterLogsRequest はログ一括登録のリクエストボディ
 type RegisterLogsRequest struct {
-	Logs []LogEntry `json:"logs" binding:"required,min=1"`
+	Logs   []LogEntry `json:"logs" binding:"required,min=1"`
+	Strict *bool      `json:"strict"` // false の場合、状態遷移が不正なログも警告付きで登録する（過去データの取り込み用。デフォルト: true）
 }
 
 // GetEvents はEvent一覧を取得するAPIハンドラー
@@ -40,6 +48,7 @@ type RegisterLogsRequest struct {
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/events [get]
 func GetEvents(c *gin.Context) {
 	events, err := service.GetEvents()
@@ -59,6 +68,7 @@ func GetEvents(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/statuses [get]
 func GetStatuses(c *gin.Context) {
 	statuses, err := service.GetStatuses()
@@ -81,6 +91,7 @@ func GetStatuses(c *gin.Context) {
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/statuses [post]
 func PostRegisterStatuses(c *gin.Context) {
 	var req RegisterStatusesRequest
@@ -107,11 +118,17 @@ func PostRegisterStatuses(c *gin.Context) {
 // @Tags events
 // @Produce json
 // @Param id path int true "イベントID"
-// @Param weekday query int true "曜日 (MySQL WEEKDAY形式: 0=月, 6=日)"
+// @Param weekday query int false "曜日 (MySQL WEEKDAY形式: 0=月, 6=日)。weekday または date のどちらかが必須"
+// @Param date query string false "対象日 (YYYY-MM-DD, JST)。指定した場合は曜日と学事暦上の扱いを返す"
 // @Param time query string false "時刻 (HH:MM形式, JST。デフォルト: 現在時刻)"
+// @Param weeks query int false "直近何週間のログから計算するか（since と同時に指定不可）"
+// @Param since query string false "この日以降のログから計算する (YYYY-MM-DD, JST)"
+// @Param details query bool false "モデルの概要と確率の信頼区間を含めるか（デフォルト: false）"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/events/{id}/probability [get]
 func GetEventProbability(c *gin.Context) {
 	// パスパラメータからイベントIDを取得
@@ -122,19 +139,11 @@ func GetEventProbability(c *gin.Context) {
 		return
 	}
 
-	// クエリパラメータから曜日を取得（必須）
-	weekdayStr := c.Query("weekday")
-	if weekdayStr == "" {
-		respondError(c, http.StatusBadRequest, "weekday parameter is required")
-		return
-	}
-	weekdayInt, err := strconv.Atoi(weekdayStr)
-	if err != nil || weekdayInt < 0 || weekdayInt > 6 {
-		respondError(c, http.StatusBadRequest, "weekday must be 0-6 (Monday=0, Sunday=6)")
+	// クエリパラメータから曜日または日付を取得（必須）
+	day, ok := parseTargetDay(c, true)
+	if !ok {
 		return
 	}
-	// MySQL WEEKDAY形式(月=0)からGoのtime.Weekday形式(日=0)に変換
-	weekday := time.Weekday((weekdayInt + 1) % 7)
 
 	// クエリパラメータから時刻を取得（オプション、デフォルトは現在時刻JST）
 	targetTimeJST := c.DefaultQuery("time", lib.NowJST().Format("15:04"))
@@ -145,56 +154,102 @@ func GetEventProbability(c *gin.Context) {
 		return
 	}
 
-	// 確率を取得
-	probability, err := service.GetActivityProbability(uint(eventID), weekday, targetTimeJST)
+	lookback, ok := parseLookback(c)
+	if !ok {
+		return
+	}
+
+	withDetails, ok := parseDetails(c)
+	if !ok {
+		return
+	}
+
+	event, err := service.GetEvent(uint(eventID))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	// 確率を取得（details=true の場合はモデルの概要と信頼区間も取得する）
+	var probability float64
+	var details *service.PredictionDetails
+	if withDetails {
+		probability, details, err = service.GetActivityProbabilityWithDetails(event, day.weekday, targetTimeJST, lookback)
+	} else {
+		probability, err = service.GetActivityProbability(event, day.weekday, targetTimeJST, lookback)
+	}
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"event_id":    eventID,
-		"weekday":     weekdayInt,
-		"time":        targetTimeJST,
-		"probability": probability,
-	})
+	body := gin.H{
+		"event_id":         eventID,
+		"weekday":          day.mysqlWeekday(),
+		"time":             targetTimeJST,
+		"probability":      probability,
+		"time_model":       event.TimeModel,
+		"prediction_model": event.PredictionModel,
+		"estimator":        service.CurrentEstimator(),
+		"lookback":         lookback,
+	}
+	if withDetails {
+		body["details"] = details
+	}
+	response, err := day.withCalendar(body)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetAllActivityProbabilities は全活動の1時間ごとの発生確率を取得するAPIハンドラー
 // @Summary 全活動の時間帯別発生確率を取得
 // @Tags activities
 // @Produce json
-// @Param weekday query int false "曜日 (MySQL WEEKDAY形式: 0=月, 6=日)。省略時は今日の曜日"
+// @Param weekday query int false "曜日 (MySQL WEEKDAY形式: 0=月, 6=日)。weekday と date を省略した場合は今日の曜日"
+// @Param date query string false "対象日 (YYYY-MM-DD, JST)。指定した場合は曜日と学事暦上の扱いを返す"
+// @Param weeks query int false "直近何週間のログから計算するか（since と同時に指定不可）"
+// @Param since query string false "この日以降のログから計算する (YYYY-MM-DD, JST)"
+// @Param details query bool false "各活動にモデルの概要と確率の信頼区間を含めるか（デフォルト: false）"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/activities/probabilities [get]
 func GetAllActivityProbabilities(c *gin.Context) {
-	var weekday time.Weekday
+	day, ok := parseTargetDay(c, false)
+	if !ok {
+		return
+	}
 
-	weekdayStr := c.Query("weekday")
-	if weekdayStr == "" {
-		// デフォルト: 今日の曜日（JST）
-		weekday = lib.NowJST().Weekday()
-	} else {
-		weekdayInt, err := strconv.Atoi(weekdayStr)
-		if err != nil || weekdayInt < 0 || weekdayInt > 6 {
-			respondError(c, http.StatusBadRequest, "weekday must be 0-6 (Monday=0, Sunday=6)")
-			return
-		}
-		// MySQL WEEKDAY形式(月=0)からGoのtime.Weekday形式(日=0)に変換
-		weekday = time.Weekday((weekdayInt + 1) % 7)
+	lookback, ok := parseLookback(c)
+	if !ok {
+		return
+	}
+
+	withDetails, ok := parseDetails(c)
+	if !ok {
+		return
 	}
 
-	results, err := service.GetAllActivityProbabilities(weekday)
+	results, err := service.GetAllActivityProbabilities(day.weekday, lookback, withDetails)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": results,
+	response, err := day.withCalendar(gin.H{
+		"data":      results,
+		"estimator": service.CurrentEstimator(),
+		"lookback":  lookback,
 	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // PostRegisterLogs はログを一括登録するAPIハンドラー
@@ -206,6 +261,7 @@ func GetAllActivityProbabilities(c *gin.Context) {
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/logs [post]
 func PostRegisterLogs(c *gin.Context) {
 	var req RegisterLogsRequest
@@ -217,30 +273,52 @@ func PostRegisterLogs(c *gin.Context) {
 	// リクエストをサービス層の入力形式に変換
 	inputs := make([]service.LogEntryInput, len(req.Logs))
 	for i, entry := range req.Logs {
-		eventID, err := strconv.ParseUint(entry.EventID, 10, 32)
-		if err != nil {
-			respondError(c, http.StatusBadRequest, "invalid event_id")
+		if entry.EventID == "" && entry.EventCode == "" {
+			respondError(c, http.StatusBadRequest, "event_id or event_code is required")
+			return
+		}
+		if entry.StatusID == 0 && entry.Status == "" {
+			respondError(c, http.StatusBadRequest, "status_id or status is required")
 			return
 		}
+		if len(entry.IdempotencyKey) > maxIdempotencyKeyLength {
+			respondError(c, http.StatusBadRequest, "idempotency_key is too long")
+			return
+		}
+		var eventID uint64
+		if entry.EventID != "" {
+			id, err := strconv.ParseUint(entry.EventID, 10, 32)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "invalid event_id")
+				return
+			}
+			eventID = id
+		}
 		inputs[i] = service.LogEntryInput{
 			EventID:                 uint(eventID),
+			EventCode:               entry.EventCode,
 			StatusID:                entry.StatusID,
+			StatusName:              entry.Status,
+			IdempotencyKey:          entry.IdempotencyKey,
 			EventTime:               entry.EventTime,
 			ParticipateStayWatchIDs: entry.ParticipateUsers,
 			RoomStayWatchIDs:        entry.RoomUsers,
 		}
 	}
 
-	logs, errors, err := service.BatchRegisterLogs(inputs)
+	strict := req.Strict == nil || *req.Strict
+	result, err := service.BatchRegisterLogs(inputs, strict)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": msgBatchRegistrationCompleted,
-		"data":    logs,
-		"errors":  errors,
+		"message":      msgBatchRegistrationCompleted,
+		"data":         result.Logs,
+		"errors":       result.Errors,
+		"deduplicated": result.Deduplicated,
+		"warnings":     result.Warnings,
 	})
 }
 
@@ -248,11 +326,20 @@ func PostRegisterLogs(c *gin.Context) {
 // @Summary 共有モニター用の表示データを取得
 // @Tags board
 // @Produce json
+// @Param weeks query int false "活動確率の計算に使う直近の週数（since と同時に指定不可）"
+// @Param since query string false "活動確率の計算に使うログの開始日 (YYYY-MM-DD, JST)"
 // @Success 200 {object} service.BoardData
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
 // @Router /api/board [get]
 func GetBoard(c *gin.Context) {
-	board, err := service.GetBoardData()
+	lookback, ok := parseLookback(c)
+	if !ok {
+		return
+	}
+
+	board, err := service.GetBoardData(lookback)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// GetCalendar は学事暦（祝日・休業・試験期間など）の予定を取得するAPIハンドラー
+// @Summary 学事暦の予定を取得
+// @Tags calendar
+// @Produce json
+// @Param from query string false "開始日 (YYYY-MM-DD, JST)。この日以降に終わる予定を返す"
+// @Param to query string false "終了日 (YYYY-MM-DD, JST)。この日までに始まる予定を返す"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/calendar [get]
+func GetCalendar(c *gin.Context) {
+	var from, until time.Time
+	if s := c.Query("from"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid from format (expected YYYY-MM-DD)")
+			return
+		}
+		from = t
+	}
+	if s := c.Query("to"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid to format (expected YYYY-MM-DD)")
+			return
+		}
+		until = t
+	}
+	if !from.IsZero() && !until.IsZero() && from.After(until) {
+		respondError(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	entries, err := service.GetCalendarEntries(from, until)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": entries,
+	})
+}
+
+// PostImportCalendar は iCalendar ファイルの予定を学事暦として取り込むAPIハンドラー
+// @Summary 学事暦を iCalendar から取り込む
+// @Tags calendar
+// @Accept text/calendar
+// @Produce json
+// @Param kind query string false "CATEGORIES で種類を指定していない予定の種類 (holiday, break, exam, term。デフォルト: holiday)"
+// @Success 200 {object} service.CalendarImportResult
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/calendar/import [post]
+func PostImportCalendar(c *gin.Context) {
+	kind := c.DefaultQuery("kind", service.CalendarKindHoliday)
+	if !service.IsCalendarKind(kind) {
+		respondError(c, http.StatusBadRequest, "kind must be holiday, break, exam or term")
+		return
+	}
+
+	result, err := service.ImportCalendar(c.Request.Body, kind)
+	if err != nil {
+		// 途中まで登録した予定があるため、それまでの結果も返す
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"data":  result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// DeleteCalendarEntry は学事暦の予定を削除するAPIハンドラー
+// @Summary 学事暦の予定を削除
+// @Tags calendar
+// @Param id path int true "予定ID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/calendar/{id} [delete]
+func DeleteCalendarEntry(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid calendar entry id")
+		return
+	}
+
+	if err := service.DeleteCalendarEntry(id); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// GetPredictionEvaluation は過去のログを1日ずつ再現して活動予測を評価するAPIハンドラー
+// 期間の日数・イベント数に比例してモデルを当てはめ直し計算量が大きいため、admin スコープを必要とする
+// @Summary 活動予測の精度を評価（バックテスト）
+// @Tags activities
+// @Produce json
+// @Param from query string false "評価する期間の開始日 (YYYY-MM-DD, JST。デフォルト: to の55日前)"
+// @Param to query string false "評価する期間の終了日 (YYYY-MM-DD, JST。デフォルト: 昨日)"
+// @Param event_id query int false "評価するイベントID (デフォルト: 全イベント)"
+// @Param model query string false "イベントの設定の代わりに使う活動開始時刻のモデル (gmm, kde, histogram)"
+// @Param weeks query int false "直近何週間のログから計算するか（since と同時に指定不可）"
+// @Param since query string false "この日以降のログから計算する (YYYY-MM-DD, JST)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/prediction/evaluation [get]
+func GetPredictionEvaluation(c *gin.Context) {
+	to := lib.NowJST().AddDate(0, 0, -1)
+	if s := c.Query("to"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid to format (expected YYYY-MM-DD)")
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(service.DefaultEvaluationDays - 1))
+	if s := c.Query("from"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid from format (expected YYYY-MM-DD)")
+			return
+		}
+		from = t
+	}
+	if to.Before(from) {
+		respondError(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+	if to.Sub(from) >= time.Duration(service.MaxEvaluationDays)*24*time.Hour {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("evaluation period must be at most %d days", service.MaxEvaluationDays))
+		return
+	}
+
+	var eventID uint
+	if s := c.Query("event_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || id == 0 {
+			respondError(c, http.StatusBadRequest, "invalid event_id")
+			return
+		}
+		eventID = uint(id)
+	}
+	predictionModel := c.Query("model")
+	if predictionModel != "" && !prediction.IsModelKind(predictionModel) {
+		respondError(c, http.StatusBadRequest, "model must be gmm, kde or histogram")
+		return
+	}
+	lookback, ok := parseLookback(c)
+	if !ok {
+		return
+	}
+
+	report, err := service.EvaluatePredictions(from, to, eventID, lookback, predictionModel)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      report,
+		"estimator": service.CurrentEstimator(),
+		"lookback":  lookback,
+	})
+}
@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// CreateEventRequest はイベント登録のリクエストボディ
+type CreateEventRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Code            string `json:"code" binding:"required"`
+	MinNumber       int    `json:"min_number" binding:"required,min=1"`
+	TimeModel       string `json:"time_model" binding:"omitempty,oneof=linear circular"`         // 省略時は linear
+	PredictionModel string `json:"prediction_model" binding:"omitempty,oneof=gmm kde histogram"` // 省略時は gmm
+}
+
+// UpdateEventRequest はイベント更新のリクエストボディ（省略した項目は変更しない）
+type UpdateEventRequest struct {
+	Name            *string `json:"name" binding:"omitempty,min=1"`
+	Code            *string `json:"code" binding:"omitempty,min=1"`
+	MinNumber       *int    `json:"min_number" binding:"omitempty,min=1"`
+	TimeModel       *string `json:"time_model" binding:"omitempty,oneof=linear circular"`
+	PredictionModel *string `json:"prediction_model" binding:"omitempty,oneof=gmm kde histogram"`
+}
+
+// AddEventMemberRequest はイベントへのメンバー登録のリクエストボディ
+type AddEventMemberRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// GetEvent はイベントを取得するAPIハンドラー
+// @Summary イベントを取得
+// @Tags events
+// @Produce json
+// @Param id path int true "イベントID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id} [get]
+func GetEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	event, err := service.GetEvent(id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": event,
+	})
+}
+
+// PostEvent はイベントを登録するAPIハンドラー
+// @Summary イベントを登録
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param request body CreateEventRequest true "登録するイベント"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events [post]
+func PostEvent(c *gin.Context) {
+	var req CreateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, msgInvalidRequestBody)
+		return
+	}
+
+	event, err := service.RegisterEvent(req.Name, req.MinNumber, req.Code, req.TimeModel, req.PredictionModel)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": event,
+	})
+}
+
+// PatchEvent はイベントを更新するAPIハンドラー
+// @Summary イベントを更新
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param id path int true "イベントID"
+// @Param request body UpdateEventRequest true "更新する項目"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id} [patch]
+func PatchEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+	var req UpdateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, msgInvalidRequestBody)
+		return
+	}
+
+	event, err := service.UpdateEvent(id, service.EventUpdateInput{
+		Name:            req.Name,
+		Code:            req.Code,
+		MinNumber:       req.MinNumber,
+		TimeModel:       req.TimeModel,
+		PredictionModel: req.PredictionModel,
+	})
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": event,
+	})
+}
+
+// DeleteEvent はイベントを削除するAPIハンドラー
+// @Summary イベントを削除
+// @Tags events
+// @Param id path int true "イベントID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id} [delete]
+func DeleteEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	if err := service.DeleteEvent(id); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetEventMembers はイベントに登録されているユーザー一覧を取得するAPIハンドラー
+// @Summary イベントの登録メンバーを取得
+// @Tags events
+// @Produce json
+// @Param id path int true "イベントID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id}/members [get]
+func GetEventMembers(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	users, err := service.GetEventMembers(id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": users,
+	})
+}
+
+// PostEventMember はイベントにユーザーを登録するAPIハンドラー
+// @Summary イベントにメンバーを登録
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param id path int true "イベントID"
+// @Param request body AddEventMemberRequest true "登録するユーザー"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id}/members [post]
+func PostEventMember(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+	var req AddEventMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, msgInvalidRequestBody)
+		return
+	}
+
+	eventUser, err := service.AddEventMember(id, req.UserID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": eventUser,
+	})
+}
+
+// DeleteEventMember はイベントからユーザーの登録を解除するAPIハンドラー
+// @Summary イベントからメンバーを解除
+// @Tags events
+// @Param id path int true "イベントID"
+// @Param user_id path int true "ユーザーID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id}/members/{user_id} [delete]
+func DeleteEventMember(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if err := service.RemoveEventMember(id, userID); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// defaultSessionDays は from 省略時に遡る日数
+const defaultSessionDays = 30
+
+// GetEventSessions はイベントのログを活動セッションに再構成して取得するAPIハンドラー
+// @Summary イベントの活動セッションを取得
+// @Tags events
+// @Produce json
+// @Param id path int true "イベントID"
+// @Param from query string false "開始日 (YYYY-MM-DD, JST。デフォルト: to の30日前)"
+// @Param to query string false "終了日 (YYYY-MM-DD, JST。デフォルト: 今日)"
+// @Param weekday query int false "開始日の曜日で絞り込む (MySQL WEEKDAY形式: 0=月, 6=日)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/events/{id}/sessions [get]
+func GetEventSessions(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	to := lib.NowJST()
+	if s := c.Query("to"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid to format (expected YYYY-MM-DD)")
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -defaultSessionDays)
+	if s := c.Query("from"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid from format (expected YYYY-MM-DD)")
+			return
+		}
+		from = t
+	}
+	if to.Before(from) {
+		respondError(c, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	var weekday *time.Weekday
+	if s := c.Query("weekday"); s != "" {
+		weekdayInt, err := strconv.Atoi(s)
+		if err != nil || weekdayInt < 0 || weekdayInt > 6 {
+			respondError(c, http.StatusBadRequest, "weekday must be 0-6 (Monday=0, Sunday=6)")
+			return
+		}
+		// MySQL WEEKDAY形式(月=0)からGoのtime.Weekday形式(日=0)に変換
+		w := time.Weekday((weekdayInt + 1) % 7)
+		weekday = &w
+	}
+
+	sessions, summary, err := service.GetEventSessions(id, from, to, weekday)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    sessions,
+		"summary": summary,
+	})
+}
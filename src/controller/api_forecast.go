@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// defaultForecastDays は活動予測の既定の日数
+const defaultForecastDays = 7
+
+// GetForecast は指定日からの日ごと・イベントごとの活動予測を取得するAPIハンドラー
+// @Summary 日付ごとの活動予測を取得
+// @Tags activities
+// @Produce json
+// @Param from query string false "開始日 (YYYY-MM-DD, JST。デフォルト: 今日)"
+// @Param days query int false "日数 (1〜14, デフォルト: 7)"
+// @Param weeks query int false "直近何週間のログから計算するか（since と同時に指定不可）"
+// @Param since query string false "この日以降のログから計算する (YYYY-MM-DD, JST)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/forecast [get]
+func GetForecast(c *gin.Context) {
+	from := lib.NowJST()
+	if s := c.Query("from"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid from format (expected YYYY-MM-DD)")
+			return
+		}
+		from = t
+	}
+	days := defaultForecastDays
+	if s := c.Query("days"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 1 || d > service.MaxForecastDays {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", service.MaxForecastDays))
+			return
+		}
+		days = d
+	}
+	lookback, ok := parseLookback(c)
+	if !ok {
+		return
+	}
+
+	forecast, err := service.GetForecast(from, days, lookback)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      forecast,
+		"estimator": service.CurrentEstimator(),
+		"lookback":  lookback,
+	})
+}
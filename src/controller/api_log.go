@@ -0,0 +1,386 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// ログ一覧の取得件数
+const (
+	defaultLogLimit = 100
+	maxLogLimit     = 1000
+)
+
+// UpdateLogRequest はログ修正のリクエストボディ（省略した項目は変更しない）
+type UpdateLogRequest struct {
+	EventID          *uint    `json:"event_id" binding:"omitempty,min=1"`
+	EventCode        *string  `json:"event_code" binding:"omitempty,min=1"`
+	StatusID         *uint    `json:"status_id" binding:"omitempty,min=1"`
+	Status           *string  `json:"status" binding:"omitempty,min=1"`
+	EventTime        *string  `json:"event_time"`        // RFC3339形式 JST
+	RoomUsers        *[]int64 `json:"room_users"`        // 在室メンバの stay_watch_id（指定した場合は置き換える）
+	ParticipateUsers *[]int64 `json:"participate_users"` // 参加メンバの stay_watch_id（指定した場合は置き換える）
+}
+
+// GetLogs はログを検索するAPIハンドラー
+// @Summary ログを検索
+// @Tags logs
+// @Produce json
+// @Param event_id query int false "イベントID"
+// @Param status query string false "ステータス名 (start, end, pose)"
+// @Param user_id query int false "在室または参加しているユーザーID"
+// @Param participant_id query int false "参加しているユーザーID"
+// @Param from query string false "開始日 (YYYY-MM-DD, JST)"
+// @Param to query string false "終了日 (YYYY-MM-DD, JST)"
+// @Param include_deleted query bool false "削除済みのログも含める"
+// @Param cursor query int false "前のページの next_cursor"
+// @Param limit query int false "取得件数 (デフォルト: 100, 最大: 1000)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs [get]
+func GetLogs(c *gin.Context) {
+	filter, ok := bindLogFilter(c)
+	if !ok {
+		return
+	}
+	if s := c.Query("cursor"); s != "" {
+		cursor, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || cursor == 0 {
+			respondError(c, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		filter.Cursor = uint(cursor)
+	}
+	filter.Limit = defaultLogLimit
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > maxLogLimit {
+			respondError(c, http.StatusBadRequest, "limit must be an integer between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	logs, nextCursor, err := service.ListLogs(filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        logs,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetLog はログを在室・参加ユーザーとともに取得するAPIハンドラー
+// @Summary ログを取得
+// @Tags logs
+// @Produce json
+// @Param id path int true "ログID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs/{id} [get]
+func GetLog(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid log id")
+		return
+	}
+
+	logEntry, err := service.GetLog(id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logEntry,
+	})
+}
+
+// PatchLog はログを修正するAPIハンドラー
+// @Summary ログを修正
+// @Tags logs
+// @Accept json
+// @Produce json
+// @Param id path int true "ログID"
+// @Param request body UpdateLogRequest true "修正する項目"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs/{id} [patch]
+func PatchLog(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid log id")
+		return
+	}
+	var req UpdateLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, msgInvalidRequestBody)
+		return
+	}
+	if req.EventID != nil && req.EventCode != nil {
+		respondError(c, http.StatusBadRequest, "specify either event_id or event_code")
+		return
+	}
+	if req.StatusID != nil && req.Status != nil {
+		respondError(c, http.StatusBadRequest, "specify either status_id or status")
+		return
+	}
+	if req.EventTime != nil {
+		if _, err := lib.ParseJST(*req.EventTime); err != nil {
+			respondError(c, http.StatusBadRequest, "invalid event_time: "+err.Error())
+			return
+		}
+	}
+
+	logEntry, err := service.UpdateLog(id, service.LogUpdateInput{
+		EventID:                 req.EventID,
+		EventCode:               req.EventCode,
+		StatusID:                req.StatusID,
+		StatusName:              req.Status,
+		EventTime:               req.EventTime,
+		ParticipateStayWatchIDs: req.ParticipateUsers,
+		RoomStayWatchIDs:        req.RoomUsers,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownStayWatchID) {
+			respondError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logEntry,
+	})
+}
+
+// DeleteLog はログを論理削除するAPIハンドラー
+// @Summary ログを削除
+// @Tags logs
+// @Param id path int true "ログID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs/{id} [delete]
+func DeleteLog(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid log id")
+		return
+	}
+
+	if err := service.DeleteLog(id); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PostRestoreLog は論理削除したログを元に戻すAPIハンドラー
+// @Summary 削除したログを復元
+// @Tags logs
+// @Produce json
+// @Param id path int true "ログID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs/{id}/restore [post]
+func PostRestoreLog(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid log id")
+		return
+	}
+
+	logEntry, err := service.RestoreLog(id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logEntry,
+	})
+}
+
+// bindLogFilter はクエリパラメータからログの検索条件（cursor と limit を除く）を取得する
+// 不正な値の場合は 400 を返して false を返す
+func bindLogFilter(c *gin.Context) (model.LogFilter, bool) {
+	var filter model.LogFilter
+
+	idParams := []struct {
+		name string
+		dest *uint
+	}{
+		{"event_id", &filter.EventID},
+		{"user_id", &filter.UserID},
+		{"participant_id", &filter.ParticipantID},
+	}
+	for _, p := range idParams {
+		s := c.Query(p.name)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || id == 0 {
+			respondError(c, http.StatusBadRequest, "invalid "+p.name)
+			return filter, false
+		}
+		*p.dest = uint(id)
+	}
+
+	if s := c.Query("status"); s != "" {
+		filter.StatusNames = service.StatusNamesForFilter(s)
+	}
+	if s := c.Query("from"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid from format (expected YYYY-MM-DD)")
+			return filter, false
+		}
+		filter.From = t
+	}
+	if s := c.Query("to"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid to format (expected YYYY-MM-DD)")
+			return filter, false
+		}
+		filter.Until = t.AddDate(0, 0, 1)
+	}
+	if !filter.From.IsZero() && !filter.Until.IsZero() && !filter.From.Before(filter.Until) {
+		respondError(c, http.StatusBadRequest, "from must not be after to")
+		return filter, false
+	}
+	if s := c.Query("include_deleted"); s != "" {
+		includeDeleted, err := strconv.ParseBool(s)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "include_deleted must be true or false")
+			return filter, false
+		}
+		filter.IncludeDeleted = includeDeleted
+	}
+	return filter, true
+}
+
+// PostImportLogs はCSVまたはNDJSONのログを一括で取り込むAPIハンドラー
+// @Summary ログを一括で取り込む
+// @Tags logs
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "入力形式 (csv, ndjson。省略時は Content-Type から判定)"
+// @Param strict query bool false "状態遷移の検証を行う (デフォルト: true)"
+// @Success 200 {object} service.LogImportResult
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs/import [post]
+func PostImportLogs(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = service.LogFormatCSV
+		case "application/x-ndjson", "application/jsonl":
+			format = service.LogFormatNDJSON
+		}
+	}
+	if format != service.LogFormatCSV && format != service.LogFormatNDJSON {
+		respondError(c, http.StatusBadRequest, "format must be csv or ndjson")
+		return
+	}
+	strict := true
+	if s := c.Query("strict"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "strict must be true or false")
+			return
+		}
+		strict = b
+	}
+
+	result, err := service.ImportLogs(c.Request.Body, format, strict)
+	if err != nil {
+		// 途中まで登録した行があるため、それまでの結果も返す
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"data":  result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// GetExportLogs はログをCSVまたはNDJSONで書き出すAPIハンドラー
+// @Summary ログを書き出す
+// @Tags logs
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "出力形式 (csv, ndjson。デフォルト: csv)"
+// @Param event_id query int false "イベントID"
+// @Param status query string false "ステータス名 (start, end, pose)"
+// @Param user_id query int false "在室または参加しているユーザーID"
+// @Param participant_id query int false "参加しているユーザーID"
+// @Param from query string false "開始日 (YYYY-MM-DD, JST)"
+// @Param to query string false "終了日 (YYYY-MM-DD, JST)"
+// @Param include_deleted query bool false "削除済みのログも含める"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/logs/export [get]
+func GetExportLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", service.LogFormatCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case service.LogFormatCSV:
+	case service.LogFormatNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		respondError(c, http.StatusBadRequest, "format must be csv or ndjson")
+		return
+	}
+	filter, ok := bindLogFilter(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="logs-%s.%s"`, lib.NowJST().Format("20060102"), format))
+	c.Status(http.StatusOK)
+
+	// ヘッダー送信後はステータスコードを変えられないため、途中のエラーはログに記録するのみとする
+	if err := service.ExportLogs(c.Writer, format, filter, c.Writer.Flush); err != nil {
+		log.Printf("failed to export logs: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// defaultNotificationLimit は送信記録一覧の既定の取得件数
+const defaultNotificationLimit = 100
+
+// GetNotifications は通知DMの送信記録を取得するAPIハンドラー
+// @Summary 通知DMの送信記録を取得
+// @Tags notifications
+// @Produce json
+// @Param date query string false "通知対象日 (YYYY-MM-DD)"
+// @Param user_id query int false "ユーザーID"
+// @Param notification_id query int false "通知実行ID"
+// @Param status query string false "送信ステータス (pending, sent, retrying, failed)"
+// @Param limit query int false "取得件数 (デフォルト: 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/notifications [get]
+func GetNotifications(c *gin.Context) {
+	filter := model.NotificationDeliveryFilter{
+		Status: c.Query("status"),
+		Limit:  defaultNotificationLimit,
+	}
+
+	if date := c.Query("date"); date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			respondError(c, http.StatusBadRequest, "invalid date format (expected YYYY-MM-DD)")
+			return
+		}
+		filter.TargetDate = date
+	}
+	if s := c.Query("user_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	if s := c.Query("notification_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid notification_id")
+			return
+		}
+		filter.NotificationID = uint(id)
+	}
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			respondError(c, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := service.GetNotificationDeliveries(filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+	})
+}
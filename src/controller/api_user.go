@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// CreateUserRequest はユーザー登録のリクエストボディ
+type CreateUserRequest struct {
+	Name        string `json:"name" binding:"required"`
+	SlackID     string `json:"slack_id" binding:"required"`
+	StayWatchID int64  `json:"stay_watch_id" binding:"omitempty,min=1"` // 省略時は名前から滞在ウォッチのユーザーを検索する
+}
+
+// UpdateUserRequest はユーザー更新のリクエストボディ（省略した項目は変更しない）
+type UpdateUserRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1"`
+	SlackID     *string `json:"slack_id" binding:"omitempty,min=1"`
+	StayWatchID *int64  `json:"stay_watch_id" binding:"omitempty,min=1"`
+}
+
+// GetUsers はユーザー一覧を取得するAPIハンドラー
+// @Summary ユーザー一覧を取得
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users [get]
+func GetUsers(c *gin.Context) {
+	users, err := service.ListAllUsers()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": users,
+	})
+}
+
+// GetUser はユーザーを登録イベントを含めて取得するAPIハンドラー
+// @Summary ユーザーを取得
+// @Tags users
+// @Produce json
+// @Param id path int true "ユーザーID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users/{id} [get]
+func GetUser(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	user, err := service.GetUser(id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": user,
+	})
+}
+
+// PostUser はユーザーを登録するAPIハンドラー
+// @Summary ユーザーを登録
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body CreateUserRequest true "登録するユーザー"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users [post]
+func PostUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, msgInvalidRequestBody)
+		return
+	}
+
+	user, err := service.CreateUser(service.UserInput{
+		Name:        req.Name,
+		SlackID:     req.SlackID,
+		StayWatchID: req.StayWatchID,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrStayWatchUserNotFound) {
+			respondError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": user,
+	})
+}
+
+// PatchUser はユーザーを更新するAPIハンドラー
+// @Summary ユーザーを更新
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "ユーザーID"
+// @Param request body UpdateUserRequest true "更新する項目"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users/{id} [patch]
+func PatchUser(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, msgInvalidRequestBody)
+		return
+	}
+
+	user, err := service.UpdateUser(id, service.UserUpdateInput{
+		Name:        req.Name,
+		SlackID:     req.SlackID,
+		StayWatchID: req.StayWatchID,
+	})
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": user,
+	})
+}
+
+// DeleteUser はユーザーを削除するAPIハンドラー
+// @Summary ユーザーを削除
+// @Tags users
+// @Param id path int true "ユーザーID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users/{id} [delete]
+func DeleteUser(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if err := service.DeleteUser(id); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
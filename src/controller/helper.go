@@ -2,9 +2,14 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
 )
 
 const msgInternalServerError = "internal server error"
@@ -14,6 +19,131 @@ func respondError(c *gin.Context, statusCode int, message string) {
 	c.JSON(statusCode, gin.H{"error": message})
 }
 
+// respondServiceError はサービス層のエラーの種類に応じたステータスコードでエラーレスポンスを返す
+// service.ErrInvalidInput は 400、service.ErrNotFound は 404、service.ErrAlreadyExists は 409、それ以外は 500 とする
+func respondServiceError(c *gin.Context, err error) {
+	message := err.Error()
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		respondError(c, http.StatusBadRequest, message)
+	case errors.Is(err, service.ErrNotFound):
+		respondError(c, http.StatusNotFound, message)
+	case errors.Is(err, service.ErrAlreadyExists):
+		respondError(c, http.StatusConflict, message)
+	default:
+		respondError(c, http.StatusInternalServerError, message)
+	}
+}
+
+// parseIDParam はパスパラメータのIDを取得する
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseLookback はクエリパラメータ weeks / since から活動確率の計算に使う期間を取得する
+// どちらも省略した場合は既定の期間とする。不正な場合は 400 を返して false を返す
+func parseLookback(c *gin.Context) (service.Lookback, bool) {
+	weeksStr := c.Query("weeks")
+	since := c.Query("since")
+	if weeksStr == "" && since == "" {
+		return service.DefaultLookback(), true
+	}
+
+	var weeks int
+	if weeksStr != "" {
+		w, err := strconv.Atoi(weeksStr)
+		if err != nil || w <= 0 {
+			respondError(c, http.StatusBadRequest, "weeks must be a positive integer")
+			return service.Lookback{}, false
+		}
+		weeks = w
+	}
+	lookback, err := service.NewLookback(weeks, since)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return service.Lookback{}, false
+	}
+	return lookback, true
+}
+
+// parseDetails はクエリパラメータ details（true/false、省略時は false）を取得する
+// 不正な場合は 400 を返して false を返す
+func parseDetails(c *gin.Context) (bool, bool) {
+	detailsStr := c.Query("details")
+	if detailsStr == "" {
+		return false, true
+	}
+	details, err := strconv.ParseBool(detailsStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "details must be true or false")
+		return false, false
+	}
+	return details, true
+}
+
+// targetDay は確率APIの対象の曜日を表す。date を指定した場合は date にその日付が入る
+type targetDay struct {
+	weekday time.Weekday
+	date    time.Time
+}
+
+// parseTargetDay はクエリパラメータ weekday（MySQL WEEKDAY形式: 0=月, 6=日）または date（YYYY-MM-DD, JST）から対象の曜日を取得する
+// どちらも省略した場合、required なら 400 を返し、そうでなければ今日の曜日とする。不正な場合は 400 を返して false を返す
+func parseTargetDay(c *gin.Context, required bool) (targetDay, bool) {
+	weekdayStr := c.Query("weekday")
+	dateStr := c.Query("date")
+
+	switch {
+	case weekdayStr != "" && dateStr != "":
+		respondError(c, http.StatusBadRequest, "specify either weekday or date")
+		return targetDay{}, false
+	case dateStr != "":
+		date, err := time.ParseInLocation("2006-01-02", dateStr, lib.JST)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid date format (expected YYYY-MM-DD)")
+			return targetDay{}, false
+		}
+		return targetDay{weekday: date.Weekday(), date: date}, true
+	case weekdayStr != "":
+		weekdayInt, err := strconv.Atoi(weekdayStr)
+		if err != nil || weekdayInt < 0 || weekdayInt > 6 {
+			respondError(c, http.StatusBadRequest, "weekday must be 0-6 (Monday=0, Sunday=6)")
+			return targetDay{}, false
+		}
+		// MySQL WEEKDAY形式(月=0)からGoのtime.Weekday形式(日=0)に変換
+		return targetDay{weekday: time.Weekday((weekdayInt + 1) % 7)}, true
+	case required:
+		respondError(c, http.StatusBadRequest, "weekday or date parameter is required")
+		return targetDay{}, false
+	default:
+		// デフォルト: 今日の曜日（JST）
+		return targetDay{weekday: lib.NowJST().Weekday()}, true
+	}
+}
+
+// mysqlWeekday は曜日をMySQL WEEKDAY形式（0=月, 6=日）で返す
+func (d targetDay) mysqlWeekday() int {
+	return (int(d.weekday) + 6) % 7
+}
+
+// withCalendar は date を指定した場合に、その日付と学事暦上の扱いをレスポンスに加える
+func (d targetDay) withCalendar(response gin.H) (gin.H, error) {
+	if d.date.IsZero() {
+		return response, nil
+	}
+	day, err := service.GetCalendarDay(d.date)
+	if err != nil {
+		return nil, err
+	}
+	response["date"] = day.Date
+	response["calendar"] = day
+	return response, nil
+}
+
 // respondSlackError はSlackコマンド用のエラーレスポンスを返す
 func respondSlackError(c *gin.Context, message string) {
 	c.JSON(http.StatusOK, gin.H{
@@ -29,3 +159,11 @@ func respondSlackSuccess(c *gin.Context, message string) {
 		"text":          message,
 	})
 }
+
+// respondSlackEphemeral はSlackコマンド用に実行者のみに見えるレスポンスを返す
+func respondSlackEphemeral(c *gin.Context, message string) {
+	c.JSON(http.StatusOK, gin.H{
+		"response_type": "ephemeral",
+		"text":          message,
+	})
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+func TestRespondServiceError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "invalid input", err: fmt.Errorf("time model must be linear or circular: %w", service.ErrInvalidInput), wantStatus: http.StatusBadRequest},
+		{name: "event not found", err: service.ErrEventNotFound, wantStatus: http.StatusNotFound},
+		{name: "wrapped not found", err: fmt.Errorf("failed to evaluate event 1: %w", service.ErrEventNotFound), wantStatus: http.StatusNotFound},
+		{name: "already exists", err: fmt.Errorf("event %w", service.ErrAlreadyExists), wantStatus: http.StatusConflict},
+		// 種類のないエラーはメッセージによらず 500 とする
+		{name: "message ending with not found", err: errors.New("record not found"), wantStatus: http.StatusInternalServerError},
+		{name: "other error", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondServiceError(c, tt.err)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if want := fmt.Sprintf(`{"error":%q}`, tt.err.Error()); w.Body.String() != want {
+				t.Errorf("body = %s, want %s", w.Body.String(), want)
+			}
+		})
+	}
+}
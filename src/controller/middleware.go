@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+// VerifySlackRequest はSlackからのリクエストの署名（X-Slack-Signature）と
+// タイムスタンプを検証するミドルウェア。検証に失敗した場合は 401 を返す
+// 後続のハンドラーが本文を読めるよう、読み取った本文はリクエストに戻す
+func VerifySlackRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "bad request")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := lib.VerifySlackSignature(c.Request.Header, body, signingSecret, time.Now()); err != nil {
+			log.Printf("rejected slack request to %s: %v", c.Request.URL.Path, err)
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSlackAdmin はスラッシュコマンドの実行者が管理者であることを確認するミドルウェア
+// 管理者でない場合は実行者のみに見えるエラーメッセージを返す
+func RequireSlackAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.IsAdmin(c.PostForm("user_id")) {
+			respondSlackEphemeral(c, "このコマンドは管理者のみ実行できます。")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAPIKey は Authorization: Bearer ヘッダーのAPIキーを検証するミドルウェア
+// キーがない・無効な場合は 401、必要なスコープを持たない場合は 403 を返す
+func RequireAPIKey(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			respondError(c, http.StatusUnauthorized, "missing api key")
+			c.Abort()
+			return
+		}
+
+		key, err := service.AuthenticateAPIKey(strings.TrimSpace(token), scope)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrAPIKeyInvalid):
+				respondError(c, http.StatusUnauthorized, err.Error())
+			case errors.Is(err, service.ErrAPIKeyForbidden):
+				respondError(c, http.StatusForbidden, err.Error())
+			default:
+				respondError(c, http.StatusInternalServerError, msgInternalServerError)
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("api_key_name", key.Name)
+		c.Next()
+	}
+}
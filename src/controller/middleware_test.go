@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+)
+
+const testSigningSecret = "test-signing-secret"
+
+// newSlackTestRouter は VerifySlackRequest の後に、受け取ったフォームの値を返すハンドラーを置いたルーターを作る
+// called はハンドラーが呼ばれたかを表す
+func newSlackTestRouter(t *testing.T, secret string) (*gin.Engine, *bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	original := signingSecret
+	signingSecret = secret
+	t.Cleanup(func() { signingSecret = original })
+
+	called := false
+	router := gin.New()
+	router.POST("/slack/command", VerifySlackRequest(), func(c *gin.Context) {
+		called = true
+		c.String(http.StatusOK, "%s %s %s", c.PostForm("command"), c.PostForm("text"), c.PostForm("user_id"))
+	})
+	return router, &called
+}
+
+// newSlackTestRequest は form のボディを持つ、署名前のリクエストを作る
+func newSlackTestRequest(form string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/slack/command", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestVerifySlackRequestRestoresBody(t *testing.T) {
+	router, called := newSlackTestRouter(t, testSigningSecret)
+	form := "command=%2Fstay-watch&text=list&user_id=U123"
+	req := newSlackTestRequest(form)
+	if err := lib.SignSlackRequest(req, testSigningSecret, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !*called {
+		t.Fatalf("status = %d, handler called = %v, want 200 and called", w.Code, *called)
+	}
+	// 署名の検証で読んだボディを、後続のハンドラーがそのままフォームとして読める
+	if want := "/stay-watch list U123"; w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestVerifySlackRequestRejects(t *testing.T) {
+	form := "command=%2Fstay-watch&text=list"
+
+	tests := []struct {
+		name     string
+		noSecret bool // サーバーの署名シークレットを未設定とする
+		// prepare は署名済みのリクエストを改変する
+		prepare func(t *testing.T, req *http.Request)
+	}{
+		{
+			name: "missing timestamp",
+			prepare: func(t *testing.T, req *http.Request) {
+				req.Header.Del(lib.SlackTimestampHeader)
+			},
+		},
+		{
+			name: "missing signature",
+			prepare: func(t *testing.T, req *http.Request) {
+				req.Header.Del(lib.SlackSignatureHeader)
+			},
+		},
+		{
+			name: "invalid timestamp",
+			prepare: func(t *testing.T, req *http.Request) {
+				req.Header.Set(lib.SlackTimestampHeader, "yesterday")
+			},
+		},
+		{
+			// 署名は正しくても、古いリクエストは再送攻撃とみなす
+			name: "stale timestamp",
+			prepare: func(t *testing.T, req *http.Request) {
+				signAt(t, req, form, testSigningSecret, time.Now().Add(-lib.SlackSignatureMaxAge-time.Minute))
+			},
+		},
+		{
+			name: "timestamp in the future",
+			prepare: func(t *testing.T, req *http.Request) {
+				signAt(t, req, form, testSigningSecret, time.Now().Add(lib.SlackSignatureMaxAge+time.Minute))
+			},
+		},
+		{
+			name: "signed with another secret",
+			prepare: func(t *testing.T, req *http.Request) {
+				signAt(t, req, form, "another-secret", time.Now())
+			},
+		},
+		{
+			name: "body changed after signing",
+			prepare: func(t *testing.T, req *http.Request) {
+				req.Body = io.NopCloser(strings.NewReader(form + "&user_id=U999"))
+			},
+		},
+		{
+			// 署名シークレットが未設定の場合はすべて拒否する
+			name:     "signing secret not configured",
+			noSecret: true,
+			prepare:  func(t *testing.T, req *http.Request) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := testSigningSecret
+			if tt.noSecret {
+				secret = ""
+			}
+			router, called := newSlackTestRouter(t, secret)
+			req := newSlackTestRequest(form)
+			if err := lib.SignSlackRequest(req, testSigningSecret, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			tt.prepare(t, req)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			if *called {
+				t.Error("handler was called for a rejected request")
+			}
+			if want := `{"error":"unauthorized"}`; w.Body.String() != want {
+				t.Errorf("body = %s, want %s", w.Body.String(), want)
+			}
+		})
+	}
+}
+
+// signAt はボディを body に戻し、now の時刻で secret を使って署名し直す
+func signAt(t *testing.T, req *http.Request, body, secret string, now time.Time) {
+	t.Helper()
+	req.Body = io.NopCloser(strings.NewReader(body))
+	if err := lib.SignSlackRequest(req, secret, now); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
 	"github.com/kajiLabTeam/stay-watch-slackbot/service"
 	"github.com/slack-go/slack"
 )
@@ -84,6 +88,54 @@ func PostDeleteOBUsersCommand(c *gin.Context) {
 	respondSlackSuccess(c, message)
 }
 
+// PostGrantAdminCommand はコマンドのtextで指定したユーザ（名前またはメンション）に管理者権限を付与する
+func PostGrantAdminCommand(c *gin.Context) {
+	text := c.PostForm("text")
+	if text == "" {
+		respondSlackEphemeral(c, "管理者にするユーザを指定してください。例: /grant_admin @山田太郎")
+		return
+	}
+
+	user, err := service.GrantAdmin(text)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			respondSlackEphemeral(c, fmt.Sprintf("User %s not found.", text))
+		case "user is already an admin":
+			respondSlackEphemeral(c, fmt.Sprintf("%s は既に管理者です。", user.Name))
+		default:
+			respondSlackEphemeral(c, fmt.Sprintf("Error: %s", err.Error()))
+		}
+		return
+	}
+	respondSlackSuccess(c, fmt.Sprintf("%s を管理者にしました。", user.Name))
+}
+
+// PostRevokeAdminCommand はコマンドのtextで指定したユーザ（名前またはメンション）の管理者権限を取り消す
+func PostRevokeAdminCommand(c *gin.Context) {
+	text := c.PostForm("text")
+	if text == "" {
+		respondSlackEphemeral(c, "管理者権限を取り消すユーザを指定してください。例: /revoke_admin @山田太郎")
+		return
+	}
+
+	user, err := service.RevokeAdmin(text)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			respondSlackEphemeral(c, fmt.Sprintf("User %s not found.", text))
+		case "user is not an admin":
+			respondSlackEphemeral(c, fmt.Sprintf("%s は管理者ではありません。", user.Name))
+		case "configured admin cannot be revoked":
+			respondSlackEphemeral(c, fmt.Sprintf("%s は設定ファイル（SLACK_ADMIN_IDS）で管理者に指定されているため取り消せません。", user.Name))
+		default:
+			respondSlackEphemeral(c, fmt.Sprintf("Error: %s", err.Error()))
+		}
+		return
+	}
+	respondSlackSuccess(c, fmt.Sprintf("%s の管理者権限を取り消しました。", user.Name))
+}
+
 func PostRegisterEventCommand(c *gin.Context) {
 	s, err := slack.SlashCommandParse(c.Request)
 	if err != nil {
@@ -185,3 +237,260 @@ func PostRegisterCorrespondCommand(c *gin.Context) {
 	}
 	respondSlackSuccess(c, "モーダルを開きました。")
 }
+
+// PostNotifyPreviewCommand は対象日の通知内容を送信せずに表示する
+// 集計に時間がかかるため、結果は response_url に非同期で返す
+// 例: /notify_preview（明日）, /notify_preview 4（次の金曜日）, /notify_preview 2026-07-23
+func PostNotifyPreviewCommand(c *gin.Context) {
+	s, err := slack.SlashCommandParse(c.Request)
+	if err != nil {
+		log.Printf("Error parsing slash command: %v", err)
+		respondError(c, http.StatusBadRequest, "bad request")
+		return
+	}
+
+	targetDate, err := parseTargetDate(strings.TrimSpace(s.Text))
+	if err != nil {
+		respondSlackError(c, "曜日は0〜6の整数（0=月曜日, ..., 6=日曜日）または日付（YYYY-MM-DD）で指定してください。例: /notify_preview 4")
+		return
+	}
+
+	go func() {
+		preview := service.PreviewNotification(targetDate)
+		msg := &slack.WebhookMessage{
+			ResponseType: slack.ResponseTypeEphemeral,
+			Text:         formatNotificationPreview(preview, s.UserID),
+		}
+		if err := slack.PostWebhook(s.ResponseURL, msg); err != nil {
+			log.Printf("Error posting notification preview: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"response_type": slack.ResponseTypeEphemeral,
+		"text":          "通知内容を集計しています...",
+	})
+}
+
+// formatNotificationPreview は通知プレビューをSlack表示用のテキストにする
+// 他ユーザーへのDM本文は含めず、コマンド実行者宛ての内容のみ表示する
+func formatNotificationPreview(preview service.NotificationPreview, slackUserID string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s の通知プレビュー*\n\n", preview.TargetDate)
+
+	for _, ev := range preview.Events {
+		if ev.Included {
+			fmt.Fprintf(&b, "✅ `%s` 活動確率 %.2f\n", ev.EventName, ev.Probability)
+			for _, r := range ev.RecommendedRanges {
+				fmt.Fprintf(&b, "    推奨: %s〜%s\n", r.Start, r.End)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "❌ `%s` 活動確率 %.2f: %s\n", ev.EventName, ev.Probability, ev.Reason)
+	}
+
+	b.WriteString("\n*あなたへのDM*\n")
+	for _, r := range preview.Recipients {
+		if r.SlackID != slackUserID {
+			continue
+		}
+		if r.Message == "" {
+			fmt.Fprintf(&b, "送信されません: %s\n", r.Reason)
+		} else {
+			b.WriteString("```\n" + r.Message + "```\n")
+		}
+		return b.String()
+	}
+	b.WriteString("送信されません: 登録している話題がありません\n")
+	return b.String()
+}
+
+// notifyWeekdayLabels は通知設定モーダルで表示する曜日（MySQL WEEKDAY形式の順）
+var notifyWeekdayLabels = []string{"月", "火", "水", "木", "金", "土", "日"}
+
+// PostNotifySettingsCommand は通知設定を編集するモーダルを開く
+func PostNotifySettingsCommand(c *gin.Context) {
+	s, err := slack.SlashCommandParse(c.Request)
+	if err != nil {
+		log.Printf("Error parsing slash command: %v", err)
+		respondError(c, http.StatusBadRequest, "bad request")
+		return
+	}
+
+	_, preference, err := service.GetNotificationPreference(s.UserID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			respondSlackError(c, "ユーザー登録されていません。先に /add_user で登録してください。")
+			return
+		}
+		respondSlackError(c, fmt.Sprintf("Error: %s", err.Error()))
+		return
+	}
+
+	events, err := service.GetEvents()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, msgInternalServerError)
+		return
+	}
+
+	modalRequest := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      "notify_settings",
+		Title:           slack.NewTextBlockObject("plain_text", "通知設定", false, false),
+		Close:           slack.NewTextBlockObject("plain_text", "閉じる", false, false),
+		Submit:          slack.NewTextBlockObject("plain_text", "保存", false, false),
+		PrivateMetadata: s.ResponseURL,
+		Blocks: slack.Blocks{
+			BlockSet: buildNotifySettingsBlocks(preference, events),
+		},
+	}
+	_, err = api.OpenView(s.TriggerID, modalRequest)
+	if err != nil {
+		log.Printf("Error opening view: %v", err)
+		respondError(c, http.StatusInternalServerError, msgInternalServerError)
+		return
+	}
+	respondSlackSuccess(c, "モーダルを開きました。")
+}
+
+// buildNotifySettingsBlocks は現在の通知設定を初期値とした通知設定モーダルのブロックを作る
+func buildNotifySettingsBlocks(preference model.NotificationPreference, events []model.Event) []slack.Block {
+	// 全通知の停止
+	muteOption := slack.NewOptionBlockObject("muted", slack.NewTextBlockObject("plain_text", "すべての通知を停止する", false, false), nil)
+	muteElement := slack.NewCheckboxGroupsBlockElement("mute_input", muteOption)
+	if preference.Muted {
+		muteElement.InitialOptions = []*slack.OptionBlockObject{muteOption}
+	}
+	muteBlock := slack.NewInputBlock("mute_block", slack.NewTextBlockObject("plain_text", "一時停止", false, false), nil, muteElement)
+	muteBlock.Optional = true
+
+	// 通知しない曜日（活動日の曜日）
+	mutedWeekdays := make(map[int]bool)
+	for _, w := range service.ParseMutedWeekdays(preference) {
+		mutedWeekdays[w] = true
+	}
+	var weekdayOptions, weekdayInitial []*slack.OptionBlockObject
+	for i, label := range notifyWeekdayLabels {
+		option := slack.NewOptionBlockObject(strconv.Itoa(i), slack.NewTextBlockObject("plain_text", label, false, false), nil)
+		weekdayOptions = append(weekdayOptions, option)
+		if mutedWeekdays[i] {
+			weekdayInitial = append(weekdayInitial, option)
+		}
+	}
+	weekdayElement := slack.NewCheckboxGroupsBlockElement("weekday_input", weekdayOptions...)
+	weekdayElement.InitialOptions = weekdayInitial
+	weekdayBlock := slack.NewInputBlock("weekday_block", slack.NewTextBlockObject("plain_text", "通知しない曜日", false, false), nil, weekdayElement)
+	weekdayBlock.Optional = true
+
+	// 希望送信時刻
+	timeElement := slack.NewTimePickerBlockElement("time_input")
+	timeElement.InitialTime = preference.DeliveryTime
+	timeBlock := slack.NewInputBlock("time_block",
+		slack.NewTextBlockObject("plain_text", "希望送信時刻", false, false),
+		slack.NewTextBlockObject("plain_text", "未設定の場合は通知の実行時にすぐ送信します", false, false),
+		timeElement)
+	timeBlock.Optional = true
+
+	// 活動確率の下限
+	probabilityElement := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject("plain_text", "例：0.5", false, false), "probability_input")
+	if preference.MinProbability > 0 {
+		probabilityElement.InitialValue = strconv.FormatFloat(preference.MinProbability, 'f', -1, 64)
+	}
+	probabilityBlock := slack.NewInputBlock("probability_block",
+		slack.NewTextBlockObject("plain_text", "通知する活動確率の下限", false, false),
+		slack.NewTextBlockObject("plain_text", "0〜1の数値。この確率未満の活動は通知しません", false, false),
+		probabilityElement)
+	probabilityBlock.Optional = true
+
+	blocks := []slack.Block{muteBlock, weekdayBlock}
+
+	// 通知しない話題（話題が1件もない場合はチェックボックスを作れないため省略）
+	if len(events) > 0 {
+		mutedEvents := make(map[uint]bool)
+		for _, id := range service.ParseMutedEventIDs(preference) {
+			mutedEvents[id] = true
+		}
+		var eventOptions, eventInitial []*slack.OptionBlockObject
+		for _, event := range events {
+			option := slack.NewOptionBlockObject(fmt.Sprintf("%d", event.ID), slack.NewTextBlockObject("plain_text", event.Name, false, false), nil)
+			eventOptions = append(eventOptions, option)
+			if mutedEvents[event.ID] {
+				eventInitial = append(eventInitial, option)
+			}
+		}
+		eventElement := slack.NewCheckboxGroupsBlockElement("event_input", eventOptions...)
+		eventElement.InitialOptions = eventInitial
+		eventBlock := slack.NewInputBlock("event_block", slack.NewTextBlockObject("plain_text", "通知しない話題", false, false), nil, eventElement)
+		eventBlock.Optional = true
+		blocks = append(blocks, eventBlock)
+	}
+
+	return append(blocks, timeBlock, probabilityBlock)
+}
+
+// apiKeyCommandUsage は /api_key コマンドの使い方
+const apiKeyCommandUsage = "使い方:\n" +
+	"• `/api_key issue <名前> <スコープ>` キーを発行（スコープは read, write-logs, admin をカンマ区切り）\n" +
+	"• `/api_key revoke <名前>` キーを失効\n" +
+	"• `/api_key list` 有効なキーの一覧"
+
+// PostAPIKeyCommand はREST API用のAPIキーを発行・失効・一覧表示する（管理者のみ）
+// 発行したキーは実行者のみに見えるメッセージで一度だけ表示する
+func PostAPIKeyCommand(c *gin.Context) {
+	args := strings.Fields(c.PostForm("text"))
+	if len(args) == 0 {
+		respondSlackEphemeral(c, apiKeyCommandUsage)
+		return
+	}
+
+	switch args[0] {
+	case "issue":
+		if len(args) < 3 {
+			respondSlackEphemeral(c, "名前とスコープを指定してください。例: /api_key issue logger-room1 write-logs")
+			return
+		}
+		key, plain, err := service.IssueAPIKey(args[1], args[2:], c.PostForm("user_id"))
+		if err != nil {
+			respondSlackEphemeral(c, fmt.Sprintf("Error: %s", err.Error()))
+			return
+		}
+		respondSlackEphemeral(c, fmt.Sprintf("APIキー `%s`（スコープ: %s）を発行しました。このキーは再表示できないため、安全な場所に保存してください。\n```%s```", key.Name, key.Scopes, plain))
+	case "revoke":
+		if len(args) < 2 {
+			respondSlackEphemeral(c, "失効させるキーの名前を指定してください。例: /api_key revoke logger-room1")
+			return
+		}
+		key, err := service.RevokeAPIKey(args[1])
+		if err != nil {
+			if err.Error() == "api key not found" {
+				respondSlackEphemeral(c, fmt.Sprintf("APIキー %s は見つかりませんでした。", args[1]))
+				return
+			}
+			respondSlackEphemeral(c, fmt.Sprintf("Error: %s", err.Error()))
+			return
+		}
+		respondSlackEphemeral(c, fmt.Sprintf("APIキー `%s` を失効させました。", key.Name))
+	case "list":
+		keys, err := service.ListAPIKeys()
+		if err != nil {
+			respondSlackEphemeral(c, fmt.Sprintf("Error: %s", err.Error()))
+			return
+		}
+		if len(keys) == 0 {
+			respondSlackEphemeral(c, "有効なAPIキーはありません。")
+			return
+		}
+		var b strings.Builder
+		b.WriteString("有効なAPIキー:\n")
+		for _, key := range keys {
+			lastUsed := "未使用"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.In(lib.JST).Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(&b, "• `%s` %s… スコープ: %s 最終使用: %s\n", key.Name, key.Prefix, key.Scopes, lastUsed)
+		}
+		respondSlackEphemeral(c, b.String())
+	default:
+		respondSlackEphemeral(c, apiKeyCommandUsage)
+	}
+}
@@ -1,111 +1,70 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
-	"github.com/kajiLabTeam/stay-watch-slackbot/model"
 	"github.com/kajiLabTeam/stay-watch-slackbot/service"
-	"github.com/slack-go/slack"
 )
 
+// SendDM は対象日の活動通知を生成し、該当ユーザーにDMを送信する
+// 対象日は date（YYYY-MM-DD）または weekday で指定する（省略時は明日）
+// 同じ対象日の通知が既に完了している場合は送信しない
+// dry_run=true の場合は送信・記録を行わず、通知内容と判定結果を返す
 func SendDM(c *gin.Context) {
-	logFile, err := os.OpenFile("../log/dm_send.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "failed to open log file")
+	param := c.Query("date")
+	if param == "" {
+		param = c.Query("weekday")
+	} else if c.Query("weekday") != "" {
+		respondError(c, http.StatusBadRequest, "specify either weekday or date")
 		return
 	}
-	defer func() { _ = logFile.Close() }()
-	logger := log.New(logFile, "", 0)
-
-	targetWeekday, err := parseTargetWeekday(c.DefaultQuery("weekday", ""))
+	targetDate, err := parseTargetDate(param)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weekday parameter. Use integer 0-6 (0=Monday, ..., 6=Sunday)"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weekday or date parameter. Use integer 0-6 (0=Monday, ..., 6=Sunday) or YYYY-MM-DD"})
 		return
 	}
 
-	users, userMessages := service.NotifyByEvent(targetWeekday)
-
-	if len(users) == 0 {
-		c.JSON(http.StatusOK, gin.H{"error": "No users found"})
-		return
-	}
-	if len(userMessages) == 0 {
-		c.JSON(http.StatusOK, gin.H{"error": "No message found"})
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{
+			"data": service.PreviewNotification(targetDate),
+		})
 		return
 	}
-	for _, user := range users {
-		sendDMToUser(c, logger, user, userMessages)
-	}
-}
 
-func parseTargetWeekday(param string) (time.Weekday, error) {
-	if param == "" {
-		tomorrow := lib.NowJST().AddDate(0, 0, 1)
-		return tomorrow.Weekday(), nil
-	}
-	weekdayInt, err := strconv.Atoi(param)
-	if err != nil || weekdayInt < 0 || weekdayInt > 6 {
-		return 0, fmt.Errorf("invalid weekday: %s", param)
-	}
-	return time.Weekday((weekdayInt + 1) % 7), nil
-}
-
-func buildMessageForUser(eventMessages map[int][]string, eventUsers []model.EventUser) string {
-	var b strings.Builder
-	for _, eu := range eventUsers {
-		m, ok := eventMessages[int(eu.EventID)]
-		if !ok {
-			continue
-		}
-		for _, v := range m {
-			b.WriteString(v)
-			b.WriteByte('\n')
+	result, err := service.RunNotification(targetDate, service.NotificationTriggerHTTP)
+	if err != nil {
+		if errors.Is(err, service.ErrNotificationAlreadyCompleted) || errors.Is(err, service.ErrNotificationInProgress) {
+			respondError(c, http.StatusConflict, err.Error())
+			return
 		}
-	}
-	return b.String()
-}
-
-func sendDMToUser(c *gin.Context, logger *log.Logger, user model.User, userMessages map[int]map[int][]string) {
-	eventMessages, hasMessages := userMessages[int(user.ID)]
-	if !hasMessages || len(eventMessages) == 0 {
-		return
-	}
-	if len(user.EventUsers) == 0 {
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	channel, _, _, err := api.OpenConversation(&slack.OpenConversationParameters{
-		ReturnIM: true,
-		Users:    []string{user.SlackID},
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
 	})
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "failed to open conversation")
-		return
-	}
+}
 
-	message := buildMessageForUser(eventMessages, user.EventUsers)
-	if message == "" {
-		return
+// parseTargetDate は曜日または日付のパラメータから通知対象日を求める
+// 省略時は明日、曜日（0〜6）の場合は明日以降で最初にその曜日となる日、日付（YYYY-MM-DD）の場合はその日を対象とする
+func parseTargetDate(param string) (time.Time, error) {
+	now := lib.NowJST()
+	if param == "" {
+		return now.AddDate(0, 0, 1), nil
 	}
-
-	_, _, err = api.PostMessage(channel.ID, slack.MsgOptionText(message, false))
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "failed to send message")
-		return
+	if date, err := time.ParseInLocation("2006-01-02", param, lib.JST); err == nil {
+		return date, nil
 	}
-
-	now := lib.NowJST()
-	logger.Printf("[%s] 送信先: %s (SlackID: %s)\n推奨活動内容:\n%s\n---\n",
-		now.Format("2006-01-02 15:04:05"),
-		user.Name,
-		user.SlackID,
-		message)
+	weekdayInt, err := strconv.Atoi(param)
+	if err != nil || weekdayInt < 0 || weekdayInt > 6 {
+		return time.Time{}, fmt.Errorf("invalid weekday: %s", param)
+	}
+	return service.NextNotificationDate(now, time.Weekday((weekdayInt+1)%7)), nil
 }
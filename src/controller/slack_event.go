@@ -11,25 +11,14 @@ import (
 	"github.com/slack-go/slack/slackevents"
 )
 
+// PostSlackEvents はSlackのEvents APIからのイベントを処理する
+// 署名の検証は VerifySlackRequest ミドルウェアで行う
 func PostSlackEvents(c *gin.Context) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		respondError(c, http.StatusBadRequest, "bad request")
 		return
 	}
-	sv, err := slack.NewSecretsVerifier(c.Request.Header, signingSecret)
-	if err != nil {
-		respondError(c, http.StatusBadRequest, "bad request")
-		return
-	}
-	if _, err := sv.Write(body); err != nil {
-		respondError(c, http.StatusInternalServerError, msgInternalServerError)
-		return
-	}
-	if err := sv.Ensure(); err != nil {
-		respondError(c, http.StatusUnauthorized, "unauthorized")
-		return
-	}
 	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, msgInternalServerError)
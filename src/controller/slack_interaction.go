@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kajiLabTeam/stay-watch-slackbot/service"
@@ -87,6 +88,8 @@ func handleViewSubmission(c *gin.Context, interaction slack.InteractionCallback)
 		handleRegisterEvent(c, interaction)
 	case "select_events":
 		handleSelectEvents(c, interaction)
+	case "notify_settings":
+		handleNotifySettings(c, interaction)
 	default:
 		c.JSON(http.StatusOK, gin.H{})
 	}
@@ -95,6 +98,12 @@ func handleViewSubmission(c *gin.Context, interaction slack.InteractionCallback)
 func handleRegisterEvent(c *gin.Context, interaction slack.InteractionCallback) {
 	values := interaction.View.State.Values
 	responseURL := interaction.View.PrivateMetadata
+
+	if !service.IsAdmin(interaction.User.ID) {
+		_, _, _ = api.PostMessage("", slack.MsgOptionReplaceOriginal(responseURL), slack.MsgOptionText("このコマンドは管理者のみ実行できます。", false))
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
 	name := values["name_block"]["name_input"].Value
 	code := values["code_block"]["code_input"].Value
 	numStr := values["number_block"]["number_input"].Value
@@ -105,7 +114,7 @@ func handleRegisterEvent(c *gin.Context, interaction slack.InteractionCallback)
 		return
 	}
 
-	if _, err := service.RegisterEvent(name, numInt, code); err != nil {
+	if _, err := service.RegisterEvent(name, numInt, code, "", ""); err != nil {
 		if err.Error() == "event already exists" {
 			_, _, _ = api.PostMessage("", slack.MsgOptionReplaceOriginal(responseURL), slack.MsgOptionText("登録済みのイベントです", false))
 			return
@@ -130,3 +139,42 @@ func handleSelectEvents(c *gin.Context, interaction slack.InteractionCallback) {
 	_, _, _ = api.PostMessage("", slack.MsgOptionReplaceOriginal(responseURL), slack.MsgOptionText("登録が完了しました。", false))
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+func handleNotifySettings(c *gin.Context, interaction slack.InteractionCallback) {
+	values := interaction.View.State.Values
+	responseURL := interaction.View.PrivateMetadata
+
+	input := service.NotificationPreferenceInput{
+		Muted:        len(values["mute_block"]["mute_input"].SelectedOptions) > 0,
+		DeliveryTime: values["time_block"]["time_input"].SelectedTime,
+	}
+	for _, opt := range values["weekday_block"]["weekday_input"].SelectedOptions {
+		if w, err := strconv.Atoi(opt.Value); err == nil {
+			input.MutedWeekdays = append(input.MutedWeekdays, w)
+		}
+	}
+	for _, opt := range values["event_block"]["event_input"].SelectedOptions {
+		if id, err := strconv.ParseUint(opt.Value, 10, 32); err == nil {
+			input.MutedEventIDs = append(input.MutedEventIDs, uint(id))
+		}
+	}
+	if probStr := strings.TrimSpace(values["probability_block"]["probability_input"].Value); probStr != "" {
+		prob, err := strconv.ParseFloat(probStr, 64)
+		if err != nil || prob < 0 || prob > 1 {
+			c.JSON(http.StatusOK, slack.NewErrorsViewSubmissionResponse(map[string]string{
+				"probability_block": "0〜1の数値を入力してください",
+			}))
+			return
+		}
+		input.MinProbability = prob
+	}
+
+	if _, err := service.UpdateNotificationPreference(interaction.User.ID, input); err != nil {
+		_, _, _ = api.PostMessage("", slack.MsgOptionReplaceOriginal(responseURL), slack.MsgOptionText("Error: "+err.Error(), false))
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	_, _, _ = api.PostMessage("", slack.MsgOptionReplaceOriginal(responseURL), slack.MsgOptionText("通知設定を保存しました。", false))
+	c.JSON(http.StatusOK, gin.H{})
+}
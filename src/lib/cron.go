@@ -0,0 +1,164 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule は5フィールド（分 時 日 月 曜日）のcron式を表す
+// 各フィールドは "*", "1,2", "1-5", "*/15", "mon-fri" の形式に対応する
+type CronSchedule struct {
+	expr     string
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+	dayAny   bool // 日フィールドが "*"
+	dowAny   bool // 曜日フィールドが "*"
+}
+
+// cronWeekdayNames は曜日フィールドで使用できる名前（0=日曜日）
+var cronWeekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseCron はcron式をパースする
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	s := &CronSchedule{
+		expr:   expr,
+		dayAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	if err := parseCronField(fields[0], 0, 59, nil, s.minutes[:]); err != nil {
+		return nil, fmt.Errorf("invalid minute field in %q: %w", expr, err)
+	}
+	if err := parseCronField(fields[1], 0, 23, nil, s.hours[:]); err != nil {
+		return nil, fmt.Errorf("invalid hour field in %q: %w", expr, err)
+	}
+	if err := parseCronField(fields[2], 1, 31, nil, s.days[:]); err != nil {
+		return nil, fmt.Errorf("invalid day field in %q: %w", expr, err)
+	}
+	if err := parseCronField(fields[3], 1, 12, nil, s.months[:]); err != nil {
+		return nil, fmt.Errorf("invalid month field in %q: %w", expr, err)
+	}
+	// 曜日は 7 も日曜日として扱う
+	var weekdays [8]bool
+	if err := parseCronField(fields[4], 0, 7, cronWeekdayNames, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("invalid weekday field in %q: %w", expr, err)
+	}
+	copy(s.weekdays[:], weekdays[:7])
+	if weekdays[7] {
+		s.weekdays[0] = true
+	}
+
+	return s, nil
+}
+
+// parseCronField は1フィールド分をパースし、該当する値を set に記録する
+func parseCronField(field string, min, max int, names map[string]int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step: %s", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], names); err != nil {
+				return err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = parseCronValue(bounds[1], names); err != nil {
+					return err
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("value out of range: %s", part)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+// parseCronValue は数値または名前をフィールド値に変換する
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %s", s)
+	}
+	return v, nil
+}
+
+// String は元のcron式を返す
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// matchesDay は日付がスケジュールの日・月・曜日に一致するかを判定する
+// 日と曜日の両方が指定されている場合は一般的なcronと同様にOR条件とする
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	if !s.months[t.Month()] {
+		return false
+	}
+	day := s.days[t.Day()]
+	dow := s.weekdays[t.Weekday()]
+	switch {
+	case s.dayAny && s.dowAny:
+		return true
+	case s.dayAny:
+		return dow
+	case s.dowAny:
+		return day
+	default:
+		return day || dow
+	}
+}
+
+// Next は t より後で最初にスケジュールに一致する時刻（分単位）を返す
+// 5年以内に一致する時刻がない場合はゼロ値を返す
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !s.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+	return time.Time{}
+}
@@ -0,0 +1,309 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		minutes  []int
+		hours    []int
+		days     []int
+		months   []int
+		weekdays []int
+	}{
+		{
+			name:     "every minute",
+			expr:     "* * * * *",
+			minutes:  rangeInts(0, 59),
+			hours:    rangeInts(0, 23),
+			days:     rangeInts(1, 31),
+			months:   rangeInts(1, 12),
+			weekdays: rangeInts(0, 6),
+		},
+		{
+			name:     "single values",
+			expr:     "30 21 15 6 3",
+			minutes:  []int{30},
+			hours:    []int{21},
+			days:     []int{15},
+			months:   []int{6},
+			weekdays: []int{3},
+		},
+		{
+			name:     "lists",
+			expr:     "0,15,45 9,21 1,31 1,12 0,6",
+			minutes:  []int{0, 15, 45},
+			hours:    []int{9, 21},
+			days:     []int{1, 31},
+			months:   []int{1, 12},
+			weekdays: []int{0, 6},
+		},
+		{
+			name:     "ranges",
+			expr:     "0-3 9-11 10-12 4-6 1-5",
+			minutes:  []int{0, 1, 2, 3},
+			hours:    []int{9, 10, 11},
+			days:     []int{10, 11, 12},
+			months:   []int{4, 5, 6},
+			weekdays: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:     "steps",
+			expr:     "*/15 0-12/6 5/10 */4 *",
+			minutes:  []int{0, 15, 30, 45},
+			hours:    []int{0, 6, 12},
+			days:     []int{5, 15, 25},
+			months:   []int{1, 5, 9},
+			weekdays: rangeInts(0, 6),
+		},
+		{
+			name:     "weekday names",
+			expr:     "0 21 * * MON-wed,Fri",
+			minutes:  []int{0},
+			hours:    []int{21},
+			days:     rangeInts(1, 31),
+			months:   rangeInts(1, 12),
+			weekdays: []int{1, 2, 3, 5},
+		},
+		{
+			name:     "7 is sunday",
+			expr:     "0 21 * * 7",
+			minutes:  []int{0},
+			hours:    []int{21},
+			days:     rangeInts(1, 31),
+			months:   rangeInts(1, 12),
+			weekdays: []int{0},
+		},
+		{
+			name:     "range up to 7 includes sunday",
+			expr:     "0 21 * * 5-7",
+			minutes:  []int{0},
+			hours:    []int{21},
+			days:     rangeInts(1, 31),
+			months:   rangeInts(1, 12),
+			weekdays: []int{0, 5, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			assertCronSet(t, "minutes", s.minutes[:], 0, tt.minutes)
+			assertCronSet(t, "hours", s.hours[:], 0, tt.hours)
+			assertCronSet(t, "days", s.days[:], 1, tt.days)
+			assertCronSet(t, "months", s.months[:], 1, tt.months)
+			assertCronSet(t, "weekdays", s.weekdays[:], 0, tt.weekdays)
+			if s.String() != tt.expr {
+				t.Errorf("String() = %q, want %q", s.String(), tt.expr)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"-5 * * * *",
+		"* * * jan *",
+		"* * * * mon-",
+		"* * * * funday",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("ParseCron(%q) error = nil, want error", expr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleMatchesDay(t *testing.T) {
+	// 2025-06-13 は金曜日
+	friday13 := time.Date(2025, 6, 13, 0, 0, 0, 0, JST)
+	// 2025-06-15 は日曜日
+	sunday15 := time.Date(2025, 6, 15, 0, 0, 0, 0, JST)
+	// 2025-06-16 は月曜日
+	monday16 := time.Date(2025, 6, 16, 0, 0, 0, 0, JST)
+
+	tests := []struct {
+		name string
+		expr string
+		day  time.Time
+		want bool
+	}{
+		{name: "any day", expr: "0 0 * * *", day: friday13, want: true},
+		{name: "day of month only matches", expr: "0 0 13 * *", day: friday13, want: true},
+		{name: "day of month only does not match", expr: "0 0 13 * *", day: sunday15, want: false},
+		{name: "weekday only matches", expr: "0 0 * * fri", day: friday13, want: true},
+		{name: "weekday only does not match", expr: "0 0 * * fri", day: monday16, want: false},
+		// 日と曜日の両方を指定した場合はどちらかに一致すればよい
+		{name: "both set, day matches", expr: "0 0 15 * mon", day: sunday15, want: true},
+		{name: "both set, weekday matches", expr: "0 0 15 * mon", day: monday16, want: true},
+		{name: "both set, neither matches", expr: "0 0 15 * mon", day: friday13, want: false},
+		{name: "month does not match", expr: "0 0 * 7 *", day: friday13, want: false},
+		{name: "month restricts OR rule", expr: "0 0 13 7 fri", day: friday13, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := s.matchesDay(tt.day); got != tt.want {
+				t.Errorf("matchesDay(%s) = %v, want %v", tt.day.Format("2006-01-02 Mon"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "later the same day",
+			expr: "0 21 * * *",
+			from: time.Date(2025, 6, 13, 9, 30, 0, 0, JST),
+			want: time.Date(2025, 6, 13, 21, 0, 0, 0, JST),
+		},
+		{
+			name: "exact match is skipped",
+			expr: "0 21 * * *",
+			from: time.Date(2025, 6, 13, 21, 0, 0, 0, JST),
+			want: time.Date(2025, 6, 14, 21, 0, 0, 0, JST),
+		},
+		{
+			name: "seconds are truncated",
+			expr: "* * * * *",
+			from: time.Date(2025, 6, 13, 9, 30, 59, 999, JST),
+			want: time.Date(2025, 6, 13, 9, 31, 0, 0, JST),
+		},
+		{
+			name: "step within the hour",
+			expr: "*/15 * * * *",
+			from: time.Date(2025, 6, 13, 9, 46, 0, 0, JST),
+			want: time.Date(2025, 6, 13, 10, 0, 0, 0, JST),
+		},
+		{
+			name: "next weekday",
+			expr: "0 21 * * mon-fri",
+			from: time.Date(2025, 6, 13, 22, 0, 0, 0, JST), // 金曜日
+			want: time.Date(2025, 6, 16, 21, 0, 0, 0, JST),
+		},
+		{
+			name: "across month boundary",
+			expr: "30 8 1 * *",
+			from: time.Date(2025, 1, 31, 12, 0, 0, 0, JST),
+			want: time.Date(2025, 2, 1, 8, 30, 0, 0, JST),
+		},
+		{
+			name: "skips months without the day",
+			expr: "0 0 31 * *",
+			from: time.Date(2025, 3, 31, 1, 0, 0, 0, JST),
+			want: time.Date(2025, 5, 31, 0, 0, 0, 0, JST),
+		},
+		{
+			name: "across year boundary",
+			expr: "0 21 * * *",
+			from: time.Date(2025, 12, 31, 21, 30, 0, 0, JST),
+			want: time.Date(2026, 1, 1, 21, 0, 0, 0, JST),
+		},
+		{
+			name: "yearly schedule",
+			expr: "0 0 1 1 *",
+			from: time.Date(2025, 1, 1, 0, 0, 0, 0, JST),
+			want: time.Date(2026, 1, 1, 0, 0, 0, 0, JST),
+		},
+		{
+			name: "leap day",
+			expr: "0 12 29 2 *",
+			from: time.Date(2025, 3, 1, 0, 0, 0, 0, JST),
+			want: time.Date(2028, 2, 29, 12, 0, 0, 0, JST),
+		},
+		{
+			name: "day or weekday, whichever comes first",
+			expr: "0 21 1 * sun",
+			from: time.Date(2025, 6, 28, 0, 0, 0, 0, JST), // 土曜日
+			want: time.Date(2025, 6, 29, 21, 0, 0, 0, JST),
+		},
+		{
+			name: "sunday written as 7",
+			expr: "0 21 * * 7",
+			from: time.Date(2025, 6, 13, 0, 0, 0, 0, JST),
+			want: time.Date(2025, 6, 15, 21, 0, 0, 0, JST),
+		},
+		{
+			name: "never matches",
+			expr: "0 0 31 2 *",
+			from: time.Date(2025, 1, 1, 0, 0, 0, 0, JST),
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+// assertCronSet は set のうち offset 以降で true の値が want と一致するかを検証する
+func assertCronSet(t *testing.T, field string, set []bool, offset int, want []int) {
+	t.Helper()
+	var got []int
+	for v := offset; v < len(set); v++ {
+		if set[v] {
+			got = append(got, v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", field, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", field, got, want)
+			return
+		}
+	}
+}
+
+func rangeInts(lo, hi int) []int {
+	values := make([]int, 0, hi-lo+1)
+	for v := lo; v <= hi; v++ {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,266 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxICalLineBytes iCalendar の1行（折り返しを戻す前）の最大長
+const maxICalLineBytes = 1024 * 1024
+
+// ICalEvent は iCalendar（RFC 5545）の VEVENT 1件を表す
+// 日付はすべてJSTの0時とし、End は終了日を含む（DTEND の前日までを期間とする）
+type ICalEvent struct {
+	Line       int // BEGIN:VEVENT の行番号（1始まり）
+	UID        string
+	Summary    string
+	Categories []string
+	Start      time.Time
+	End        time.Time
+	Recurring  bool // RRULE / RDATE を持つ
+}
+
+// icalProperty は折り返しを戻した1行分のプロパティを表す
+type icalProperty struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICalendar は iCalendar を読み込み、VEVENT ごとに fn を呼び出す
+// 日付を解釈できない VEVENT はエラーとともに fn に渡して続行し、読み込み自体ができなくなった場合のみエラーを返す
+func ParseICalendar(r io.Reader, fn func(ICalEvent, error)) error {
+	var event *ICalEvent
+	var eventErr error
+	var hasEnd, endIsDate bool
+	nested := 0 // VEVENT 内の VALARM などの入れ子の深さ
+
+	err := readICalProperties(r, func(p icalProperty) {
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			event = &ICalEvent{Line: p.line}
+			eventErr = nil
+			hasEnd, endIsDate = false, false
+			nested = 0
+			return
+		case event != nil && p.name == "BEGIN":
+			nested++
+			return
+		case event != nil && p.name == "END" && nested > 0:
+			nested--
+			return
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if event == nil {
+				return
+			}
+			if eventErr == nil && event.Start.IsZero() {
+				eventErr = fmt.Errorf("DTSTART is required")
+			}
+			if eventErr == nil {
+				switch {
+				case !hasEnd:
+					event.End = event.Start
+				case endIsDate:
+					// 終日の予定の DTEND は期間に含まれない
+					event.End = event.End.AddDate(0, 0, -1)
+				}
+				if event.End.Before(event.Start) {
+					event.End = event.Start
+				}
+			}
+			fn(*event, eventErr)
+			event = nil
+			return
+		}
+		if event == nil || eventErr != nil || nested > 0 {
+			return
+		}
+
+		switch p.name {
+		case "UID":
+			event.UID = unescapeICalText(p.value)
+		case "SUMMARY":
+			event.Summary = unescapeICalText(p.value)
+		case "CATEGORIES":
+			for _, c := range splitICalList(p.value) {
+				if c = strings.TrimSpace(unescapeICalText(c)); c != "" {
+					event.Categories = append(event.Categories, c)
+				}
+			}
+		case "RRULE", "RDATE":
+			event.Recurring = true
+		case "DTSTART":
+			date, _, err := parseICalDate(p, false)
+			if err != nil {
+				eventErr = fmt.Errorf("invalid DTSTART: %w", err)
+				return
+			}
+			event.Start = date
+		case "DTEND":
+			date, isDate, err := parseICalDate(p, true)
+			if err != nil {
+				eventErr = fmt.Errorf("invalid DTEND: %w", err)
+				return
+			}
+			event.End = date
+			hasEnd, endIsDate = true, isDate
+		}
+	})
+	return err
+}
+
+// readICalProperties は行の折り返し（空白またはタブで始まる行）を戻し、プロパティごとに fn を呼び出す
+func readICalProperties(r io.Reader, fn func(icalProperty)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxICalLineBytes)
+
+	var current strings.Builder
+	start, line := 0, 0
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		if p, ok := parseICalProperty(current.String()); ok {
+			p.line = start
+			fn(p)
+		}
+		current.Reset()
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			current.WriteString(text[1:])
+			continue
+		}
+		flush()
+		start = line
+		current.WriteString(text)
+	}
+	flush()
+	return scanner.Err()
+}
+
+// parseICalProperty は "NAME;PARAM=VALUE:value" 形式の1行を解析する
+func parseICalProperty(s string) (icalProperty, bool) {
+	// 引用符で囲まれたパラメータ値の中の ":" は区切りとしない
+	colon := -1
+	quoted := false
+	for i, r := range s {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icalProperty{}, false
+	}
+
+	parts := strings.Split(s[:colon], ";")
+	p := icalProperty{
+		name:   strings.ToUpper(strings.TrimSpace(parts[0])),
+		params: make(map[string]string, len(parts)-1),
+		value:  s[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			continue
+		}
+		p.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return p, true
+}
+
+// parseICalDate は DTSTART / DTEND の値をJSTの日付に変換し、終日（VALUE=DATE）かどうかとともに返す
+// 日時の場合は TZID（読み込めない場合はJST）または UTC（末尾が Z）として解釈する
+// DTEND の日時は期間に含まれないため、isEnd の場合は直前の時刻の日付とする
+func parseICalDate(p icalProperty, isEnd bool) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, JST)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s", value)
+		}
+		return t, true, nil
+	}
+
+	loc := JST
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	var t time.Time
+	var err error
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+	} else {
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s", value)
+	}
+	if isEnd {
+		t = t.Add(-time.Nanosecond)
+	}
+	t = t.In(JST)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, JST), false, nil
+}
+
+// splitICalList はカンマ区切りの値を分割する（"\," は区切りとしない）
+func splitICalList(s string) []string {
+	var values []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			values = append(values, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(values, current.String())
+}
+
+// unescapeICalText は TEXT 型の値のエスケープ（\\ \; \, \n）を戻す
+func unescapeICalText(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if escaped {
+			switch r {
+			case 'n', 'N':
+				b.WriteRune('\n')
+			default:
+				b.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,253 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parsedICalEvent は fn に渡された VEVENT とエラーを表す
+type parsedICalEvent struct {
+	event ICalEvent
+	err   string
+}
+
+func parseICalString(t *testing.T, input string) []parsedICalEvent {
+	t.Helper()
+	var got []parsedICalEvent
+	err := ParseICalendar(strings.NewReader(input), func(e ICalEvent, err error) {
+		p := parsedICalEvent{event: e}
+		if err != nil {
+			p.err = err.Error()
+		}
+		got = append(got, p)
+	})
+	if err != nil {
+		t.Fatalf("ParseICalendar() error = %v", err)
+	}
+	return got
+}
+
+func jstDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, JST)
+}
+
+func TestParseICalendar(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []ICalEvent
+	}{
+		{
+			name: "all-day event with exclusive DTEND",
+			input: "BEGIN:VCALENDAR\n" +
+				"BEGIN:VEVENT\n" +
+				"UID:umi-no-hi-2025\n" +
+				"SUMMARY:海の日\n" +
+				"DTSTART;VALUE=DATE:20250721\n" +
+				"DTEND;VALUE=DATE:20250722\n" +
+				"END:VEVENT\n" +
+				"END:VCALENDAR\n",
+			want: []ICalEvent{{Line: 2, UID: "umi-no-hi-2025", Summary: "海の日", Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21)}},
+		},
+		{
+			name: "multi-day all-day event without VALUE parameter",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART:20250811\n" +
+				"DTEND:20250816\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Start: jstDate(2025, 8, 11), End: jstDate(2025, 8, 15)}},
+		},
+		{
+			name: "without DTEND",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART;VALUE=DATE:20250721\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21)}},
+		},
+		{
+			name: "date-time values",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART:20250721T090000\n" +
+				"DTEND:20250723T100000\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 23)}},
+		},
+		{
+			// 日時の DTEND も期間に含まれないため、0時ちょうどに終わる予定は前日までとなる
+			name: "date-time DTEND at midnight",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART:20250721T090000\n" +
+				"DTEND:20250722T000000\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21)}},
+		},
+		{
+			name: "UTC and TZID are converted to JST dates",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART:20250720T150000Z\n" +
+				"DTEND;TZID=\"UTC\":20250721T160000\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 22)}},
+		},
+		{
+			name: "DTEND before DTSTART",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART;VALUE=DATE:20250721\n" +
+				"DTEND;VALUE=DATE:20250701\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21)}},
+		},
+		{
+			name: "folded lines with CRLF and BOM",
+			input: "\ufeffBEGIN:VCALENDAR\r\n" +
+				"BEGIN:VEVENT\r\n" +
+				"UID:very-long-\r\n" +
+				" uid\r\n" +
+				"SUMMARY:夏季\r\n" +
+				"\t休業\r\n" +
+				"DTSTART;VALUE=DATE:2025\r\n" +
+				" 0811\r\n" +
+				"END:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			want: []ICalEvent{{Line: 2, UID: "very-long-uid", Summary: "夏季休業", Start: jstDate(2025, 8, 11), End: jstDate(2025, 8, 11)}},
+		},
+		{
+			name: "escaped text and categories",
+			input: "BEGIN:VEVENT\n" +
+				`SUMMARY:前期\, 定期試験\; 午前\n午後\\` + "\n" +
+				`CATEGORIES:EXAM,,a\,b ,Holiday` + "\n" +
+				"DTSTART;VALUE=DATE:20250728\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{
+				Line: 1, Summary: "前期, 定期試験; 午前\n午後\\", Categories: []string{"EXAM", "a,b", "Holiday"},
+				Start: jstDate(2025, 7, 28), End: jstDate(2025, 7, 28),
+			}},
+		},
+		{
+			name: "nested VALARM is ignored",
+			input: "BEGIN:VEVENT\n" +
+				"SUMMARY:海の日\n" +
+				"BEGIN:VALARM\n" +
+				"SUMMARY:リマインダー\n" +
+				"DTSTART:20250101\n" +
+				"END:VALARM\n" +
+				"DTSTART;VALUE=DATE:20250721\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{{Line: 1, Summary: "海の日", Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21)}},
+		},
+		{
+			name: "RRULE and RDATE are detected",
+			input: "BEGIN:VEVENT\n" +
+				"DTSTART;VALUE=DATE:20250721\n" +
+				"RRULE:FREQ=YEARLY\n" +
+				"END:VEVENT\n" +
+				"BEGIN:VEVENT\n" +
+				"DTSTART;VALUE=DATE:20250721\n" +
+				"RDATE;VALUE=DATE:20260720\n" +
+				"END:VEVENT\n",
+			want: []ICalEvent{
+				{Line: 1, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21), Recurring: true},
+				{Line: 5, Start: jstDate(2025, 7, 21), End: jstDate(2025, 7, 21), Recurring: true},
+			},
+		},
+		{
+			name: "lines outside VEVENT are ignored",
+			input: "BEGIN:VCALENDAR\n" +
+				"X-WR-CALNAME:祝日\n" +
+				"no colon here\n" +
+				"BEGIN:VTIMEZONE\n" +
+				"DTSTART:19700101T000000\n" +
+				"END:VTIMEZONE\n" +
+				"END:VEVENT\n" +
+				"END:VCALENDAR\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseICalString(t, tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i].err != "" {
+					t.Errorf("event %d error = %s", i, got[i].err)
+					continue
+				}
+				assertICalEvent(t, i, got[i].event, tt.want[i])
+			}
+		})
+	}
+}
+
+func TestParseICalendarInvalidEventContinues(t *testing.T) {
+	input := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\n" +
+		"UID:bad-start\n" +
+		"DTSTART:2025-07-21\n" +
+		"END:VEVENT\n" +
+		"BEGIN:VEVENT\n" +
+		"UID:bad-end\n" +
+		"DTSTART;VALUE=DATE:20250721\n" +
+		"DTEND;VALUE=DATE:2025072\n" +
+		"END:VEVENT\n" +
+		"BEGIN:VEVENT\n" +
+		"UID:no-start\n" +
+		"END:VEVENT\n" +
+		"BEGIN:VEVENT\n" +
+		"UID:ok\n" +
+		"DTSTART;VALUE=DATE:20250811\n" +
+		"END:VEVENT\n" +
+		"END:VCALENDAR\n"
+
+	got := parseICalString(t, input)
+	want := []struct {
+		line int
+		uid  string
+		err  string
+	}{
+		{line: 2, uid: "bad-start", err: "invalid DTSTART: 2025-07-21"},
+		{line: 6, uid: "bad-end", err: "invalid DTEND: 2025072"},
+		{line: 11, uid: "no-start", err: "DTSTART is required"},
+		{line: 14, uid: "ok"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].event.Line != w.line || got[i].event.UID != w.uid || got[i].err != w.err {
+			t.Errorf("event %d = line %d, UID %q, error %q, want line %d, UID %q, error %q",
+				i, got[i].event.Line, got[i].event.UID, got[i].err, w.line, w.uid, w.err)
+		}
+	}
+	if !got[3].event.Start.Equal(jstDate(2025, 8, 11)) {
+		t.Errorf("valid event after errors Start = %s", got[3].event.Start)
+	}
+}
+
+func TestParseICalendarLineTooLong(t *testing.T) {
+	input := "BEGIN:VEVENT\nSUMMARY:" + strings.Repeat("a", maxICalLineBytes) + "\nEND:VEVENT\n"
+	err := ParseICalendar(strings.NewReader(input), func(ICalEvent, error) {})
+	if err == nil {
+		t.Error("ParseICalendar() error = nil, want error for a line over the limit")
+	}
+}
+
+// assertICalEvent は VEVENT が want と一致するかを検証する
+func assertICalEvent(t *testing.T, i int, got, want ICalEvent) {
+	t.Helper()
+	if got.Line != want.Line || got.UID != want.UID || got.Summary != want.Summary || got.Recurring != want.Recurring {
+		t.Errorf("event %d = %+v, want %+v", i, got, want)
+	}
+	if !reflect.DeepEqual(got.Categories, want.Categories) {
+		t.Errorf("event %d Categories = %q, want %q", i, got.Categories, want.Categories)
+	}
+	if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+		t.Errorf("event %d period = %s〜%s, want %s〜%s", i,
+			got.Start.Format("2006-01-02"), got.End.Format("2006-01-02"),
+			want.Start.Format("2006-01-02"), want.End.Format("2006-01-02"))
+	}
+}
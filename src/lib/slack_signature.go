@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Slackリクエスト署名のヘッダー名
+const (
+	SlackSignatureHeader = "X-Slack-Signature"
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+)
+
+// SlackSignatureMaxAge はリクエストのタイムスタンプとして許容する現在時刻とのずれ
+// これを超えるリクエストはリプレイ攻撃とみなして拒否する
+const SlackSignatureMaxAge = 5 * time.Minute
+
+var (
+	// ErrSlackSignatureMissing は署名ヘッダーが欠けている場合に返す
+	ErrSlackSignatureMissing = errors.New("missing slack signature headers")
+	// ErrSlackTimestampExpired はタイムスタンプが許容範囲外の場合に返す
+	ErrSlackTimestampExpired = errors.New("slack request timestamp is outside the allowed window")
+	// ErrSlackSignatureMismatch は署名が一致しない場合に返す
+	ErrSlackSignatureMismatch = errors.New("slack signature mismatch")
+)
+
+// ComputeSlackSignature はSlackの署名形式 "v0=<hex(HMAC-SHA256)>" で署名を計算する
+func ComputeSlackSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":"))
+	_, _ = mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySlackSignature はリクエストヘッダーの署名とタイムスタンプを検証する
+func VerifySlackSignature(header http.Header, body []byte, secret string, now time.Time) error {
+	signature := header.Get(SlackSignatureHeader)
+	timestamp := header.Get(SlackTimestampHeader)
+	if signature == "" || timestamp == "" || secret == "" {
+		return ErrSlackSignatureMissing
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid slack request timestamp: %s", timestamp)
+	}
+	diff := now.Sub(time.Unix(ts, 0))
+	if diff > SlackSignatureMaxAge || diff < -SlackSignatureMaxAge {
+		return ErrSlackTimestampExpired
+	}
+
+	expected := ComputeSlackSignature(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSlackSignatureMismatch
+	}
+	return nil
+}
+
+// SignSlackRequest はリクエストにSlackと同じ形式の署名ヘッダーを付与する
+// ハンドラーのテストでSlackからのリクエストをオフラインで再現するために使用する
+func SignSlackRequest(req *http.Request, secret string, now time.Time) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return err
+		}
+		_ = req.Body.Close()
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(SlackTimestampHeader, timestamp)
+	req.Header.Set(SlackSignatureHeader, ComputeSlackSignature(secret, timestamp, body))
+	return nil
+}
@@ -0,0 +1,183 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func signedHeader(secret string, timestamp time.Time, body []byte) http.Header {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	header := http.Header{}
+	header.Set(SlackTimestampHeader, ts)
+	header.Set(SlackSignatureHeader, ComputeSlackSignature(secret, ts, body))
+	return header
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, JST)
+	body := []byte("token=xyz&team_id=T1&user_id=U1&command=%2Fnotify_settings")
+
+	tests := []struct {
+		name    string
+		header  func() http.Header
+		body    []byte
+		secret  string
+		wantErr error
+	}{
+		{
+			name:   "valid signature",
+			header: func() http.Header { return signedHeader(testSigningSecret, now, body) },
+			body:   body,
+			secret: testSigningSecret,
+		},
+		{
+			name: "valid signature within allowed clock skew",
+			header: func() http.Header {
+				return signedHeader(testSigningSecret, now.Add(-SlackSignatureMaxAge+time.Second), body)
+			},
+			body:   body,
+			secret: testSigningSecret,
+		},
+		{
+			name:    "signed with a different secret",
+			header:  func() http.Header { return signedHeader("other-secret", now, body) },
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackSignatureMismatch,
+		},
+		{
+			name:    "tampered body",
+			header:  func() http.Header { return signedHeader(testSigningSecret, now, body) },
+			body:    []byte(string(body) + "&user_id=UADMIN"),
+			secret:  testSigningSecret,
+			wantErr: ErrSlackSignatureMismatch,
+		},
+		{
+			name: "malformed signature",
+			header: func() http.Header {
+				header := signedHeader(testSigningSecret, now, body)
+				header.Set(SlackSignatureHeader, "v0=deadbeef")
+				return header
+			},
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackSignatureMismatch,
+		},
+		{
+			name: "expired timestamp",
+			header: func() http.Header {
+				return signedHeader(testSigningSecret, now.Add(-SlackSignatureMaxAge-time.Second), body)
+			},
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackTimestampExpired,
+		},
+		{
+			name: "timestamp too far in the future",
+			header: func() http.Header {
+				return signedHeader(testSigningSecret, now.Add(SlackSignatureMaxAge+time.Second), body)
+			},
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackTimestampExpired,
+		},
+		{
+			// 過去に傍受した正しい署名のリクエストをそのまま再送した場合
+			name:    "replayed request after the window",
+			header:  func() http.Header { return signedHeader(testSigningSecret, now.Add(-time.Hour), body) },
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackTimestampExpired,
+		},
+		{
+			// 傍受した署名のタイムスタンプだけを現在時刻に書き換えて再送した場合
+			name: "replayed signature with refreshed timestamp",
+			header: func() http.Header {
+				header := signedHeader(testSigningSecret, now.Add(-time.Hour), body)
+				header.Set(SlackTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+				return header
+			},
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackSignatureMismatch,
+		},
+		{
+			name: "missing signature header",
+			header: func() http.Header {
+				header := signedHeader(testSigningSecret, now, body)
+				header.Del(SlackSignatureHeader)
+				return header
+			},
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackSignatureMissing,
+		},
+		{
+			name: "missing timestamp header",
+			header: func() http.Header {
+				header := signedHeader(testSigningSecret, now, body)
+				header.Del(SlackTimestampHeader)
+				return header
+			},
+			body:    body,
+			secret:  testSigningSecret,
+			wantErr: ErrSlackSignatureMissing,
+		},
+		{
+			name:    "signing secret not configured",
+			header:  func() http.Header { return signedHeader("", now, body) },
+			body:    body,
+			secret:  "",
+			wantErr: ErrSlackSignatureMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySlackSignature(tt.header(), tt.body, tt.secret, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySlackSignature() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySlackSignatureInvalidTimestamp(t *testing.T) {
+	header := http.Header{}
+	header.Set(SlackTimestampHeader, "not-a-number")
+	header.Set(SlackSignatureHeader, ComputeSlackSignature(testSigningSecret, "not-a-number", nil))
+	if err := VerifySlackSignature(header, nil, testSigningSecret, time.Now()); err == nil {
+		t.Error("VerifySlackSignature() accepted a non-numeric timestamp")
+	}
+}
+
+func TestSignSlackRequest(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, JST)
+	body := []byte("payload=%7B%22type%22%3A%22block_actions%22%7D")
+	req, err := http.NewRequest(http.MethodPost, "/slack/interaction", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignSlackRequest(req, testSigningSecret, now); err != nil {
+		t.Fatalf("SignSlackRequest() error = %v", err)
+	}
+
+	// 署名後も本文を読み直せること
+	got, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("body after signing = %q, want %q", got, body)
+	}
+	if err := VerifySlackSignature(req.Header, got, testSigningSecret, now); err != nil {
+		t.Errorf("VerifySlackSignature() of signed request error = %v", err)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"github.com/kajiLabTeam/stay-watch-slackbot/router"
+	"github.com/kajiLabTeam/stay-watch-slackbot/scheduler"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
 )
 
 // @title Stay Watch Slackbot API
@@ -9,6 +13,15 @@ import (
 // @description 研究室の来訪予測・活動管理のためのAPI
 // @host localhost:8085
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
+	service.SeedAdmins()
+	scheduler.Start()
 	router.Router()
 }
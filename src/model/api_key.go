@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+func (k *APIKey) Create() error {
+	if err := db.Create(k).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadByKeyHash はキーのハッシュから有効な（失効していない）APIキーを取得する。見つからない場合 ID は 0 のまま
+func (k *APIKey) ReadByKeyHash() error {
+	if err := db.Where("key_hash = ? AND revoked_at IS NULL", k.KeyHash).Limit(1).Find(k).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadActiveByName は名前から有効な（失効していない）APIキーを取得する。見つからない場合 ID は 0 のまま
+func (k *APIKey) ReadActiveByName() error {
+	if err := db.Where("name = ? AND revoked_at IS NULL", k.Name).Limit(1).Find(k).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadAllActive は有効な（失効していない）APIキーの一覧を取得する
+func (k *APIKey) ReadAllActive() ([]APIKey, error) {
+	var keys []APIKey
+	if err := db.Where("revoked_at IS NULL").Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (k *APIKey) UpdateLastUsedAt(t time.Time) error {
+	k.LastUsedAt = &t
+	return db.Model(k).Update("last_used_at", t).Error
+}
+
+func (k *APIKey) UpdateRevokedAt(t time.Time) error {
+	k.RevokedAt = &t
+	return db.Model(k).Update("revoked_at", t).Error
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+func (c *CalendarEntry) Create() error {
+	if err := db.Create(c).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update は予定を更新する。論理削除済みの予定を DeletedAt を戻して更新できるよう Unscoped で保存する
+func (c *CalendarEntry) Update() error {
+	if err := db.Unscoped().Save(c).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CalendarEntry) ReadByID() error {
+	if err := db.First(c, c.ID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadByUID は UID から予定を取得する。見つからない場合 ID は 0 のまま
+// UNIQUE 制約は論理削除済みの予定にも残るため、論理削除済みの予定も対象とする
+func (c *CalendarEntry) ReadByUID() error {
+	if err := db.Unscoped().Where("uid = ?", c.UID).Limit(1).Find(c).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *CalendarEntry) Delete() error {
+	if err := db.Delete(c).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadCalendarEntriesBetween は期間が from〜until（日付、両端を含む）と重なる予定を開始日順に取得する
+// from または until がゼロ値の場合は、その側の範囲を限定しない
+func ReadCalendarEntriesBetween(from, until time.Time) ([]CalendarEntry, error) {
+	var entries []CalendarEntry
+	query := db
+	if !until.IsZero() {
+		query = query.Where("start_date <= ?", until.Format("2006-01-02"))
+	}
+	if !from.IsZero() {
+		query = query.Where("end_date >= ?", from.Format("2006-01-02"))
+	}
+	if err := query.Order("start_date").Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -21,6 +21,14 @@ func (e *Event) ReadByName() error {
 	return nil
 }
 
+// ReadByCode はデバイスが送信するイベントコードからイベントを取得する
+func (e *Event) ReadByCode() error {
+	if err := db.Where("code = ?", e.Code).First(e).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 func (e *Event) ReadAll() ([]Event, error) {
 	var events []Event
 	if err := db.Find(&events).Error; err != nil {
@@ -39,3 +39,13 @@ func (eu *EventUser) ReadByUserIDs(userIDs []uint) ([]EventUser, error) {
 	}
 	return eventUsers, nil
 }
+
+// DeleteByEventIDAndUserID は Event と User の対応を物理削除する
+// UNIQUE 制約のある中間テーブルのため、論理削除では同じ対応を再登録できなくなる
+func (eu *EventUser) DeleteByEventIDAndUserID() (int64, error) {
+	result := db.Unscoped().Where("event_id = ? AND user_id = ?", eu.EventID, eu.UserID).Delete(&EventUser{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
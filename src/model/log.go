@@ -1,11 +1,26 @@
 package model
 
 import (
+	"database/sql"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// LogFilter はログの検索条件を表す。ゼロ値の項目は条件に含めない
+type LogFilter struct {
+	EventID        uint
+	StatusNames    []string
+	UserID         uint      // 在室または参加しているユーザー
+	ParticipantID  uint      // 参加しているユーザー
+	From           time.Time // 発生時刻の下限（含む）
+	Until          time.Time // 発生時刻の上限（含まない）
+	IncludeDeleted bool      // 論理削除済みのログも含める
+	Cursor         uint      // 前回取得した最後のログID。これより後（発生時刻が古い側）を取得する
+	Limit          int
+}
+
 func (l *Log) Create() error {
 	if err := db.Create(l).Error; err != nil {
 		return err
@@ -42,6 +57,132 @@ func (l *Log) ReadByID() error {
 	return nil
 }
 
+// ReadByIdempotencyKey は重複排除キーからログを取得する。見つからない場合 ID は 0 のまま
+// UNIQUE 制約は論理削除済みのログにも残るため、論理削除済みのログも対象とする
+func (l *Log) ReadByIdempotencyKey() error {
+	if err := db.Unscoped().Preload("Event").Preload("Status").Where("idempotency_key = ?", l.IdempotencyKey).Limit(1).Find(l).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadByNaturalKey はイベント・ステータス・発生時刻が一致するログを取得する。見つからない場合 ID は 0 のまま
+func (l *Log) ReadByNaturalKey() error {
+	if err := db.Preload("Event").Preload("Status").
+		Where("event_id = ? AND status_id = ? AND event_time = ?", l.EventID, l.StatusID, l.EventTime).
+		Limit(1).Find(l).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadByIDWithUsers は論理削除済みのログも含めて、在室・参加ユーザーとともにログを取得する
+func (l *Log) ReadByIDWithUsers() error {
+	if err := db.Unscoped().
+		Preload("Event").Preload("Status").Preload("RoomUsers").Preload("ParticipateUsers").
+		First(l, l.ID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateWithUsers はログを更新する。roomUserIDs / participateUserIDs が nil でない場合は在室・参加ユーザーを置き換える
+func (l *Log) UpdateWithUsers(roomUserIDs, participateUserIDs *[]uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(l).Error; err != nil {
+			return err
+		}
+		if roomUserIDs != nil {
+			if err := tx.Unscoped().Where("log_id = ?", l.ID).Delete(&LogsUserRoom{}).Error; err != nil {
+				return err
+			}
+			for _, uid := range *roomUserIDs {
+				row := LogsUserRoom{LogID: l.ID, UserID: uid}
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if participateUserIDs != nil {
+			if err := tx.Unscoped().Where("log_id = ?", l.ID).Delete(&LogsUserParticipate{}).Error; err != nil {
+				return err
+			}
+			for _, uid := range *participateUserIDs {
+				row := LogsUserParticipate{LogID: l.ID, UserID: uid}
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Delete はログを論理削除する
+func (l *Log) Delete() error {
+	return db.Delete(l).Error
+}
+
+// Restore は論理削除したログを元に戻す
+func (l *Log) Restore() error {
+	return db.Unscoped().Model(l).Update("deleted_at", nil).Error
+}
+
+// ReadLogsByFilter は条件に一致するログを発生時刻の新しい順に、在室・参加ユーザーとともに取得する
+// 同時刻のログは ID の大きい順とし、Cursor にはその順序での前回の最後のログIDを指定する
+func ReadLogsByFilter(filter LogFilter) ([]Log, error) {
+	var logs []Log
+	query := logFilterQuery(filter)
+	if filter.Cursor != 0 {
+		query = query.Where("(logs.event_time < (SELECT event_time FROM logs WHERE id = ?) OR (logs.event_time = (SELECT event_time FROM logs WHERE id = ?) AND logs.id < ?))",
+			filter.Cursor, filter.Cursor, filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Order("logs.event_time DESC").Order("logs.id DESC").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// FindLogsInBatches は条件に一致するログを ID 順に batchSize 件ずつ、在室・参加ユーザーとともに取得して fn に渡す
+// 全件をメモリに載せずに処理するためのもので、Cursor と Limit は使わない
+func FindLogsInBatches(filter LogFilter, batchSize int, fn func([]Log) error) error {
+	var logs []Log
+	return logFilterQuery(filter).FindInBatches(&logs, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(logs)
+	}).Error
+}
+
+// logFilterQuery は検索条件（Cursor と Limit を除く）を適用したクエリを返す
+func logFilterQuery(filter LogFilter) *gorm.DB {
+	query := db.Preload("Event").Preload("Status").Preload("RoomUsers").Preload("ParticipateUsers")
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if filter.EventID != 0 {
+		query = query.Where("logs.event_id = ?", filter.EventID)
+	}
+	if len(filter.StatusNames) > 0 {
+		query = query.Where("logs.status_id IN (SELECT id FROM statuses WHERE name IN ?)", filter.StatusNames)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("(logs.id IN (SELECT log_id FROM logs_user_rooms WHERE user_id = ?) OR logs.id IN (SELECT log_id FROM logs_user_participates WHERE user_id = ?))",
+			filter.UserID, filter.UserID)
+	}
+	if filter.ParticipantID != 0 {
+		query = query.Where("logs.id IN (SELECT log_id FROM logs_user_participates WHERE user_id = ?)", filter.ParticipantID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("logs.event_time >= ?", filter.From)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("logs.event_time < ?", filter.Until)
+	}
+	return query
+}
+
 func (l *Log) ReadAll() ([]Log, error) {
 	var logs []Log
 	if err := db.Preload("Event").Preload("Status").Find(&logs).Error; err != nil {
@@ -78,8 +219,9 @@ func ReadLogsByEventIDAndDayOfWeek(eventID uint, dayOfWeek time.Weekday) ([]Log,
 	// dayOfWeekをMysqlのWEEKDAY関数に合わせて変換 (0=月曜日, ..., 6=日曜日)
 	mysqlDayOfWeek := (int(dayOfWeek) + 6) % 7
 
-	// 指定した曜日のログを全て取得
+	// 指定した曜日のログを全て取得（予測の結果が行の順序によらないよう、発生時刻順とする）
 	if err := db.Where("event_id = ? AND WEEKDAY(logs.event_time) = ?", eventID, mysqlDayOfWeek).
+		Order("logs.event_time").Order("logs.id").
 		Preload("Event").
 		Preload("Status").
 		Find(&logs).Error; err != nil {
@@ -89,15 +231,16 @@ func ReadLogsByEventIDAndDayOfWeek(eventID uint, dayOfWeek time.Weekday) ([]Log,
 	return logs, nil
 }
 
-// ReadLogsByEventIDAndDayOfWeekWithWeeks retrieves logs by event ID, day of week, and number of weeks
-func ReadLogsByEventIDAndDayOfWeekWithWeeks(eventID uint, dayOfWeek time.Weekday, weeks int) ([]Log, error) {
+// ReadLogsByEventIDAndDayOfWeekSince は指定した曜日のログのうち、since 以降のものを取得する
+// 期間の絞り込みはSQLで行い、対象外の古いログは読み込まない
+func ReadLogsByEventIDAndDayOfWeekSince(eventID uint, dayOfWeek time.Weekday, since time.Time) ([]Log, error) {
 	var logs []Log
 
 	// dayOfWeekをMysqlのWEEKDAY関数に合わせて変換 (0=月曜日, ..., 6=日曜日)
 	mysqlDayOfWeek := (int(dayOfWeek) + 6) % 7
 
-	// 指定した曜日、指定した週数のログを全て取得
-	if err := db.Where("event_id = ? AND WEEKDAY(logs.event_time) = ?", eventID, mysqlDayOfWeek).
+	if err := db.Where("event_id = ? AND WEEKDAY(logs.event_time) = ? AND logs.event_time >= ?", eventID, mysqlDayOfWeek, since).
+		Order("logs.event_time").Order("logs.id").
 		Preload("Event").
 		Preload("Status").
 		Find(&logs).Error; err != nil {
@@ -106,3 +249,15 @@ func ReadLogsByEventIDAndDayOfWeekWithWeeks(eventID uint, dayOfWeek time.Weekday
 
 	return logs, nil
 }
+
+// ReadFirstLogTimeByEventID はイベントの最初のログの発生時刻を取得する。ログがない場合はゼロ値を返す
+func ReadFirstLogTimeByEventID(eventID uint) (time.Time, error) {
+	var first sql.NullTime
+	if err := db.Model(&Log{}).Where("event_id = ?", eventID).Select("MIN(event_time)").Scan(&first).Error; err != nil {
+		return time.Time{}, err
+	}
+	if !first.Valid {
+		return time.Time{}, nil
+	}
+	return first.Time, nil
+}
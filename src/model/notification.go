@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+func (n *Notification) Create() error {
+	if err := db.Create(n).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadByTargetDate は通知対象日から実行記録を取得する。見つからない場合 ID は 0 のまま
+func (n *Notification) ReadByTargetDate(targetDate time.Time) error {
+	if err := db.Where("target_date = ?", targetDate.Format("2006-01-02")).Limit(1).Find(n).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (n *Notification) Update() error {
+	if err := db.Save(n).Error; err != nil {
+		return err
+	}
+	return nil
+}
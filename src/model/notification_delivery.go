@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// NotificationDeliveryFilter は送信記録の検索条件を表す。ゼロ値の項目は条件に含めない
+type NotificationDeliveryFilter struct {
+	NotificationID uint
+	UserID         uint
+	TargetDate     string // "2006-01-02"
+	Status         string
+	Limit          int
+}
+
+func (d *NotificationDelivery) Create() error {
+	if err := db.Omit("Notification", "User").Create(d).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *NotificationDelivery) Update() error {
+	if err := db.Omit("Notification", "User").Save(d).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadNotificationDeliveries は条件に一致する送信記録を新しい順に取得する
+func ReadNotificationDeliveries(filter NotificationDeliveryFilter) ([]NotificationDelivery, error) {
+	var deliveries []NotificationDelivery
+	query := db.Preload("Notification").Preload("User").
+		Joins("JOIN notifications ON notifications.id = notification_deliveries.notification_id")
+	if filter.NotificationID != 0 {
+		query = query.Where("notification_deliveries.notification_id = ?", filter.NotificationID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("notification_deliveries.user_id = ?", filter.UserID)
+	}
+	if filter.TargetDate != "" {
+		query = query.Where("notifications.target_date = ?", filter.TargetDate)
+	}
+	if filter.Status != "" {
+		query = query.Where("notification_deliveries.status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Order("notification_deliveries.id DESC").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ReadDueNotificationDeliveries は送信予定時刻を過ぎた未送信（pending / retrying）の送信記録を取得する
+func ReadDueNotificationDeliveries(now time.Time) ([]NotificationDelivery, error) {
+	var deliveries []NotificationDelivery
+	if err := db.Preload("User").
+		Where("status IN ? AND next_attempt_at <= ?", []string{"pending", "retrying"}, now).
+		Order("next_attempt_at").
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,25 @@
+package model
+
+// ReadByUserID はユーザーIDから通知設定を取得する。未設定の場合 ID は 0 のまま
+func (p *NotificationPreference) ReadByUserID() error {
+	if err := db.Where("user_id = ?", p.UserID).Limit(1).Find(p).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *NotificationPreference) ReadAll() ([]NotificationPreference, error) {
+	var preferences []NotificationPreference
+	if err := db.Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// Save は通知設定を作成または更新する
+func (p *NotificationPreference) Save() error {
+	if err := db.Omit("User").Save(p).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ type User struct {
 	SlackID     string
 	StayWatchID int64
 	IconURL     string
+	IsAdmin     bool        `gorm:"default:false"` // 破壊的なコマンドを実行できる管理者
 	EventUsers  []EventUser `gorm:"foreignKey:UserID"`
 }
 
@@ -29,10 +30,12 @@ type Status struct {
 // Event は活動イベントを表す
 type Event struct {
 	gorm.Model
-	Name       string `gorm:"type:varchar(255);uniqueIndex;not null"` // スマブラ、人生ゲーム など
-	Code       string `gorm:"type:varchar(255);uniqueIndex;not null"` // イベントを一意に定める識別子（例: 1, 2, 0437ac48be2a81）
-	MinNumber  int    `gorm:"default:2"`                              // 最低必要人数
-	EventUsers []EventUser `gorm:"foreignKey:EventID"`
+	Name            string      `gorm:"type:varchar(255);uniqueIndex;not null"`     // スマブラ、人生ゲーム など
+	Code            string      `gorm:"type:varchar(255);uniqueIndex;not null"`     // イベントを一意に定める識別子（例: 1, 2, 0437ac48be2a81）
+	MinNumber       int         `gorm:"default:2"`                                  // 最低必要人数
+	TimeModel       string      `gorm:"type:varchar(16);default:'linear';not null"` // 活動開始時刻の時間軸の扱い（linear / circular）
+	PredictionModel string      `gorm:"type:varchar(16);default:'gmm';not null"`    // 活動開始時刻の分布のモデル（gmm / kde / histogram）
+	EventUsers      []EventUser `gorm:"foreignKey:EventID"`
 }
 
 // EventUser は Event と User の関係を表す中間テーブル
@@ -54,9 +57,10 @@ type Log struct {
 	EventID          uint
 	Event            Event `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	StatusID         uint
-	Status           Status `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	RoomUsers        []User `gorm:"many2many:logs_user_rooms;"`
-	ParticipateUsers []User `gorm:"many2many:logs_user_participates;"`
+	Status           Status  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	RoomUsers        []User  `gorm:"many2many:logs_user_rooms;"`
+	ParticipateUsers []User  `gorm:"many2many:logs_user_participates;"`
+	IdempotencyKey   *string `gorm:"type:varchar(255);uniqueIndex"` // 送信元が付与する重複排除キー（再送時に同じ値を送る）
 }
 
 // LogsUserRoom は Log と User の中間テーブル（在室ユーザー）
@@ -77,6 +81,70 @@ type LogsUserParticipate struct {
 	User   User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
+// Notification は活動通知の実行1回分を表す（対象日ごとに1件）
+type Notification struct {
+	gorm.Model
+	TargetDate  time.Time  `gorm:"type:date;uniqueIndex;not null"` // 通知対象日（JST）
+	TriggeredBy string     `gorm:"type:varchar(32)"`               // scheduler / http
+	Status      string     `gorm:"type:varchar(32);index"`         // running / completed / failed
+	StartedAt   time.Time  // 実行開始時刻
+	FinishedAt  *time.Time // 実行終了時刻（実行中は NULL）
+	Error       string     `gorm:"type:text"` // 失敗時のエラー内容
+}
+
+// NotificationDelivery は通知実行におけるユーザー1人分のDM送信記録を表す
+type NotificationDelivery struct {
+	gorm.Model
+	NotificationID uint         `gorm:"uniqueIndex:idx_notification_user"`
+	Notification   Notification `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID         uint         `gorm:"index;uniqueIndex:idx_notification_user"`
+	User           User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	EventIDs       string       `gorm:"type:varchar(255)"`      // 通知に含まれるイベントID（カンマ区切り）
+	Message        string       `gorm:"type:text"`              // 送信した本文
+	SlackChannel   string       `gorm:"type:varchar(64)"`       // 送信先のDMチャンネルID
+	SlackTS        string       `gorm:"type:varchar(64)"`       // 送信したメッセージのタイムスタンプ
+	Status         string       `gorm:"type:varchar(32);index"` // pending / sent / retrying / failed
+	Error          string       `gorm:"type:text"`              // 直近の送信失敗時のエラー内容
+	Attempts       int          // 送信試行回数
+	NextAttemptAt  *time.Time   `gorm:"index"` // 次回の送信予定時刻（pending は希望送信時刻、retrying は再送予定時刻）
+	SentAt         *time.Time   // 送信完了時刻
+}
+
+// NotificationPreference はユーザーごとの通知設定を表す
+type NotificationPreference struct {
+	gorm.Model
+	UserID         uint    `gorm:"uniqueIndex"`
+	User           User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Muted          bool    // すべての通知を停止する
+	MutedWeekdays  string  `gorm:"type:varchar(32)"`  // 通知しない曜日（MySQL WEEKDAY形式 0=月〜6=日、カンマ区切り）
+	MutedEventIDs  string  `gorm:"type:varchar(255)"` // 通知しないイベントID（カンマ区切り）
+	DeliveryTime   string  `gorm:"type:varchar(5)"`   // 希望送信時刻 "HH:MM"（空なら通知実行時に送信）
+	MinProbability float64 // 通知する活動確率の下限（0 なら全体の閾値のみ適用）
+}
+
+// APIKey はREST APIの認証に使うAPIキーを表す
+// キー本体は保存せず、SHA-256 ハッシュのみを保存する
+type APIKey struct {
+	gorm.Model
+	Name       string     `gorm:"type:varchar(255);index;not null"`   // 用途を表す名前（例: logger-room1, board）
+	Prefix     string     `gorm:"type:varchar(16);index"`             // キーの先頭部分（一覧での識別用）
+	KeyHash    string     `gorm:"type:char(64);uniqueIndex;not null"` // キーの SHA-256（16進数）
+	Scopes     string     `gorm:"type:varchar(255)"`                  // read / write-logs / admin（カンマ区切り）
+	CreatedBy  string     `gorm:"type:varchar(64)"`                   // 発行したSlackユーザーID（CLIの場合は "cli"）
+	LastUsedAt *time.Time // 最終使用時刻
+	RevokedAt  *time.Time `gorm:"index"` // 失効時刻（有効なキーは NULL）
+}
+
+// CalendarEntry は学事暦の祝日・休暇・試験期間などを表す（期間は開始日・終了日を含む）
+type CalendarEntry struct {
+	gorm.Model
+	UID       string    `gorm:"type:varchar(255);uniqueIndex;not null"` // iCalendar の UID（再取り込み時に同じ予定を更新する）
+	Summary   string    `gorm:"type:varchar(255)"`                      // 海の日、夏季休業 など
+	Kind      string    `gorm:"type:varchar(32);index"`                 // holiday / break / exam / term
+	StartDate time.Time `gorm:"type:date;index;not null"`               // 開始日（JST）
+	EndDate   time.Time `gorm:"type:date;index;not null"`               // 終了日（JST）
+}
+
 // UserDetail は来訪予測を含む詳細なユーザー情報を表す
 type UserDetail struct {
 	User             User
@@ -89,7 +157,7 @@ var db *gorm.DB
 
 func init() {
 	db = lib.SQLConnect()
-	if err := db.AutoMigrate(&User{}, &Status{}, &Event{}, &EventUser{}, &Log{}, &LogsUserRoom{}, &LogsUserParticipate{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &Status{}, &Event{}, &EventUser{}, &Log{}, &LogsUserRoom{}, &LogsUserParticipate{}, &Notification{}, &NotificationDelivery{}, &NotificationPreference{}, &APIKey{}, &CalendarEntry{}); err != nil {
 		log.Fatalf("AutoMigrate failed: %v", err)
 	}
 }
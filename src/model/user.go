@@ -53,10 +53,38 @@ func (u *User) ReadAll() ([]User, error) {
 	return users, nil
 }
 
+// ReadByIDWithEvents はIDからユーザーを登録イベントを含めて取得する
+func (u *User) ReadByIDWithEvents() error {
+	if err := db.Preload("EventUsers.Event").First(u, u.ID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *User) Update() error {
+	if err := db.Omit("EventUsers").Save(u).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 func (u *User) UpdateIconURL() error {
 	return db.Model(u).Update("icon_url", u.IconURL).Error
 }
 
+func (u *User) UpdateIsAdmin() error {
+	return db.Model(u).Update("is_admin", u.IsAdmin).Error
+}
+
+// ReadBySlackIDs は複数の SlackID からユーザーをバッチで取得する
+func (u *User) ReadBySlackIDs(slackIDs []string) ([]User, error) {
+	var users []User
+	if err := db.Where("slack_id IN ?", slackIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // Delete はユーザを削除する（gorm.DeletedAtによる論理削除）
 func (u *User) Delete() error {
 	if err := db.Delete(u).Error; err != nil {
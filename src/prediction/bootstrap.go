@@ -0,0 +1,93 @@
+package prediction
+
+import (
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// ブートストラップの既定値
+const (
+	DefaultBootstrapReplicates = 50  // 当てはめ直す回数
+	DefaultConfidenceLevel     = 0.9 // 信頼区間の水準
+)
+
+// bootstrapRestarts ブートストラップで GMM を当てはめ直す際のクラスタリングの試行回数
+const bootstrapRestarts = 2
+
+// BootstrapDatetimes は観測機会（週）を重複を許して取り出し直したデータで、base と同じ種類のモデルを replicates 回当てはめる
+// 活動のなかった週も観測機会として取り出すため、活動する頻度と時刻の両方のばらつきが反映される
+// 確率の分母は元のデータと同じ値を使い、乱数のシードはデータから決めるため同じデータからは常に同じ結果になる
+// data・ages・estimator・uniqueDate は base の当てはめに使ったものを渡す
+func BootstrapDatetimes(base Model, data []string, ages []int, estimator Estimator, uniqueDate bool, replicates int) ([]Model, error) {
+	observations, err := parseObservations(data, estimator, uniqueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重みが 0 でない週を観測機会とし、観測を週ごとにまとめる
+	slots := make(map[int][]Observation)
+	for _, age := range ages {
+		if estimator.weightAt(age) > 0 {
+			slots[age] = nil
+		}
+	}
+	seed := make([]float64, 0, len(observations))
+	for _, o := range observations {
+		slots[o.age] = append(slots[o.age], o)
+		seed = append(seed, float64(o.Minutes))
+	}
+	slotAges := make([]int, 0, len(slots))
+	for age := range slots {
+		slotAges = append(slotAges, age)
+	}
+	sort.Ints(slotAges)
+
+	totalWeight := estimator.totalWeight(ages)
+	rng := rand.New(rand.NewSource(DataSeed(seed)))
+	models := make([]Model, 0, replicates)
+	for r := 0; r < replicates; r++ {
+		sample := make([]Observation, 0, len(observations))
+		for range slotAges {
+			sample = append(sample, slots[slotAges[rng.Intn(len(slotAges))]]...)
+		}
+		m := newReplicate(base)
+		m.Fit(sample, totalWeight)
+		models = append(models, m)
+	}
+	return models, nil
+}
+
+// newReplicate は base と同じ種類の、当てはめる前のモデルを作る
+// 点推定と同じ区間の確率を比べられるよう circular の Origin は base の値に固定する
+// 計算量を抑えるため、gmm のクラスタ数は base で選ばれた数に固定し、試行回数も減らす
+func newReplicate(base Model) Model {
+	switch b := base.(type) {
+	case *CircularModel:
+		return &CircularModel{Inner: newReplicate(b.Inner), Origin: b.Origin, fixedOrigin: true}
+	case *KernelDensity:
+		return &KernelDensity{}
+	case *Histogram:
+		return &Histogram{}
+	case *MixtureModel:
+		clusters := len(b.Components)
+		if clusters == 0 {
+			clusters = 1
+		}
+		return &MixtureModel{clusters: clusters, restarts: bootstrapRestarts}
+	}
+	return &MixtureModel{}
+}
+
+// PercentileInterval は values の分位点から、水準 level の信頼区間（下限, 上限）を求める
+// values が空の場合は (0, 0) を返す
+func PercentileInterval(values []float64, level float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	alpha := (1 - level) / 2
+	return stat.Quantile(alpha, stat.LinInterp, sorted, nil), stat.Quantile(1-alpha, stat.LinInterp, sorted, nil)
+}
@@ -0,0 +1,164 @@
+package prediction
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+)
+
+// weeklyDatetimes は now の weeksAgo[i] 週前の日付に times[i] の日時を作る
+func weeklyDatetimes(now time.Time, weeksAgo []int, times []string) []string {
+	datetimes := make([]string, len(weeksAgo))
+	for i, w := range weeksAgo {
+		datetimes[i] = now.AddDate(0, 0, -7*w).Format("2006-01-02") + " " + times[i]
+	}
+	return datetimes
+}
+
+func TestBootstrapDatetimes(t *testing.T) {
+	now := time.Date(2025, 6, 13, 21, 0, 0, 0, lib.JST)
+	data := weeklyDatetimes(now,
+		[]int{1, 2, 3, 5, 6, 7},
+		[]string{"10:00", "10:20", "09:40", "10:10", "10:30", "09:50"})
+	ages := WeekAges(now, 8, nil)
+	estimator := Estimator{Mode: EstimatorUniform, Now: now}
+
+	for _, spec := range []ModelSpec{
+		{Kind: ModelGMM},
+		{Kind: ModelKDE},
+		{Kind: ModelHistogram},
+		{Kind: ModelGMM, TimeModel: TimeModelCircular},
+	} {
+		t.Run(spec.Kind+"/"+spec.TimeModel, func(t *testing.T) {
+			base, err := FitDatetimes(data, ages, estimator, false, spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, err := BootstrapDatetimes(base, data, ages, estimator, false, DefaultBootstrapReplicates)
+			if err != nil {
+				t.Fatalf("BootstrapDatetimes() error = %v", err)
+			}
+			second, err := BootstrapDatetimes(base, data, ages, estimator, false, DefaultBootstrapReplicates)
+			if err != nil {
+				t.Fatalf("BootstrapDatetimes() error = %v", err)
+			}
+			if len(first) != DefaultBootstrapReplicates {
+				t.Fatalf("got %d replicates, want %d", len(first), DefaultBootstrapReplicates)
+			}
+
+			for _, minute := range []float64{540, 600, 630, 720} {
+				firstValues := replicateCDFs(first, minute)
+				// 乱数のシードはデータから決めるため、同じデータからは同じ結果になる
+				if secondValues := replicateCDFs(second, minute); !reflect.DeepEqual(firstValues, secondValues) {
+					t.Fatalf("CDF(%v) of replicates differ between runs", minute)
+				}
+				lower, upper := PercentileInterval(firstValues, DefaultConfidenceLevel)
+				point := base.CDF(minute)
+				if lower > point+1e-9 || point > upper+1e-9 {
+					t.Errorf("CDF(%v): interval [%v, %v] does not contain the point estimate %v", minute, lower, upper, point)
+				}
+				if lower < 0 || upper > 1+1e-9 {
+					t.Errorf("CDF(%v): interval [%v, %v] is outside [0, 1]", minute, lower, upper)
+				}
+			}
+			// 活動した週・しなかった週の両方を取り出し直すため、区間には幅がある
+			lower, upper := PercentileInterval(replicateCDFs(first, 720), DefaultConfidenceLevel)
+			if upper-lower <= 0 {
+				t.Errorf("CDF(12:00) interval [%v, %v] has no width", lower, upper)
+			}
+
+			if circular, ok := base.(*CircularModel); ok {
+				for _, r := range first {
+					if got := r.(*CircularModel).Origin; got != circular.Origin {
+						t.Fatalf("replicate Origin = %v, want the base origin %v", got, circular.Origin)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBootstrapDatetimesWithoutData(t *testing.T) {
+	now := time.Date(2025, 6, 13, 21, 0, 0, 0, lib.JST)
+	estimator := Estimator{Mode: EstimatorUniform, Now: now}
+	data := weeklyDatetimes(now, []int{1}, []string{"10:00"})
+
+	tests := []struct {
+		name  string
+		data  []string
+		weeks int
+	}{
+		{name: "no data", data: nil, weeks: 4},
+		{name: "no weeks", data: nil, weeks: 0},
+		{name: "data without weeks", data: data, weeks: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := FitDatetimes(tt.data, WeekAges(now, tt.weeks, nil), estimator, false, ModelSpec{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			replicates, err := BootstrapDatetimes(base, tt.data, WeekAges(now, tt.weeks, nil), estimator, false, 10)
+			if err != nil {
+				t.Fatalf("BootstrapDatetimes() error = %v", err)
+			}
+			values := replicateCDFs(replicates, 720)
+			for _, v := range values {
+				if math.IsNaN(v) {
+					t.Fatal("replicate CDF is NaN")
+				}
+			}
+			if lower, upper := PercentileInterval(values, DefaultConfidenceLevel); lower != 0 || upper != 0 {
+				t.Errorf("PercentileInterval() = [%v, %v], want [0, 0]", lower, upper)
+			}
+		})
+	}
+}
+
+func TestBootstrapDatetimesInvalidData(t *testing.T) {
+	estimator := Estimator{Mode: EstimatorUniform, Now: time.Date(2025, 6, 13, 21, 0, 0, 0, lib.JST)}
+	if _, err := BootstrapDatetimes(&MixtureModel{}, []string{"2025-06-06T10:00"}, []int{0, 1, 2, 3}, estimator, false, 10); err == nil {
+		t.Error("BootstrapDatetimes() error = nil, want invalid datetime")
+	}
+}
+
+func TestPercentileInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []float64
+		level     float64
+		wantLower float64
+		wantUpper float64
+	}{
+		{name: "empty", values: nil, level: 0.9},
+		{name: "single value", values: []float64{0.4}, level: 0.9, wantLower: 0.4, wantUpper: 0.4},
+		{name: "unsorted values", values: []float64{11, 3, 7, 1, 9, 5, 2, 10, 4, 8, 6}, level: 0.8, wantLower: 1.1, wantUpper: 9.9},
+		{name: "full range", values: []float64{3, 1, 2}, level: 1, wantLower: 1, wantUpper: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]float64(nil), tt.values...)
+			lower, upper := PercentileInterval(tt.values, tt.level)
+			if math.Abs(lower-tt.wantLower) > 1e-9 || math.Abs(upper-tt.wantUpper) > 1e-9 {
+				t.Errorf("PercentileInterval() = [%v, %v], want [%v, %v]", lower, upper, tt.wantLower, tt.wantUpper)
+			}
+			if !reflect.DeepEqual(tt.values, input) {
+				t.Errorf("PercentileInterval() modified its input: %v", tt.values)
+			}
+		})
+	}
+}
+
+// replicateCDFs は各モデルの CDF(minute) を返す
+func replicateCDFs(models []Model, minute float64) []float64 {
+	values := make([]float64, len(models))
+	for i, m := range models {
+		values[i] = m.CDF(minute)
+	}
+	return values
+}
@@ -0,0 +1,162 @@
+package prediction
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 活動開始時刻の時間軸の扱い（どのモデルとも組み合わせられる）
+const (
+	TimeModelLinear   = "linear"   // 0:00〜23:59 を直線として扱う
+	TimeModelCircular = "circular" // 時刻を円周上で扱う（深夜0時をまたぐ活動向け）
+)
+
+// minutesPerDay 1日の分数（円周の長さ）
+const minutesPerDay = 24 * 60
+
+// wrapTerms 円周上に巻き込む際に足し合わせる周期の範囲（-wrapTerms〜+wrapTerms 周）
+const wrapTerms = 2
+
+// IsTimeModel は活動開始時刻の時間軸の扱いとして有効かを判定する
+func IsTimeModel(timeModel string) bool {
+	return timeModel == TimeModelLinear || timeModel == TimeModelCircular
+}
+
+// mod1440 は分を 0〜1439 の範囲に丸める
+func mod1440(minutes float64) float64 {
+	m := math.Mod(minutes, minutesPerDay)
+	if m < 0 {
+		m += minutesPerDay
+	}
+	return m
+}
+
+// circularOrigin は観測時刻の間隔が最も空いている区間の中央を、円周を切り開く時刻として返す
+// この時刻で切り開けば、23:50 と 00:10 のように0時をまたぐ観測は隣り合う
+func circularOrigin(minutes []int) int {
+	if len(minutes) == 0 {
+		return 0
+	}
+	times := make([]int, len(minutes))
+	for i, m := range minutes {
+		times[i] = int(mod1440(float64(m)))
+	}
+	sort.Ints(times)
+
+	// 最後の観測から翌日の最初の観測までの間隔
+	bestStart := times[len(times)-1]
+	bestGap := times[0] + minutesPerDay - bestStart
+	for i := 1; i < len(times); i++ {
+		if gap := times[i] - times[i-1]; gap > bestGap {
+			bestStart, bestGap = times[i-1], gap
+		}
+	}
+	return int(mod1440(float64(bestStart + bestGap/2)))
+}
+
+// CircularModel は時刻を円周上で扱う活動開始時刻のモデルを表す
+// 観測の間隔が最も空いている時刻（Origin）で円周を切り開いて Inner を当てはめ、
+// Inner の分布を円周上に巻き込む（正規分布なら巻き込み正規分布となる）
+// Origin はログが増えるたびに動くため、CDF は Origin ではなく固定の日の区切り（0:00）から数える
+type CircularModel struct {
+	Inner  Model
+	Origin float64 // 円周を切り開いた時刻（0時からの分）
+
+	fixedOrigin bool // Origin を観測から求め直さない（ブートストラップで元の当てはめと揃えるため）
+}
+
+// Fit は観測時刻を Origin からの経過分に直して Inner を当てはめる
+func (m *CircularModel) Fit(observations []Observation, totalWeight float64) {
+	origin := int(m.Origin)
+	if !m.fixedOrigin {
+		minutes := make([]int, len(observations))
+		for i, o := range observations {
+			minutes[i] = o.Minutes
+		}
+		origin = circularOrigin(minutes)
+	}
+
+	shifted := make([]Observation, len(observations))
+	for i, o := range observations {
+		shifted[i] = Observation{Minutes: int(mod1440(float64(o.Minutes - origin))), Weight: o.Weight}
+	}
+	m.Origin = float64(origin)
+	m.Inner.Fit(shifted, totalWeight)
+}
+
+// CDF は 0:00 から timeMinutes までに活動が始まる確率を返す
+// 23:50 と 00:10 に始まる活動では、00:10 の分のみが当日の 0:00 以降に含まれる
+func (m *CircularModel) CDF(timeMinutes float64) float64 {
+	if timeMinutes <= 0 {
+		return 0
+	}
+	return m.Probability(0, timeMinutes)
+}
+
+// originCDF は Origin から timeMinutes までの円周上の区間に活動が始まる確率を返す
+func (m *CircularModel) originCDF(timeMinutes float64) float64 {
+	d := mod1440(timeMinutes - m.Origin)
+	total := 0.0
+	for k := -wrapTerms; k <= wrapTerms; k++ {
+		offset := float64(k * minutesPerDay)
+		total += m.Inner.CDF(d+offset) - m.Inner.CDF(offset)
+	}
+	return total
+}
+
+// Density は timeMinutes における1分あたりの確率密度を返す
+func (m *CircularModel) Density(timeMinutes float64) float64 {
+	d := mod1440(timeMinutes - m.Origin)
+	total := 0.0
+	for k := -wrapTerms; k <= wrapTerms; k++ {
+		total += m.Inner.Density(d + float64(k*minutesPerDay))
+	}
+	return total
+}
+
+// Mode は Inner の最頻時刻を元の時刻に戻して返す
+func (m *CircularModel) Mode() (float64, bool) {
+	mode, ok := m.Inner.Mode()
+	if !ok {
+		return 0, false
+	}
+	return mod1440(mode + m.Origin), true
+}
+
+// Probability は from〜to の間に活動が始まる確率を返す。23:30〜00:30 のように0時をまたぐ区間も求まる
+func (m *CircularModel) Probability(from, to float64) float64 {
+	total := m.Inner.CDF(math.Inf(1)) - m.Inner.CDF(math.Inf(-1))
+	if to-from >= minutesPerDay {
+		return total
+	}
+	start, end := m.originCDF(from), m.originCDF(to)
+	if mod1440(to-m.Origin) < mod1440(from-m.Origin) {
+		// 区間が Origin をまたぐ
+		return total - start + end
+	}
+	return end - start
+}
+
+// Details は Inner の概要を、各成分の平均を元の時刻に戻して返す
+func (m *CircularModel) Details() ModelDetails {
+	details := m.Inner.Details()
+	details.TimeModel = TimeModelCircular
+	origin := m.Origin
+	details.Origin = &origin
+	for i := range details.Components {
+		details.Components[i].Mean = mod1440(details.Components[i].Mean + m.Origin)
+	}
+	return details
+}
+
+// validateTimeModel は活動開始時刻のモデルが有効かを確認する。空の場合は linear とする
+func validateTimeModel(timeModel string) (string, error) {
+	if timeModel == "" {
+		return TimeModelLinear, nil
+	}
+	if !IsTimeModel(timeModel) {
+		return "", fmt.Errorf("unknown time model: %s", timeModel)
+	}
+	return timeModel, nil
+}
@@ -0,0 +1,139 @@
+package prediction
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCircularOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes []int
+		want    int
+	}{
+		{name: "no observations", minutes: nil, want: 0},
+		{name: "single observation", minutes: []int{600}, want: 1320},
+		{name: "daytime observations", minutes: []int{600, 660}, want: 1350},
+		{name: "observations across midnight", minutes: []int{1430, 10}, want: 720},
+		{name: "input order does not matter", minutes: []int{10, 1430, 1400, 40}, want: 720},
+		{name: "largest gap inside the day", minutes: []int{60, 120, 1200, 1260}, want: 660},
+		{name: "minutes outside the day are wrapped", minutes: []int{-10, 1450}, want: 720},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := circularOrigin(tt.minutes); got != tt.want {
+				t.Errorf("circularOrigin(%v) = %d, want %d", tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircularModelAcrossMidnight(t *testing.T) {
+	// 2週とも 23:30 と 00:30 に1回ずつ活動が始まった
+	observations := observationsAt(1410, 30, 1410, 30)
+
+	for _, kind := range []string{ModelGMM, ModelKDE, ModelHistogram} {
+		t.Run(kind, func(t *testing.T) {
+			m, err := NewModel(ModelSpec{Kind: kind, TimeModel: TimeModelCircular})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Fit(observations, 2)
+			c := m.(*CircularModel)
+			if c.Origin != 720 {
+				t.Errorf("Origin = %v, want 720", c.Origin)
+			}
+
+			assertNoNaN(t, m)
+			// CDF は 0:00 から数え、24:00 で1日あたりの活動回数（4回 / 2週 = 2）となる
+			assertValidCDF(t, m, 0, 2)
+			tolerance := 0.01
+			if kind == ModelHistogram {
+				tolerance = modelTolerance
+			}
+			tests := []struct {
+				name     string
+				from, to float64
+				want     float64
+			}{
+				{name: "22:00-02:00", from: 1320, to: 120, want: 2},
+				{name: "22:00-24:00", from: 1320, to: 1440, want: 1},
+				{name: "00:00-02:00", from: 0, to: 120, want: 1},
+				{name: "02:00-22:00", from: 120, to: 1320, want: 0},
+				{name: "whole day", from: 720, to: 720 + minutesPerDay, want: 2},
+				{name: "previous day 22:00 to 02:00", from: -120, to: 120, want: 2},
+			}
+			for _, tt := range tests {
+				if got := m.Probability(tt.from, tt.to); math.Abs(got-tt.want) > tolerance {
+					t.Errorf("Probability(%s) = %v, want %v", tt.name, got, tt.want)
+				}
+			}
+			if got := m.CDF(120); math.Abs(got-1) > tolerance {
+				t.Errorf("CDF(02:00) = %v, want 1", got)
+			}
+			if got := m.CDF(1320); math.Abs(got-1) > tolerance {
+				t.Errorf("CDF(22:00) = %v, want 1", got)
+			}
+			// 0時をまたぐ区間の確率は、0時の前後の区間の和になる
+			for _, r := range [][2]float64{{1380, 60}, {1320, 120}, {1439, 1}} {
+				got := m.Probability(r[0], r[1])
+				want := m.Probability(r[0], minutesPerDay) + m.Probability(0, r[1])
+				if math.Abs(got-want) > modelTolerance {
+					t.Errorf("Probability(%v, %v) = %v, want %v", r[0], r[1], got, want)
+				}
+			}
+
+			mode, ok := m.Mode()
+			if !ok || mode < 0 || mode >= minutesPerDay {
+				t.Fatalf("Mode() = %v, %v, want within [0, 1440)", mode, ok)
+			}
+			// 最頻時刻は 23:30 か 00:30 の近く
+			if d := math.Min(math.Abs(mode-1410), math.Abs(mode-30)); d > 30 {
+				t.Errorf("Mode() = %v, want near 23:30 or 00:30", mode)
+			}
+		})
+	}
+}
+
+func TestCircularModelModeWrapsIntoDay(t *testing.T) {
+	// Origin を足すと 1440 を超える最頻時刻も、0時からの分に戻す
+	m := &CircularModel{Inner: &Histogram{}}
+	m.Fit(observationsAt(600), 1)
+	if m.Origin != 1320 {
+		t.Fatalf("Origin = %v, want 1320", m.Origin)
+	}
+	if got, ok := m.Mode(); !ok || got != 615 {
+		t.Errorf("Mode() = %v, %v, want 615", got, ok)
+	}
+	details := m.Details()
+	if details.TimeModel != TimeModelCircular || details.Origin == nil || *details.Origin != 1320 {
+		t.Errorf("Details() = %+v", details)
+	}
+	if len(details.Components) != 1 || details.Components[0].Mean != 615 {
+		t.Errorf("Details().Components = %+v, want mean 615", details.Components)
+	}
+
+	empty := &CircularModel{Inner: &Histogram{}}
+	empty.Fit(nil, 1)
+	if _, ok := empty.Mode(); ok {
+		t.Error("Mode() ok = true without observations")
+	}
+	assertNoNaN(t, empty)
+}
+
+func TestCircularModelFixedOrigin(t *testing.T) {
+	m := &CircularModel{Inner: &Histogram{}, Origin: 300, fixedOrigin: true}
+	m.Fit(observationsAt(1410, 30), 2)
+	if m.Origin != 300 {
+		t.Errorf("Origin = %v, want the pinned 300", m.Origin)
+	}
+	// どこで切り開いても 0:00 から数えた確率は変わらない
+	free := &CircularModel{Inner: &Histogram{}}
+	free.Fit(observationsAt(1410, 30), 2)
+	for _, minute := range []float64{0, 30, 45, 1380, 1425, 1440} {
+		if got, want := m.CDF(minute), free.CDF(minute); math.Abs(got-want) > modelTolerance {
+			t.Errorf("CDF(%v) = %v with origin 300, want %v", minute, got, want)
+		}
+	}
+}
@@ -2,8 +2,11 @@
 package prediction
 
 import (
+	"encoding/binary"
+	"hash/fnv"
 	"math"
 	"math/rand"
+	"sort"
 
 	"gonum.org/v1/gonum/stat"
 	"gonum.org/v1/gonum/stat/distuv"
@@ -23,6 +26,9 @@ type GaussianMixture struct {
 	Weights     []float64
 	MaxIter     int
 	Tolerance   float64
+	// Rand はK-means++の初期化に使う乱数生成器。nil の場合は学習データから決めたシードを使うため、
+	// 同じデータからは常に同じ結果になる
+	Rand *rand.Rand
 }
 
 // NewGaussianMixture 新しいGMMを作成する
@@ -34,11 +40,34 @@ func NewGaussianMixture(nComponents int) *GaussianMixture {
 	}
 }
 
+// NewGaussianMixtureWithRand 初期化に rng を使う新しいGMMを作成する
+func NewGaussianMixtureWithRand(nComponents int, rng *rand.Rand) *GaussianMixture {
+	gmm := NewGaussianMixture(nComponents)
+	gmm.Rand = rng
+	return gmm
+}
+
+// DataSeed はデータから乱数のシードを求める。並べ替えてから求めるため、同じデータからは順序によらず同じシードになる
+func DataSeed(data []float64) int64 {
+	sorted := append([]float64{}, data...)
+	sort.Float64s(sorted)
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, x := range sorted {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(x))
+		h.Write(buf[:])
+	}
+	return int64(h.Sum64())
+}
+
 // Fit EMアルゴリズムを使用してGMMを学習する
 func (gmm *GaussianMixture) Fit(data []float64) {
 	n := len(data)
 	k := gmm.NComponents
 
+	if gmm.Rand == nil {
+		gmm.Rand = rand.New(rand.NewSource(DataSeed(data)))
+	}
 	gmm.initializeKMeansPlusPlus(data)
 	gmm.initializeVariancesAndWeights(data)
 
@@ -139,11 +168,11 @@ func (gmm *GaussianMixture) initializeKMeansPlusPlus(data []float64) {
 		return
 	}
 
-	gmm.Means[0] = data[rand.Intn(n)]
+	gmm.Means[0] = data[gmm.Rand.Intn(n)]
 
 	for i := 1; i < k; i++ {
 		distances, totalDist := calcMinDistances(data, gmm.Means[:i])
-		gmm.Means[i] = selectByDistance(data, distances, totalDist)
+		gmm.Means[i] = selectByDistance(gmm.Rand, data, distances, totalDist)
 	}
 }
 
@@ -166,11 +195,11 @@ func calcMinDistances(data []float64, centers []float64) ([]float64, float64) {
 }
 
 // selectByDistance 距離の二乗に比例した確率でデータポイントを選択する
-func selectByDistance(data []float64, distances []float64, totalDist float64) float64 {
+func selectByDistance(rng *rand.Rand, data []float64, distances []float64, totalDist float64) float64 {
 	if totalDist <= 0 {
-		return data[rand.Intn(len(data))]
+		return data[rng.Intn(len(data))]
 	}
-	r := rand.Float64() * totalDist
+	r := rng.Float64() * totalDist
 	var cumSum float64
 	for j, d := range distances {
 		cumSum += d
@@ -237,25 +266,66 @@ func (gmm *GaussianMixture) BIC(data []float64) float64 {
 const nRestarts = 10
 
 // Clustering データをクラスタリングする（元のPython実装と同等）
+// データを並べ替え、乱数のシードもデータから決めるため、同じデータからは順序によらず常に同じ結果になる
 func Clustering(data []int) []ClusteringResult {
-	// intをfloat64に変換
+	results, _ := clusteringWithBIC(data)
+	return results
+}
+
+// clusteringWithBIC はデータから決めたシードでクラスタリングし、選ばれたクラスタ数のBICとともに返す
+func clusteringWithBIC(data []int) ([]ClusteringResult, float64) {
+	floatData := sortedFloat64s(data)
+	return clustering(floatData, rand.New(rand.NewSource(DataSeed(floatData))))
+}
+
+// ClusteringWithRand 初期化に rng を使ってデータをクラスタリングする
+func ClusteringWithRand(data []int, rng *rand.Rand) []ClusteringResult {
+	results, _ := clustering(sortedFloat64s(data), rng)
+	return results
+}
+
+// sortedFloat64s intをfloat64に変換して昇順に並べ替える
+// K-means++の初期値はデータの並び順に依存するため、クラスタリングの前に並べ替える
+func sortedFloat64s(data []int) []float64 {
 	floatData := make([]float64, len(data))
 	for i, v := range data {
 		floatData[i] = float64(v)
 	}
+	sort.Float64s(floatData)
+	return floatData
+}
+
+// maxClusters はBICで選ぶクラスタ数の上限
+const maxClusters = 4
 
-	// BICを使用して最適なクラスタ数を見つける
-	maxClusters := 4
+// clustering BICを使用して最適なクラスタ数を選び、データをクラスタリングする。選ばれたGMMのBICも返す
+// 試行ごとに rng から異なる初期値を取り出す
+func clustering(floatData []float64, rng *rand.Rand) ([]ClusteringResult, float64) {
+	return clusteringRange(floatData, rng, 1, maxClusters, nRestarts)
+}
+
+// clusteringFixed はクラスタ数を nClusters に固定し、restarts 回の試行でデータをクラスタリングする
+// ブートストラップのように同じデータを何度も当てはめ直す場合に、計算量を抑えるために使う
+func clusteringFixed(data []int, nClusters, restarts int) ([]ClusteringResult, float64) {
+	floatData := sortedFloat64s(data)
+	return clusteringRange(floatData, rand.New(rand.NewSource(DataSeed(floatData))), nClusters, nClusters, restarts)
+}
+
+// clusteringRange はクラスタ数 minClusters〜maxClusters（データ数が上限）について restarts 回ずつ試行し、BIC最良の結果を返す
+func clusteringRange(floatData []float64, rng *rand.Rand, minClusters, maxClusters, restarts int) ([]ClusteringResult, float64) {
 	if len(floatData) < maxClusters {
 		maxClusters = len(floatData)
 	}
+	if minClusters > maxClusters {
+		minClusters = maxClusters
+	}
 
 	var bestGMM *GaussianMixture
 	bestBIC := math.Inf(1)
 
-	for nClusters := 1; nClusters <= maxClusters; nClusters++ {
-		for trial := 0; trial < nRestarts; trial++ {
-			gmm := NewGaussianMixture(nClusters)
+	for nClusters := minClusters; nClusters <= maxClusters; nClusters++ {
+		for trial := 0; trial < restarts; trial++ {
+			gmm := NewGaussianMixtureWithRand(nClusters, rng)
 			gmm.Fit(floatData)
 			bic := gmm.BIC(floatData)
 			if bic < bestBIC {
@@ -265,7 +335,7 @@ func Clustering(data []int) []ClusteringResult {
 		}
 	}
 
-	return makeResultsList(floatData, bestGMM)
+	return makeResultsList(floatData, bestGMM), bestBIC
 }
 
 // makeResultsList クラスタリング結果をリストに変換する
@@ -0,0 +1,56 @@
+package prediction
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusteringIndependentOfInputOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []int
+		reversed []int
+	}{
+		{
+			name:     "two clusters",
+			data:     []int{600, 605, 610, 615, 1080, 1085, 1090, 1095, 1100},
+			reversed: []int{1100, 1095, 1090, 1085, 1080, 615, 610, 605, 600},
+		},
+		{
+			name:     "shuffled with outlier",
+			data:     []int{1020, 1300, 1030, 1015, 1025, 1040, 1010},
+			reversed: []int{1040, 1010, 1025, 1300, 1015, 1030, 1020},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if DataSeed(toFloat64s(tt.data)) != DataSeed(toFloat64s(tt.reversed)) {
+				t.Errorf("DataSeed differs between input orders")
+			}
+			got, gotBIC := clusteringWithBIC(tt.data)
+			want, wantBIC := clusteringWithBIC(tt.reversed)
+			if !reflect.DeepEqual(got, want) || gotBIC != wantBIC {
+				t.Errorf("Clustering differs between input orders: %v (BIC %v) vs %v (BIC %v)", got, gotBIC, want, wantBIC)
+			}
+		})
+	}
+}
+
+func TestClusteringDeterministic(t *testing.T) {
+	data := []int{600, 605, 610, 615, 1080, 1085, 1090, 1095, 1100}
+	first := Clustering(data)
+	for i := 0; i < 5; i++ {
+		if got := Clustering(data); !reflect.DeepEqual(got, first) {
+			t.Fatalf("Clustering returned %v, want %v", got, first)
+		}
+	}
+}
+
+func toFloat64s(data []int) []float64 {
+	floatData := make([]float64, len(data))
+	for i, v := range data {
+		floatData[i] = float64(v)
+	}
+	return floatData
+}
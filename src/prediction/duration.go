@@ -0,0 +1,48 @@
+package prediction
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DurationPrediction は活動時間（分）の分布から求めた予測値を表す
+type DurationPrediction struct {
+	Median  float64 // 活動時間の中央値
+	Lower   float64 // 信頼区間の下限（lowerQuantile 分位点）
+	Upper   float64 // 信頼区間の上限（upperQuantile 分位点）
+	Samples int     // 予測に使用した活動の数
+}
+
+// PredictDuration は過去の活動時間（分）の経験分布から、中央値と分位点による区間を求める
+// 活動時間は外れ値の影響が大きく正規分布に従わないため、平均ではなく分位点を使う
+func PredictDuration(durations []float64, lowerQuantile, upperQuantile float64) (DurationPrediction, error) {
+	if len(durations) == 0 {
+		return DurationPrediction{}, fmt.Errorf("no data provided")
+	}
+	if lowerQuantile < 0 || upperQuantile > 1 || lowerQuantile > upperQuantile {
+		return DurationPrediction{}, fmt.Errorf("invalid quantiles: %v, %v", lowerQuantile, upperQuantile)
+	}
+
+	sorted := make([]float64, len(durations))
+	copy(sorted, durations)
+	sort.Float64s(sorted)
+
+	return DurationPrediction{
+		Median:  sortedQuantile(sorted, 0.5),
+		Lower:   sortedQuantile(sorted, lowerQuantile),
+		Upper:   sortedQuantile(sorted, upperQuantile),
+		Samples: len(sorted),
+	}, nil
+}
+
+// sortedQuantile は昇順に並んだ sorted の p 分位点を、隣り合う値の線形補間で求める
+// stat.Quantile の LinInterp は観測数が奇数のとき p=0.5 でも中央値にならないため使わない
+func sortedQuantile(sorted []float64, p float64) float64 {
+	h := p * float64(len(sorted)-1)
+	i := int(math.Floor(h))
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (h-float64(i))*(sorted[i+1]-sorted[i])
+}
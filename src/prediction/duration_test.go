@@ -0,0 +1,93 @@
+package prediction
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPredictDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []float64
+		lower     float64
+		upper     float64
+		want      DurationPrediction
+	}{
+		{
+			name:      "single session",
+			durations: []float64{45},
+			lower:     0.1, upper: 0.9,
+			want: DurationPrediction{Median: 45, Lower: 45, Upper: 45, Samples: 1},
+		},
+		{
+			name:      "three sessions",
+			durations: []float64{90, 30, 60},
+			lower:     0.1, upper: 0.9,
+			want: DurationPrediction{Median: 60, Lower: 36, Upper: 84, Samples: 3},
+		},
+		{
+			name:      "odd count median is the middle value",
+			durations: []float64{10, 20, 30, 40, 50},
+			lower:     0.1, upper: 0.9,
+			want: DurationPrediction{Median: 30, Lower: 14, Upper: 46, Samples: 5},
+		},
+		{
+			name:      "even count median is the mean of the middle values",
+			durations: []float64{40, 10, 30, 20},
+			lower:     0.1, upper: 0.9,
+			want: DurationPrediction{Median: 25, Lower: 13, Upper: 37, Samples: 4},
+		},
+		{
+			name:      "outlier does not move the median",
+			durations: []float64{60, 60, 65, 70, 600},
+			lower:     0.25, upper: 0.75,
+			want: DurationPrediction{Median: 65, Lower: 60, Upper: 70, Samples: 5},
+		},
+		{
+			name:      "extreme quantiles are the minimum and maximum",
+			durations: []float64{30, 60, 90},
+			lower:     0, upper: 1,
+			want: DurationPrediction{Median: 60, Lower: 30, Upper: 90, Samples: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]float64{}, tt.durations...)
+			got, err := PredictDuration(tt.durations, tt.lower, tt.upper)
+			if err != nil {
+				t.Fatalf("PredictDuration() error = %v", err)
+			}
+			if math.Abs(got.Median-tt.want.Median) > 1e-9 || math.Abs(got.Lower-tt.want.Lower) > 1e-9 ||
+				math.Abs(got.Upper-tt.want.Upper) > 1e-9 || got.Samples != tt.want.Samples {
+				t.Errorf("PredictDuration() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.durations, input) {
+				t.Errorf("PredictDuration() modified its input: %v", tt.durations)
+			}
+		})
+	}
+}
+
+func TestPredictDurationInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []float64
+		lower     float64
+		upper     float64
+	}{
+		{name: "no sessions", durations: nil, lower: 0.1, upper: 0.9},
+		{name: "negative lower quantile", durations: []float64{60}, lower: -0.1, upper: 0.9},
+		{name: "upper quantile above 1", durations: []float64{60}, lower: 0.1, upper: 1.1},
+		{name: "lower above upper", durations: []float64{60}, lower: 0.9, upper: 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PredictDuration(tt.durations, tt.lower, tt.upper); err == nil {
+				t.Error("PredictDuration() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package prediction
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// 過去のログの重み付け方式
+const (
+	EstimatorUniform = "uniform" // すべての週を等しく扱う
+	EstimatorDecay   = "decay"   // 経過週数に応じて指数的に重みを減らす
+	EstimatorWindow  = "window"  // 直近の一定週数のみを使う
+)
+
+// Estimator は活動確率の推定で、過去の観測をどの程度重視するかを表す
+// 研究室の習慣は学期ごとに変わるため、古いログの影響を減らすために使う
+type Estimator struct {
+	Mode          string    `json:"mode"`                      // uniform / decay / window
+	HalfLifeWeeks float64   `json:"half_life_weeks,omitempty"` // decay: 重みが半分になる経過週数
+	WindowWeeks   int       `json:"window_weeks,omitempty"`    // window: 対象とする直近の週数
+	Now           time.Time `json:"-"`                         // 経過週数を数える基準時刻
+}
+
+// Validate は設定値が有効かを確認する
+func (e Estimator) Validate() error {
+	switch e.Mode {
+	case EstimatorUniform:
+	case EstimatorDecay:
+		if e.HalfLifeWeeks <= 0 {
+			return fmt.Errorf("half-life must be positive: %v", e.HalfLifeWeeks)
+		}
+	case EstimatorWindow:
+		if e.WindowWeeks <= 0 {
+			return fmt.Errorf("window must be positive: %d", e.WindowWeeks)
+		}
+	default:
+		return fmt.Errorf("unknown estimator mode: %s", e.Mode)
+	}
+	return nil
+}
+
+// ageWeeks は t から Now までの経過週数（今週を 0 とする）を返す
+func (e Estimator) ageWeeks(t time.Time) int {
+	return ageWeeksAt(t, e.Now)
+}
+
+// ageWeeksAt は t から now までの経過週数（今週を 0 とする）を返す
+func ageWeeksAt(t, now time.Time) int {
+	days := int(now.Sub(t).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days / 7
+}
+
+// WeekAges は now までの直近 weeks 週の観測機会を、経過週数（今週を 0 とする）の昇順で返す
+// excluded の日（学事暦で除外した日など）を含む週は観測機会がないものとして除く
+func WeekAges(now time.Time, weeks int, excluded []time.Time) []int {
+	skip := make(map[int]bool, len(excluded))
+	for _, d := range excluded {
+		skip[ageWeeksAt(d, now)] = true
+	}
+	ages := make([]int, 0, weeks)
+	for age := 0; age < weeks; age++ {
+		if !skip[age] {
+			ages = append(ages, age)
+		}
+	}
+	return ages
+}
+
+// weightAt は経過週数 age の観測の重みを返す
+func (e Estimator) weightAt(age int) float64 {
+	switch e.Mode {
+	case EstimatorDecay:
+		return math.Pow(0.5, float64(age)/e.HalfLifeWeeks)
+	case EstimatorWindow:
+		if age < e.WindowWeeks {
+			return 1
+		}
+		return 0
+	default:
+		return 1
+	}
+}
+
+// totalWeight は経過週数 ages の観測機会の重みの合計を返す（確率の分母）
+// uniform では週数と等しく、毎週同じ時刻に活動していれば確率は 1 となる
+func (e Estimator) totalWeight(ages []int) float64 {
+	total := 0.0
+	for _, age := range ages {
+		total += e.weightAt(age)
+	}
+	return total
+}
+
+// Observation は重み付きの観測時刻（0時からの分）を表す
+type Observation struct {
+	Minutes int     `json:"minutes"`
+	Weight  float64 `json:"weight"`
+
+	age int // 経過週数（ブートストラップで観測機会ごとにまとめるために使う）
+}
+
+// parseObservations は "2006-01-02 15:04" 形式の日時から重み付きの観測時刻を作る
+// uniqueDate の場合は日付ごとに最初の時刻のみを使う。重みが 0 の観測は含めない
+func parseObservations(data []string, estimator Estimator, uniqueDate bool) ([]Observation, error) {
+	seen := make(map[string]bool)
+	observations := make([]Observation, 0, len(data))
+	for _, d := range data {
+		t, err := time.ParseInLocation("2006-01-02 15:04", d, estimator.Now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid datetime format: %s", d)
+		}
+		date := d[:len("2006-01-02")]
+		if uniqueDate {
+			if seen[date] {
+				continue
+			}
+			seen[date] = true
+		}
+		// 経過週数は日付単位で数える
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		age := estimator.ageWeeks(day)
+		weight := estimator.weightAt(age)
+		if weight <= 0 {
+			continue
+		}
+		observations = append(observations, Observation{Minutes: t.Hour()*60 + t.Minute(), Weight: weight, age: age})
+	}
+	return observations, nil
+}
+
+// weightedMeanStdDev は重み付きの平均と標準偏差を返す
+// 標準偏差は重みを正規化した上で不偏分散（n-1 で割る）に合わせて補正するため、重みがすべて等しい場合は stat.StdDev と一致する
+func weightedMeanStdDev(data, weights []float64) (float64, float64) {
+	var sumW, sumWX float64
+	for i, x := range data {
+		sumW += weights[i]
+		sumWX += weights[i] * x
+	}
+	if sumW <= 0 {
+		return 0, 0
+	}
+	mean := sumWX / sumW
+
+	n := float64(len(data))
+	if n < 2 {
+		return mean, 0
+	}
+	var sumWD2 float64
+	for i, x := range data {
+		d := x - mean
+		sumWD2 += weights[i] * d * d
+	}
+	return mean, math.Sqrt(sumWD2 / sumW * n / (n - 1))
+}
@@ -0,0 +1,153 @@
+package prediction
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+)
+
+func TestEstimatorValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimator Estimator
+		wantErr   bool
+	}{
+		{name: "uniform", estimator: Estimator{Mode: EstimatorUniform}},
+		{name: "uniform ignores other fields", estimator: Estimator{Mode: EstimatorUniform, HalfLifeWeeks: -1, WindowWeeks: -1}},
+		{name: "decay", estimator: Estimator{Mode: EstimatorDecay, HalfLifeWeeks: 8}},
+		{name: "decay with fractional half-life", estimator: Estimator{Mode: EstimatorDecay, HalfLifeWeeks: 0.5}},
+		{name: "decay without half-life", estimator: Estimator{Mode: EstimatorDecay}, wantErr: true},
+		{name: "decay with negative half-life", estimator: Estimator{Mode: EstimatorDecay, HalfLifeWeeks: -2}, wantErr: true},
+		{name: "window", estimator: Estimator{Mode: EstimatorWindow, WindowWeeks: 4}},
+		{name: "window without weeks", estimator: Estimator{Mode: EstimatorWindow}, wantErr: true},
+		{name: "window with negative weeks", estimator: Estimator{Mode: EstimatorWindow, WindowWeeks: -1}, wantErr: true},
+		{name: "empty mode", estimator: Estimator{}, wantErr: true},
+		{name: "unknown mode", estimator: Estimator{Mode: "linear"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.estimator.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEstimatorWeights(t *testing.T) {
+	uniform := Estimator{Mode: EstimatorUniform}
+	decay := Estimator{Mode: EstimatorDecay, HalfLifeWeeks: 2}
+	window := Estimator{Mode: EstimatorWindow, WindowWeeks: 3}
+
+	tests := []struct {
+		name        string
+		estimator   Estimator
+		ages        []int
+		wantWeights []float64
+		wantTotal   float64
+	}{
+		{name: "uniform", estimator: uniform, ages: []int{0, 1, 2, 3}, wantWeights: []float64{1, 1, 1, 1}, wantTotal: 4},
+		{name: "uniform without weeks", estimator: uniform, ages: nil, wantTotal: 0},
+		// 2週ごとに重みが半分になる
+		{name: "decay", estimator: decay, ages: []int{0, 1, 2, 4}, wantWeights: []float64{1, math.Sqrt(0.5), 0.5, 0.25}, wantTotal: 1.75 + math.Sqrt(0.5)},
+		{name: "window", estimator: window, ages: []int{0, 1, 2, 3, 4}, wantWeights: []float64{1, 1, 1, 0, 0}, wantTotal: 3},
+		// 学事暦で除外した週（経過週数 1）は分母に含めない
+		{name: "uniform with an excluded week", estimator: uniform, ages: []int{0, 2, 3}, wantWeights: []float64{1, 1, 1}, wantTotal: 3},
+		{name: "decay with an excluded week", estimator: decay, ages: []int{1, 2}, wantWeights: []float64{math.Sqrt(0.5), 0.5}, wantTotal: 0.5 + math.Sqrt(0.5)},
+		{name: "window with an excluded week", estimator: window, ages: []int{0, 2, 3}, wantWeights: []float64{1, 1, 0}, wantTotal: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, age := range tt.ages {
+				if got := tt.estimator.weightAt(age); math.Abs(got-tt.wantWeights[i]) > modelTolerance {
+					t.Errorf("weightAt(%d) = %v, want %v", age, got, tt.wantWeights[i])
+				}
+			}
+			if got := tt.estimator.totalWeight(tt.ages); math.Abs(got-tt.wantTotal) > modelTolerance {
+				t.Errorf("totalWeight(%v) = %v, want %v", tt.ages, got, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestEstimatorAgeWeeks(t *testing.T) {
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+	e := Estimator{Now: now}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want int
+	}{
+		{name: "today", t: time.Date(2025, 6, 13, 0, 0, 0, 0, lib.JST), want: 0},
+		{name: "six days ago", t: time.Date(2025, 6, 7, 0, 0, 0, 0, lib.JST), want: 0},
+		{name: "a week ago", t: time.Date(2025, 6, 6, 0, 0, 0, 0, lib.JST), want: 1},
+		{name: "three weeks ago", t: time.Date(2025, 5, 23, 0, 0, 0, 0, lib.JST), want: 3},
+		{name: "future", t: time.Date(2025, 6, 20, 0, 0, 0, 0, lib.JST), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.ageWeeks(tt.t); got != tt.want {
+				t.Errorf("ageWeeks(%s) = %d, want %d", tt.t.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeekAges(t *testing.T) {
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+	weeksAgo := func(w int) time.Time {
+		return time.Date(2025, 6, 13-7*w, 0, 0, 0, 0, lib.JST)
+	}
+
+	tests := []struct {
+		name     string
+		weeks    int
+		excluded []time.Time
+		want     []int
+	}{
+		{name: "no weeks", weeks: 0, want: []int{}},
+		{name: "no excluded days", weeks: 4, want: []int{0, 1, 2, 3}},
+		{name: "this week excluded", weeks: 4, excluded: []time.Time{weeksAgo(0)}, want: []int{1, 2, 3}},
+		{name: "middle weeks excluded", weeks: 5, excluded: []time.Time{weeksAgo(3), weeksAgo(1)}, want: []int{0, 2, 4}},
+		{name: "excluded day outside the weeks", weeks: 2, excluded: []time.Time{weeksAgo(5)}, want: []int{0, 1}},
+		{name: "all weeks excluded", weeks: 2, excluded: []time.Time{weeksAgo(0), weeksAgo(1)}, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeekAges(now, tt.weeks, tt.excluded); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WeekAges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitDatetimesExcludedWeeks(t *testing.T) {
+	now := time.Date(2025, 6, 13, 21, 0, 0, 0, lib.JST)
+	// 4週のうち1週前は休日で、残りの週は毎週 10:00 に活動した
+	data := weeklyDatetimes(now, []int{0, 2, 3}, []string{"10:00", "10:00", "10:00"})
+	ages := WeekAges(now, 4, []time.Time{now.AddDate(0, 0, -7)})
+
+	for _, estimator := range []Estimator{
+		{Mode: EstimatorUniform, Now: now},
+		{Mode: EstimatorDecay, HalfLifeWeeks: 1, Now: now},
+		{Mode: EstimatorWindow, WindowWeeks: 3, Now: now},
+	} {
+		t.Run(estimator.Mode, func(t *testing.T) {
+			m, err := FitDatetimes(data, ages, estimator, true, ModelSpec{Kind: ModelHistogram})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 観測できた週にはすべて活動したため、どの重み付けでも確率は 1 となる
+			if got := m.CDF(minutesPerDay); math.Abs(got-1) > modelTolerance {
+				t.Errorf("CDF(24:00) = %v, want 1", got)
+			}
+		})
+	}
+}
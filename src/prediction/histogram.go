@@ -0,0 +1,98 @@
+package prediction
+
+import "math"
+
+// histogramBinMinutes ヒストグラムの1区間の長さ（分）
+const histogramBinMinutes = 30
+
+// Histogram は観測時刻を一定の時間幅で数えた経験分布（Model のヒストグラム実装）を表す
+// 分布の形を仮定しないため、他のモデルとの比較の基準に使う。区間内では一様に活動が始まるものとする
+type Histogram struct {
+	BinMinutes int       `json:"bin_minutes"`
+	Bins       []float64 `json:"bins"` // 0時から順に、各区間の重みを確率の分母で割ったもの
+
+	samples int
+}
+
+// Fit は観測時刻を区間ごとに数える
+func (h *Histogram) Fit(observations []Observation, totalWeight float64) {
+	h.BinMinutes = histogramBinMinutes
+	h.Bins = make([]float64, minutesPerDay/histogramBinMinutes)
+	h.samples = 0
+	if totalWeight <= 0 {
+		return
+	}
+	for _, o := range observations {
+		if o.Minutes < 0 || o.Minutes >= minutesPerDay {
+			continue
+		}
+		h.Bins[o.Minutes/h.BinMinutes] += o.Weight / totalWeight
+		h.samples++
+	}
+}
+
+// CDF は timeMinutes（0時からの分）までに活動が始まる確率を返す
+func (h *Histogram) CDF(timeMinutes float64) float64 {
+	total := 0.0
+	for i, w := range h.Bins {
+		start := float64(i * h.BinMinutes)
+		end := start + float64(h.BinMinutes)
+		switch {
+		case timeMinutes >= end:
+			total += w
+		case timeMinutes > start:
+			total += w * (timeMinutes - start) / float64(h.BinMinutes)
+		}
+	}
+	return total
+}
+
+// Density は timeMinutes における1分あたりの確率密度を返す
+func (h *Histogram) Density(timeMinutes float64) float64 {
+	if timeMinutes < 0 || timeMinutes >= minutesPerDay || len(h.Bins) == 0 {
+		return 0
+	}
+	return h.Bins[int(timeMinutes)/h.BinMinutes] / float64(h.BinMinutes)
+}
+
+// Mode は重みが最大の区間の中央の時刻を返す
+func (h *Histogram) Mode() (float64, bool) {
+	best := -1
+	for i, w := range h.Bins {
+		if w > 0 && (best < 0 || w > h.Bins[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return float64(best*h.BinMinutes) + float64(h.BinMinutes)/2, true
+}
+
+// Probability は from〜to の間に活動が始まる確率を返す
+func (h *Histogram) Probability(from, to float64) float64 {
+	return h.CDF(to) - h.CDF(from)
+}
+
+// Details は観測のある区間を返す。各区間は区間内の一様分布と同じ標準偏差（幅/√12）の成分として表す
+func (h *Histogram) Details() ModelDetails {
+	components := []MixtureComponent{}
+	for i, w := range h.Bins {
+		if w <= 0 {
+			continue
+		}
+		components = append(components, MixtureComponent{
+			Mean:   float64(i*h.BinMinutes) + float64(h.BinMinutes)/2,
+			StdDev: float64(h.BinMinutes) / math.Sqrt(12),
+			Weight: w,
+		})
+	}
+	return ModelDetails{
+		Kind:           ModelHistogram,
+		TimeModel:      TimeModelLinear,
+		Samples:        h.samples,
+		ComponentCount: len(components),
+		Components:     components,
+		BinMinutes:     h.BinMinutes,
+	}
+}
@@ -0,0 +1,97 @@
+package prediction
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations []Observation
+		totalWeight  float64
+		wantSamples  int
+		wantRate     float64 // CDF(1440)
+	}{
+		{name: "no observations", totalWeight: 4},
+		{name: "no observation weeks", observations: observationsAt(600), totalWeight: 0},
+		{name: "single observation", observations: observationsAt(600), totalWeight: 4, wantSamples: 1, wantRate: 0.25},
+		{name: "single bin", observations: observationsAt(600, 610, 629), totalWeight: 3, wantSamples: 3, wantRate: 1},
+		{name: "several bins", observations: observationsAt(0, 600, 1080, 1439), totalWeight: 8, wantSamples: 4, wantRate: 0.5},
+		{name: "out of range minutes are dropped", observations: observationsAt(-1, 600, 1440), totalWeight: 2, wantSamples: 1, wantRate: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Histogram{}
+			h.Fit(tt.observations, tt.totalWeight)
+			if len(h.Bins) != minutesPerDay/histogramBinMinutes {
+				t.Fatalf("len(Bins) = %d", len(h.Bins))
+			}
+			assertNoNaN(t, h)
+			assertValidCDF(t, h, 0, tt.wantRate)
+			if got := h.Probability(0, minutesPerDay); math.Abs(got-tt.wantRate) > modelTolerance {
+				t.Errorf("Probability(0, 1440) = %v, want %v", got, tt.wantRate)
+			}
+			if _, ok := h.Mode(); ok != (tt.wantSamples > 0) {
+				t.Errorf("Mode() ok = %v, want %v", ok, tt.wantSamples > 0)
+			}
+			if details := h.Details(); details.Samples != tt.wantSamples {
+				t.Errorf("Details().Samples = %d, want %d", details.Samples, tt.wantSamples)
+			}
+		})
+	}
+}
+
+func TestHistogramSingleBin(t *testing.T) {
+	h := &Histogram{}
+	h.Fit(observationsAt(600, 610, 629), 3)
+
+	// 区間（10:00〜10:30）の中では一様に活動が始まるものとする
+	tests := []struct {
+		minutes float64
+		cdf     float64
+		density float64
+	}{
+		{599, 0, 0},
+		{600, 0, 1.0 / 30},
+		{615, 0.5, 1.0 / 30},
+		{629, 29.0 / 30, 1.0 / 30},
+		{630, 1, 0},
+		{1439, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := h.CDF(tt.minutes); math.Abs(got-tt.cdf) > modelTolerance {
+			t.Errorf("CDF(%v) = %v, want %v", tt.minutes, got, tt.cdf)
+		}
+		if got := h.Density(tt.minutes); math.Abs(got-tt.density) > modelTolerance {
+			t.Errorf("Density(%v) = %v, want %v", tt.minutes, got, tt.density)
+		}
+	}
+	if got, ok := h.Mode(); !ok || got != 615 {
+		t.Errorf("Mode() = %v, %v, want 615", got, ok)
+	}
+	details := h.Details()
+	if details.ComponentCount != 1 || details.Components[0].Mean != 615 || details.Components[0].Weight != 1 {
+		t.Errorf("Details() = %+v", details)
+	}
+}
+
+func TestHistogramMode(t *testing.T) {
+	h := &Histogram{}
+	h.Fit([]Observation{{Minutes: 600, Weight: 1}, {Minutes: 1080, Weight: 0.5}, {Minutes: 1085, Weight: 0.75}}, 4)
+	if got, ok := h.Mode(); !ok || got != 1095 {
+		t.Errorf("Mode() = %v, %v, want 1095", got, ok)
+	}
+}
+
+func TestHistogramZeroValue(t *testing.T) {
+	h := &Histogram{}
+	assertNoNaN(t, h)
+	if got := h.CDF(minutesPerDay); got != 0 {
+		t.Errorf("CDF(1440) = %v, want 0", got)
+	}
+	if _, ok := h.Mode(); ok {
+		t.Error("Mode() ok = true before Fit")
+	}
+}
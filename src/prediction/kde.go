@@ -0,0 +1,115 @@
+package prediction
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// minKDEBandwidth カーネル密度推定の最小のバンド幅（分）
+// 観測が1つだけの場合や、すべて同じ時刻の場合もこの幅でなめらかにする
+const minKDEBandwidth = 10.0
+
+// KernelDensity は観測ごとに正規カーネルを置くカーネル密度推定（Model の KDE 実装）を表す
+// GMM のようにクラスタ数を選ばないため、観測が少ない場合や分布の形が複雑な場合に使う
+type KernelDensity struct {
+	Bandwidth float64       `json:"bandwidth"` // カーネルの標準偏差（分）
+	Points    []Observation `json:"points"`    // 観測時刻と、確率の分母で割った重み
+}
+
+// Fit は観測時刻を当てはめる。バンド幅は重み付きの標準偏差・四分位範囲と有効観測数から Silverman の目安で決める
+// 外れ値があっても広がりすぎないよう、標準偏差と 四分位範囲/1.34 の小さい方を使う
+func (k *KernelDensity) Fit(observations []Observation, totalWeight float64) {
+	k.Points = []Observation{}
+	k.Bandwidth = minKDEBandwidth
+	if len(observations) == 0 || totalWeight <= 0 {
+		return
+	}
+
+	data := make([]float64, len(observations))
+	weights := make([]float64, len(observations))
+	var sumW, sumW2 float64
+	for i, o := range observations {
+		data[i] = float64(o.Minutes)
+		weights[i] = o.Weight
+		sumW += o.Weight
+		sumW2 += o.Weight * o.Weight
+		k.Points = append(k.Points, Observation{Minutes: o.Minutes, Weight: o.Weight / totalWeight})
+	}
+	if len(observations) > 1 && sumW2 > 0 {
+		_, spread := weightedMeanStdDev(data, weights)
+		if iqr := weightedIQR(data, weights); iqr > 0 {
+			spread = math.Min(spread, iqr/1.34)
+		}
+		effective := sumW * sumW / sumW2
+		k.Bandwidth = math.Max(0.9*spread*math.Pow(effective, -0.2), minKDEBandwidth)
+	}
+}
+
+// weightedIQR は重み付きの四分位範囲を返す
+func weightedIQR(data, weights []float64) float64 {
+	idx := make([]int, len(data))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return data[idx[a]] < data[idx[b]] })
+	x := make([]float64, len(data))
+	w := make([]float64, len(data))
+	for i, j := range idx {
+		x[i], w[i] = data[j], weights[j]
+	}
+	return stat.Quantile(0.75, stat.Empirical, x, w) - stat.Quantile(0.25, stat.Empirical, x, w)
+}
+
+// CDF は timeMinutes（0時からの分）までに活動が始まる確率を返す
+func (k *KernelDensity) CDF(timeMinutes float64) float64 {
+	total := 0.0
+	for _, p := range k.Points {
+		total += normalCDF(timeMinutes, float64(p.Minutes), k.Bandwidth) * p.Weight
+	}
+	return total
+}
+
+// Density は timeMinutes における1分あたりの確率密度を返す
+func (k *KernelDensity) Density(timeMinutes float64) float64 {
+	total := 0.0
+	for _, p := range k.Points {
+		total += normalPDF(timeMinutes, float64(p.Minutes), k.Bandwidth) * p.Weight
+	}
+	return total
+}
+
+// Mode は確率密度が最大となる時刻を返す
+func (k *KernelDensity) Mode() (float64, bool) {
+	if len(k.Points) == 0 {
+		return 0, false
+	}
+	lower, upper := math.Inf(1), math.Inf(-1)
+	for _, p := range k.Points {
+		lower = math.Min(lower, float64(p.Minutes))
+		upper = math.Max(upper, float64(p.Minutes))
+	}
+	return gridMode(k.Density, lower, upper), true
+}
+
+// Probability は from〜to の間に活動が始まる確率を返す。from が負（前日）や to が 1440 以上（翌日）でもよい
+func (k *KernelDensity) Probability(from, to float64) float64 {
+	return k.CDF(to) - k.CDF(from)
+}
+
+// Details は各観測に置いたカーネルとバンド幅を返す
+func (k *KernelDensity) Details() ModelDetails {
+	components := make([]MixtureComponent, 0, len(k.Points))
+	for _, p := range k.Points {
+		components = append(components, MixtureComponent{Mean: float64(p.Minutes), StdDev: k.Bandwidth, Weight: p.Weight})
+	}
+	return ModelDetails{
+		Kind:           ModelKDE,
+		TimeModel:      TimeModelLinear,
+		Samples:        len(k.Points),
+		ComponentCount: len(components),
+		Components:     components,
+		Bandwidth:      k.Bandwidth,
+	}
+}
@@ -0,0 +1,130 @@
+package prediction
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKernelDensityFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		observations  []Observation
+		totalWeight   float64
+		wantBandwidth float64 // 0 の場合は minKDEBandwidth より大きいことのみ確認する
+		wantRate      float64 // CDF(1440) - CDF(0)
+	}{
+		{
+			name:          "no observations",
+			totalWeight:   4,
+			wantBandwidth: minKDEBandwidth,
+		},
+		{
+			name:          "no observation weeks",
+			observations:  observationsAt(600),
+			totalWeight:   0,
+			wantBandwidth: minKDEBandwidth,
+		},
+		{
+			name:          "single observation",
+			observations:  observationsAt(600),
+			totalWeight:   4,
+			wantBandwidth: minKDEBandwidth,
+			wantRate:      0.25,
+		},
+		{
+			// 標準偏差が 0 でもバンド幅は最小値となり、階段関数にはならない
+			name:          "identical observations",
+			observations:  observationsAt(600, 600, 600),
+			totalWeight:   3,
+			wantBandwidth: minKDEBandwidth,
+			wantRate:      1,
+		},
+		{
+			name:         "spread observations",
+			observations: observationsAt(540, 600, 660, 720, 780),
+			totalWeight:  5,
+			wantRate:     1,
+		},
+		{
+			name:         "weighted observations",
+			observations: []Observation{{Minutes: 600, Weight: 1}, {Minutes: 660, Weight: 0.5}, {Minutes: 720, Weight: 0.25}},
+			totalWeight:  2,
+			wantRate:     0.875,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KernelDensity{}
+			k.Fit(tt.observations, tt.totalWeight)
+			if tt.wantBandwidth > 0 && k.Bandwidth != tt.wantBandwidth {
+				t.Errorf("Bandwidth = %v, want %v", k.Bandwidth, tt.wantBandwidth)
+			}
+			if tt.wantBandwidth == 0 && k.Bandwidth <= minKDEBandwidth {
+				t.Errorf("Bandwidth = %v, want more than %v", k.Bandwidth, minKDEBandwidth)
+			}
+			assertNoNaN(t, k)
+			assertValidCDF(t, k, 0, tt.wantRate)
+			if got := k.Probability(0, minutesPerDay); math.Abs(got-tt.wantRate) > 1e-6 {
+				t.Errorf("Probability(0, 1440) = %v, want %v", got, tt.wantRate)
+			}
+			if _, ok := k.Mode(); ok != (len(k.Points) > 0) {
+				t.Errorf("Mode() ok = %v with %d points", ok, len(k.Points))
+			}
+			if details := k.Details(); details.Samples != len(k.Points) || details.Bandwidth != k.Bandwidth {
+				t.Errorf("Details() = %+v", details)
+			}
+		})
+	}
+}
+
+func TestKernelDensityCDF(t *testing.T) {
+	k := &KernelDensity{}
+	k.Fit(observationsAt(600), 2)
+
+	// 観測時刻でちょうど半分、バンド幅1つ分で正規分布の値になる
+	tests := []struct {
+		minutes float64
+		want    float64
+	}{
+		{600, 0.25},
+		{600 + minKDEBandwidth, 0.5 * 0.8413447460685429},
+		{600 - minKDEBandwidth, 0.5 * 0.15865525393145707},
+	}
+	for _, tt := range tests {
+		if got := k.CDF(tt.minutes); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CDF(%v) = %v, want %v", tt.minutes, got, tt.want)
+		}
+	}
+	if got, want := k.Density(600), 0.5/(minKDEBandwidth*math.Sqrt(2*math.Pi)); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Density(600) = %v, want %v", got, want)
+	}
+}
+
+func TestKernelDensityMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations []Observation
+		want         float64 // 密度が最大となるクラスタの中心
+	}{
+		{name: "single observation", observations: observationsAt(600), want: 600},
+		{name: "largest cluster", observations: observationsAt(600, 1080, 1080, 1085), want: 1080},
+		{name: "weights decide the peak", observations: []Observation{{Minutes: 600, Weight: 1}, {Minutes: 1080, Weight: 0.2}}, want: 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KernelDensity{}
+			k.Fit(tt.observations, 4)
+			got, ok := k.Mode()
+			if !ok || math.Abs(got-tt.want) > k.Bandwidth {
+				t.Errorf("Mode() = %v, %v, want within %v of %v", got, ok, k.Bandwidth, tt.want)
+			}
+			for minute := 0.0; minute < minutesPerDay; minute++ {
+				if k.Density(minute) > k.Density(got)+modelTolerance {
+					t.Fatalf("Density(%v) = %v is larger than at Mode() %v", minute, k.Density(minute), got)
+				}
+			}
+		})
+	}
+}
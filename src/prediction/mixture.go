@@ -0,0 +1,163 @@
+package prediction
+
+import "math"
+
+// MixtureComponent は混合分布の1成分（GMM の1クラスタ）を表す
+type MixtureComponent struct {
+	Mean   float64 `json:"mean"`    // 平均（0時からの分）
+	StdDev float64 `json:"std_dev"` // 標準偏差。0 の場合は Mean での階段関数として扱う
+	Weight float64 `json:"weight"`  // クラスタの重み / 確率の分母
+}
+
+// MixtureModel は観測時刻から当てはめた活動開始時刻の混合分布（Model の GMM 実装）を表す
+// GMM によるクラスタリングは当てはめ時に1度だけ行い、CDF は各成分の正規分布から解析的に計算する
+type MixtureModel struct {
+	Components []MixtureComponent `json:"components"`
+
+	samples int
+	bic     *float64 // 選ばれたクラスタ数のBIC（クラスタリングした場合のみ）
+
+	// 以下はブートストラップで当てはめ直す場合のみ設定する（0 の場合はBICでクラスタ数を選ぶ）
+	clusters int // クラスタ数
+	restarts int // クラスタリングの試行回数
+}
+
+// Fit は重み付きの観測時刻を GMM でクラスタリングし、各クラスタを正規分布とした混合分布を当てはめる
+func (m *MixtureModel) Fit(observations []Observation, totalWeight float64) {
+	*m = *fitMixture(observations, totalWeight, m.clusters, m.restarts)
+}
+
+// fitMixture は重み付きの観測時刻を GMM でクラスタリングし、各クラスタを重み付きの正規分布とした混合分布を作る
+// clusters が 0 の場合はBICでクラスタ数を選び、そうでなければ clusters 個のクラスタに restarts 回の試行で分ける
+func fitMixture(observations []Observation, totalWeight float64, clusters, restarts int) *MixtureModel {
+	m := &MixtureModel{Components: []MixtureComponent{}, samples: len(observations), clusters: clusters, restarts: restarts}
+	if len(observations) == 0 || totalWeight <= 0 {
+		return m
+	}
+
+	// データポイントが1つの場合の特別処理
+	if len(observations) == 1 {
+		m.Components = append(m.Components, MixtureComponent{
+			Mean:   float64(observations[0].Minutes),
+			Weight: observations[0].Weight / totalWeight,
+		})
+		return m
+	}
+
+	// 同じ時刻の観測は必ず同じクラスタに属するため、時刻ごとの平均の重みを各観測に割り当てる
+	minutes := make([]int, len(observations))
+	weightSum := make(map[float64]float64)
+	count := make(map[float64]int)
+	for i, o := range observations {
+		minutes[i] = o.Minutes
+		weightSum[float64(o.Minutes)] += o.Weight
+		count[float64(o.Minutes)]++
+	}
+
+	var results []ClusteringResult
+	var bic float64
+	if clusters > 0 {
+		results, bic = clusteringFixed(minutes, clusters, restarts)
+	} else {
+		results, bic = clusteringWithBIC(minutes)
+	}
+	m.bic = &bic
+	for _, c := range results {
+		if len(c.Data) == 0 {
+			continue
+		}
+		weights := make([]float64, len(c.Data))
+		clusterWeight := 0.0
+		for i, x := range c.Data {
+			weights[i] = weightSum[x] / float64(count[x])
+			clusterWeight += weights[i]
+		}
+
+		component := MixtureComponent{Mean: c.Data[0], Weight: clusterWeight / totalWeight}
+		// クラスタ内のデータポイントが1つの場合は標準偏差 0 とする
+		if len(c.Data) > 1 {
+			component.Mean, component.StdDev = weightedMeanStdDev(c.Data, weights)
+		}
+		m.Components = append(m.Components, component)
+	}
+	return m
+}
+
+// CDF は timeMinutes（0時からの分）までに活動が始まる確率を返す
+func (m *MixtureModel) CDF(timeMinutes float64) float64 {
+	total := 0.0
+	for _, c := range m.Components {
+		// scale = 0（クラスタ内のすべてのデータが同じ）
+		if c.StdDev == 0 {
+			if timeMinutes >= c.Mean {
+				total += c.Weight
+			}
+			continue
+		}
+		p := normalCDF(timeMinutes, c.Mean, c.StdDev) * c.Weight
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			continue
+		}
+		total += p
+	}
+	return total
+}
+
+// Density は timeMinutes における1分あたりの確率密度を返す
+// 標準偏差 0 の成分は Mean を中心とする幅1分の区間に重みがあるものとして扱う
+func (m *MixtureModel) Density(timeMinutes float64) float64 {
+	total := 0.0
+	for _, c := range m.Components {
+		if c.StdDev == 0 {
+			if math.Abs(timeMinutes-c.Mean) < 0.5 {
+				total += c.Weight
+			}
+			continue
+		}
+		total += normalPDF(timeMinutes, c.Mean, c.StdDev) * c.Weight
+	}
+	return total
+}
+
+// Mode は混合分布の密度が最大となる時刻を1分刻みで探して返す（KDE と同じ考え方）
+// 標準偏差 0 の成分（1点のみのクラスタ）は密度が1点に集中して外れ値が選ばれやすいため、
+// 最頻時刻を探す際のみ KDE の最小のバンド幅を標準偏差とする
+func (m *MixtureModel) Mode() (float64, bool) {
+	if len(m.Components) == 0 {
+		return 0, false
+	}
+	lower, upper := math.Inf(1), math.Inf(-1)
+	for _, c := range m.Components {
+		lower = math.Min(lower, c.Mean)
+		upper = math.Max(upper, c.Mean)
+	}
+	density := func(t float64) float64 {
+		total := 0.0
+		for _, c := range m.Components {
+			stdDev := c.StdDev
+			if stdDev == 0 {
+				stdDev = minKDEBandwidth
+			}
+			total += normalPDF(t, c.Mean, stdDev) * c.Weight
+		}
+		return total
+	}
+	return gridMode(density, lower, upper), true
+}
+
+// Probability は from〜to の間に活動が始まる確率を返す。from が負（前日）や to が 1440 以上（翌日）でもよい
+func (m *MixtureModel) Probability(from, to float64) float64 {
+	return m.CDF(to) - m.CDF(from)
+}
+
+// Details は選ばれたクラスタ数と各クラスタの正規分布、BIC を返す
+func (m *MixtureModel) Details() ModelDetails {
+	return ModelDetails{
+		Kind:           ModelGMM,
+		TimeModel:      TimeModelLinear,
+		Samples:        m.samples,
+		ComponentCount: len(m.Components),
+		Components:     append([]MixtureComponent{}, m.Components...),
+		BIC:            m.bic,
+	}
+}
@@ -0,0 +1,124 @@
+package prediction
+
+import (
+	"fmt"
+	"math"
+)
+
+// 活動開始時刻の分布のモデル
+const (
+	ModelGMM       = "gmm"       // GMM でクラスタリングし、各クラスタを正規分布とした混合分布
+	ModelKDE       = "kde"       // 観測ごとに正規カーネルを置くカーネル密度推定
+	ModelHistogram = "histogram" // 30分ごとの経験分布（ヒストグラム）
+)
+
+// Model は重み付きの観測時刻から当てはめた活動開始時刻の分布を表す
+// 毎週同じ時刻に活動していれば全体の確率は 1 となり、CDF は指定時刻までに活動が始まる確率を表す
+type Model interface {
+	// Fit は観測時刻（0時からの分）を当てはめる。totalWeight は確率の分母（観測機会の重みの合計）
+	Fit(observations []Observation, totalWeight float64)
+	// CDF は timeMinutes（0時からの分）までに活動が始まる確率を返す
+	CDF(timeMinutes float64) float64
+	// Density は timeMinutes における1分あたりの確率密度（CDF の傾き）を返す
+	Density(timeMinutes float64) float64
+	// Mode は最も活動が始まりやすい時刻（0時からの分）を返す。観測がない場合は false を返す
+	Mode() (float64, bool)
+	// Probability は from〜to（from を含み to を含まない）の間に活動が始まる確率を返す
+	Probability(from, to float64) float64
+	// Details は当てはめたモデルの概要を返す
+	Details() ModelDetails
+}
+
+// ModelDetails は当てはめたモデルの概要を表す。観測数が少ない予測を見分けるために使う
+// Components は各成分を正規分布で近似したもの（gmm: クラスタ / kde: カーネル / histogram: 観測のある区間）
+type ModelDetails struct {
+	Kind           string             `json:"kind"`
+	TimeModel      string             `json:"time_model"`
+	Origin         *float64           `json:"origin,omitempty"`      // circular: 円周を切り開いた時刻（0時からの分）
+	Samples        int                `json:"samples"`               // 当てはめに使った観測数
+	ComponentCount int                `json:"component_count"`       // 成分の数
+	Components     []MixtureComponent `json:"components"`            // 各成分の平均・標準偏差（分）と重み
+	BIC            *float64           `json:"bic,omitempty"`         // gmm: 選ばれたクラスタ数のBIC（観測が2つ以上の場合）
+	Bandwidth      float64            `json:"bandwidth,omitempty"`   // kde: カーネルの標準偏差（分）
+	BinMinutes     int                `json:"bin_minutes,omitempty"` // histogram: 区間の長さ（分）
+}
+
+// ModelSpec はイベントごとの活動開始時刻のモデルの設定を表す
+type ModelSpec struct {
+	Kind      string `json:"kind"`       // gmm / kde / histogram
+	TimeModel string `json:"time_model"` // linear / circular
+}
+
+// IsModelKind は活動開始時刻の分布のモデルとして有効かを判定する
+func IsModelKind(kind string) bool {
+	switch kind {
+	case ModelGMM, ModelKDE, ModelHistogram:
+		return true
+	}
+	return false
+}
+
+// normalize は空の項目を既定値（gmm / linear）とし、設定が有効かを確認する
+func (s ModelSpec) normalize() (ModelSpec, error) {
+	if s.Kind == "" {
+		s.Kind = ModelGMM
+	}
+	if !IsModelKind(s.Kind) {
+		return s, fmt.Errorf("unknown prediction model: %s", s.Kind)
+	}
+	timeModel, err := validateTimeModel(s.TimeModel)
+	if err != nil {
+		return s, err
+	}
+	s.TimeModel = timeModel
+	return s, nil
+}
+
+// NewModel は設定に従って、当てはめる前のモデルを作る
+func NewModel(spec ModelSpec) (Model, error) {
+	spec, err := spec.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var m Model
+	switch spec.Kind {
+	case ModelKDE:
+		m = &KernelDensity{}
+	case ModelHistogram:
+		m = &Histogram{}
+	default:
+		m = &MixtureModel{}
+	}
+	if spec.TimeModel == TimeModelCircular {
+		m = &CircularModel{Inner: m}
+	}
+	return m, nil
+}
+
+// FitDatetimes は "2006-01-02 15:04" 形式の日時から活動開始時刻のモデルを当てはめる
+// 各観測と確率の分母（経過週数 ages の観測機会。WeekAges で求める）は estimator に従って経過週数で重み付けする
+// uniqueDate の場合は日付ごとに最初の時刻のみを使う
+func FitDatetimes(data []string, ages []int, estimator Estimator, uniqueDate bool, spec ModelSpec) (Model, error) {
+	m, err := NewModel(spec)
+	if err != nil {
+		return nil, err
+	}
+	observations, err := parseObservations(data, estimator, uniqueDate)
+	if err != nil {
+		return nil, err
+	}
+	m.Fit(observations, estimator.totalWeight(ages))
+	return m, nil
+}
+
+// gridMode は lower〜upper を1分刻みで調べ、density が最大となる時刻を返す
+func gridMode(density func(float64) float64, lower, upper float64) float64 {
+	best, bestDensity := lower, math.Inf(-1)
+	for t := math.Floor(lower); t <= upper; t++ {
+		if d := density(t); d > bestDensity {
+			best, bestDensity = t, d
+		}
+	}
+	return best
+}
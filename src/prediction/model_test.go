@@ -0,0 +1,87 @@
+package prediction
+
+import (
+	"math"
+	"testing"
+)
+
+const modelTolerance = 1e-9
+
+// observationsAt は重み 1 の観測時刻を作る
+func observationsAt(minutes ...int) []Observation {
+	observations := make([]Observation, len(minutes))
+	for i, m := range minutes {
+		observations[i] = Observation{Minutes: m, Weight: 1}
+	}
+	return observations
+}
+
+// assertValidCDF は CDF が 0時から24時まで NaN を含まずに単調に増加し、lower から upper の範囲に収まるかを検証する
+func assertValidCDF(t *testing.T, m Model, lower, upper float64) {
+	t.Helper()
+	prev := math.Inf(-1)
+	for minute := 0.0; minute <= minutesPerDay; minute++ {
+		v := m.CDF(minute)
+		if math.IsNaN(v) {
+			t.Fatalf("CDF(%v) is NaN", minute)
+		}
+		if v < prev-modelTolerance {
+			t.Fatalf("CDF decreases at %v: %v < %v", minute, v, prev)
+		}
+		prev = v
+	}
+	if got := m.CDF(0); math.Abs(got-lower) > 1e-6 {
+		t.Errorf("CDF(0) = %v, want %v", got, lower)
+	}
+	if got := m.CDF(minutesPerDay); math.Abs(got-upper) > 1e-6 {
+		t.Errorf("CDF(1440) = %v, want %v", got, upper)
+	}
+}
+
+// assertNoNaN はモデルの各関数が NaN を返さないかを検証する
+func assertNoNaN(t *testing.T, m Model) {
+	t.Helper()
+	for _, minute := range []float64{-60, 0, 600, 720, 1439, 1440, 1500} {
+		if v := m.CDF(minute); math.IsNaN(v) {
+			t.Errorf("CDF(%v) is NaN", minute)
+		}
+		if v := m.Density(minute); math.IsNaN(v) || math.IsInf(v, 0) {
+			t.Errorf("Density(%v) = %v", minute, v)
+		}
+		if v := m.Probability(minute, minute+60); math.IsNaN(v) {
+			t.Errorf("Probability(%v, %v) is NaN", minute, minute+60)
+		}
+	}
+	if mode, ok := m.Mode(); ok && (math.IsNaN(mode) || math.IsInf(mode, 0)) {
+		t.Errorf("Mode() = %v", mode)
+	}
+}
+
+func TestModelsProbabilityMatchesCDF(t *testing.T) {
+	observations := observationsAt(600, 610, 620, 640, 1080, 1090)
+	for _, kind := range []string{ModelGMM, ModelKDE, ModelHistogram} {
+		t.Run(kind, func(t *testing.T) {
+			every, err := NewModel(ModelSpec{Kind: kind})
+			if err != nil {
+				t.Fatal(err)
+			}
+			every.Fit(observations, 6)
+			half, err := NewModel(ModelSpec{Kind: kind})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 同じ観測で観測機会が2倍なら、活動する頻度は半分になる
+			half.Fit(observations, 12)
+
+			for _, r := range [][2]float64{{0, 1440}, {540, 660}, {615, 1085}, {1000, 1200}} {
+				p := every.Probability(r[0], r[1])
+				if want := every.CDF(r[1]) - every.CDF(r[0]); math.Abs(p-want) > modelTolerance {
+					t.Errorf("Probability(%v, %v) = %v, want CDF difference %v", r[0], r[1], p, want)
+				}
+				if got := half.Probability(r[0], r[1]); math.Abs(got-p/2) > modelTolerance {
+					t.Errorf("Probability(%v, %v) with twice the weeks = %v, want %v", r[0], r[1], got, p/2)
+				}
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kajiLabTeam/stay-watch-slackbot/controller"
 	_ "github.com/kajiLabTeam/stay-watch-slackbot/docs"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -92,6 +93,8 @@ func Router() {
 		AllowMethods: []string{
 			"GET",
 			"POST",
+			"PATCH",
+			"DELETE",
 		},
 		// 許可したいHTTPリクエストヘッダ
 		AllowHeaders: []string{
@@ -107,29 +110,69 @@ func Router() {
 		MaxAge: 24 * time.Hour,
 	}))
 
-	// Slack endpoints
-	r.POST("/slack/events", controller.PostSlackEvents)
-	r.POST("/slack/interaction", controller.PostSlackInteraction)
-	r.POST("/slack/command/add_user", controller.PostRegisterUserCommand)
-	r.POST("/slack/command/add_event", controller.PostRegisterEventCommand)
-	r.POST("/slack/command/add_correspond", controller.PostRegisterCorrespondCommand)
-	r.POST("/slack/command/list_users", controller.PostListUsersCommand)
-	r.POST("/slack/command/delete_user", controller.PostDeleteUserCommand)
-	r.POST("/slack/command/delete_ob_users", controller.PostDeleteOBUsersCommand)
-	r.GET("/notification", controller.SendDM)
+	// Slack endpoints（すべて署名を検証する）
+	slackGroup := r.Group("/slack", controller.VerifySlackRequest())
+	slackGroup.POST("/events", controller.PostSlackEvents)
+	slackGroup.POST("/interaction", controller.PostSlackInteraction)
+	slackGroup.POST("/command/add_user", controller.PostRegisterUserCommand)
+	slackGroup.POST("/command/add_event", controller.RequireSlackAdmin(), controller.PostRegisterEventCommand)
+	slackGroup.POST("/command/add_correspond", controller.PostRegisterCorrespondCommand)
+	slackGroup.POST("/command/list_users", controller.PostListUsersCommand)
+	slackGroup.POST("/command/delete_user", controller.RequireSlackAdmin(), controller.PostDeleteUserCommand)
+	slackGroup.POST("/command/delete_ob_users", controller.RequireSlackAdmin(), controller.PostDeleteOBUsersCommand)
+	slackGroup.POST("/command/grant_admin", controller.RequireSlackAdmin(), controller.PostGrantAdminCommand)
+	slackGroup.POST("/command/revoke_admin", controller.RequireSlackAdmin(), controller.PostRevokeAdminCommand)
+	slackGroup.POST("/command/notify_preview", controller.PostNotifyPreviewCommand)
+	slackGroup.POST("/command/notify_settings", controller.PostNotifySettingsCommand)
+	slackGroup.POST("/command/api_key", controller.RequireSlackAdmin(), controller.PostAPIKeyCommand)
 
 	// Swagger
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// REST API endpoints
-	r.POST("/api/statuses", controller.PostRegisterStatuses)
-	r.GET("/api/statuses", controller.GetStatuses)
-	r.GET("/api/events", controller.GetEvents)
-	r.GET("/api/events/:id/probability", controller.GetEventProbability)
-	r.GET("/api/activities/probabilities", controller.GetAllActivityProbabilities)
-	r.POST("/api/logs", controller.PostRegisterLogs)
-	r.POST("/api/users/icons/refresh", controller.PostRefreshUserIcons)
-	r.GET("/api/board", controller.GetBoard)
+	// REST API endpoints（Authorization: Bearer のAPIキーをスコープごとに検証する）
+	read := r.Group("/api", controller.RequireAPIKey(service.APIScopeRead))
+	read.GET("/statuses", controller.GetStatuses)
+	read.GET("/events", controller.GetEvents)
+	read.GET("/events/:id", controller.GetEvent)
+	read.GET("/events/:id/members", controller.GetEventMembers)
+	read.GET("/events/:id/probability", controller.GetEventProbability)
+	read.GET("/events/:id/sessions", controller.GetEventSessions)
+	read.GET("/activities/probabilities", controller.GetAllActivityProbabilities)
+	read.GET("/forecast", controller.GetForecast)
+	read.GET("/logs", controller.GetLogs)
+	read.GET("/logs/export", controller.GetExportLogs)
+	read.GET("/logs/:id", controller.GetLog)
+	read.GET("/users", controller.GetUsers)
+	read.GET("/users/:id", controller.GetUser)
+	read.GET("/board", controller.GetBoard)
+	read.GET("/notifications", controller.GetNotifications)
+	read.GET("/calendar", controller.GetCalendar)
+
+	writeLogs := r.Group("/api", controller.RequireAPIKey(service.APIScopeWriteLogs))
+	writeLogs.POST("/logs", controller.PostRegisterLogs)
+
+	admin := r.Group("/api", controller.RequireAPIKey(service.APIScopeAdmin))
+	admin.POST("/statuses", controller.PostRegisterStatuses)
+	admin.POST("/events", controller.PostEvent)
+	admin.PATCH("/events/:id", controller.PatchEvent)
+	admin.DELETE("/events/:id", controller.DeleteEvent)
+	admin.POST("/events/:id/members", controller.PostEventMember)
+	admin.DELETE("/events/:id/members/:user_id", controller.DeleteEventMember)
+	admin.POST("/logs/import", controller.PostImportLogs)
+	admin.PATCH("/logs/:id", controller.PatchLog)
+	admin.DELETE("/logs/:id", controller.DeleteLog)
+	admin.POST("/logs/:id/restore", controller.PostRestoreLog)
+	admin.POST("/users", controller.PostUser)
+	admin.PATCH("/users/:id", controller.PatchUser)
+	admin.DELETE("/users/:id", controller.DeleteUser)
+	admin.POST("/users/icons/refresh", controller.PostRefreshUserIcons)
+	admin.POST("/calendar/import", controller.PostImportCalendar)
+	admin.DELETE("/calendar/:id", controller.DeleteCalendarEntry)
+	// 予測の評価は対象日ごとにモデルを当てはめ直すため、GET でも admin スコープを必要とする
+	admin.GET("/prediction/evaluation", controller.GetPredictionEvaluation)
+
+	// 通知の手動実行はDMを送信するため admin スコープを必要とする
+	r.GET("/notification", controller.RequireAPIKey(service.APIScopeAdmin), controller.SendDM)
 
 	_ = r.Run(":8085")
 }
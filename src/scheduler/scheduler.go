@@ -0,0 +1,125 @@
+// Package scheduler runs periodic jobs such as the daily activity notification in-process.
+package scheduler
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/service"
+)
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// parseSchedules は ";" 区切りの cron 式を読み込む
+// 曜日ごとに送信時刻を変える場合は "0 21 * * sun-thu; 0 20 * * fri" のように複数指定する
+func parseSchedules(spec string) ([]*lib.CronSchedule, error) {
+	var schedules []*lib.CronSchedule
+	for _, expr := range strings.Split(spec, ";") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		s, err := lib.ParseCron(expr)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// nextRun は各スケジュールのうち最も早い次回実行時刻を返す
+func nextRun(schedules []*lib.CronSchedule, now time.Time) time.Time {
+	var next time.Time
+	for _, s := range schedules {
+		t := s.Next(now)
+		if t.IsZero() {
+			continue
+		}
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// deliveryInterval は希望送信時刻待ち・再送待ちのDMを確認する間隔
+const deliveryInterval = time.Minute
+
+// Start は NOTIFICATION_SCHEDULE（JSTのcron式）に従って通知ジョブをバックグラウンドで開始する
+// 未設定の場合は通知ジョブを起動せず、GET /notification による外部起動のみとなる
+// 希望送信時刻待ち・再送待ちのDMの送信ジョブは設定に関わらず常に起動する
+func Start() {
+	go sendDueDeliveries()
+
+	spec := getEnv("NOTIFICATION_SCHEDULE", "")
+	if spec == "" {
+		log.Printf("NOTIFICATION_SCHEDULE is not set; notification scheduler disabled")
+		return
+	}
+
+	schedules, err := parseSchedules(spec)
+	if err != nil {
+		log.Fatalf("invalid NOTIFICATION_SCHEDULE: %v", err)
+	}
+	if len(schedules) == 0 {
+		log.Printf("NOTIFICATION_SCHEDULE has no schedules; notification scheduler disabled")
+		return
+	}
+
+	go run(schedules)
+}
+
+// run は次回実行時刻まで待機して通知を実行する処理を繰り返す
+func run(schedules []*lib.CronSchedule) {
+	for {
+		next := nextRun(schedules, lib.NowJST())
+		if next.IsZero() {
+			log.Printf("notification scheduler has no upcoming runs; stopping")
+			return
+		}
+		time.Sleep(time.Until(next))
+		runNotification(next)
+	}
+}
+
+// runNotification は実行時刻の翌日を対象日として通知を実行する
+func runNotification(at time.Time) {
+	targetDate := at.AddDate(0, 0, 1)
+	result, err := service.RunNotification(targetDate, service.NotificationTriggerScheduler)
+	if err != nil {
+		if errors.Is(err, service.ErrNotificationAlreadyCompleted) || errors.Is(err, service.ErrNotificationInProgress) {
+			log.Printf("notification for %s skipped: %v", targetDate.Format("2006-01-02"), err)
+			return
+		}
+		log.Printf("notification for %s failed: %v", targetDate.Format("2006-01-02"), err)
+		return
+	}
+	log.Printf("notification for %s completed: recipients=%d sent=%d failed=%d skipped=%d",
+		result.TargetDate, result.Recipients, result.Sent, result.Failed, result.Skipped)
+}
+
+// sendDueDeliveries は一定間隔で送信予定時刻を過ぎたDMを送信する
+func sendDueDeliveries() {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sent, err := service.SendDueNotificationDeliveries()
+		if err != nil {
+			log.Printf("sending due notifications failed: %v", err)
+			continue
+		}
+		if sent > 0 {
+			log.Printf("sent %d due notification DM(s)", sent)
+		}
+	}
+}
@@ -12,80 +12,138 @@ import (
 
 // ActivityProbability は活動名と1時間ごとの発生確率を表す
 type ActivityProbability struct {
-	ActivityName  string    `json:"activity_name"`
-	Probabilities []float64 `json:"probabilities"` // length 24, index = hour (0-23 JST), value = 0.0〜1.0
+	ActivityName  string             `json:"activity_name"`
+	Probabilities []float64          `json:"probabilities"`     // length 24, index = hour (0-23 JST), value = 0.0〜1.0
+	Details       *PredictionDetails `json:"details,omitempty"` // withDetails の場合のみ設定される
 }
 
 // ActivityTimeRange は活動の予測時間帯を表す
 type ActivityTimeRange struct {
 	Start string // "HH:MM"
-	End   string // "HH:MM"
+	End   string // "HH:MM"（circular のイベントで0時をまたぐ場合のみ End < Start となる）
+	// 以下は活動時間モデルで予測した場合のみ設定される
+	EndLower        string  // 終了時刻の信頼区間の下限 "HH:MM"
+	EndUpper        string  // 終了時刻の信頼区間の上限 "HH:MM"
+	DurationMinutes float64 // 予測した活動時間（分）
+	DurationSamples int     // 活動時間の予測に使用した活動の数
+	Method          string  // "duration"（開始時刻 + 活動時間）/ "end_time"（終了時刻を個別に予測）
 }
 
-// calculateWeeks 最初のログから今日までの週数を計算する
-func calculateWeeks(logs []model.Log) int {
-	if len(logs) == 0 {
-		return 0
+// minuteSegments は予測時間帯を0時からの分の区間 [開始, 終了] に分けて返す
+// 0時をまたぐ場合は [Start, 23:59] と [0:00, End] の2つとなる
+func (r ActivityTimeRange) minuteSegments() ([][2]int, error) {
+	start, err := lib.TimeToMinutes(r.Start)
+	if err != nil {
+		return nil, err
 	}
-	oldestLog := logs[0]
-	for _, log := range logs {
-		if log.EventTime.Before(oldestLog.EventTime) {
-			oldestLog = log
-		}
+	end, err := lib.TimeToMinutes(r.End)
+	if err != nil {
+		return nil, err
 	}
-	now := lib.NowJST()
-	days := int(now.Sub(oldestLog.EventTime).Hours() / 24)
-	return (days / 7) + 1
+	if end < start {
+		return [][2]int{{start, lastMinuteOfDay}, {0, end}}, nil
+	}
+	return [][2]int{{start, end}}, nil
 }
 
-// GetActivityProbability イベントごとの活動確率を取得する
-func GetActivityProbability(eventID uint, dayOfWeek time.Weekday, targetTime string) (float64, error) {
-	// 1. ログを取得
-	logs, err := model.ReadLogsByEventIDAndDayOfWeek(eventID, dayOfWeek)
-	if err != nil || len(logs) == 0 {
-		return 0.0, nil // データ不足時は 0.0 を返す
+// contains は minutes（0時からの分）が予測時間帯に含まれるかを返す
+func (r ActivityTimeRange) contains(minutes int) bool {
+	segments, err := r.minuteSegments()
+	if err != nil {
+		return false
+	}
+	for _, seg := range segments {
+		if minutes >= seg[0] && minutes <= seg[1] {
+			return true
+		}
 	}
+	return false
+}
 
-	// 2. 週数を計算
-	weeks := calculateWeeks(logs)
+// 活動時間モデルの設定
+const (
+	activityRangeMethodDuration = "duration"
+	activityRangeMethodEndTime  = "end_time"
+	minDurationSamples          = 3    // これ未満の活動数では終了時刻を個別に予測する
+	durationLowerQuantile       = 0.1  // 終了時刻の信頼区間の下限
+	durationUpperQuantile       = 0.9  // 終了時刻の信頼区間の上限
+	lastMinuteOfDay             = 1439 // 23:59
+)
 
-	// 3. Status が "start" のログをフィルタリング（日付付き）
-	// DBはJSTなのでそのまま使用（targetTimeもJST）
-	var datetimeStrings []string
-	for _, log := range logs {
-		if log.Status.Name == "start" {
-			datetimeStr := log.EventTime.Format("2006-01-02 15:04")
-			datetimeStrings = append(datetimeStrings, datetimeStr)
+// calculateWeekAges は start から今日までの観測機会の週を、経過週数（今週を 0 とする）で返す
+// 学事暦で除外する日（excluded）のうち dayOfWeek の日は、その週の観測機会がないものとして除く
+func calculateWeekAges(start time.Time, dayOfWeek time.Weekday, excluded map[string]bool) []int {
+	return calculateWeekAgesAt(start, lib.NowJST(), dayOfWeek, excluded)
+}
+
+// calculateWeekAgesAt は start から now までの観測機会の週を返す。excluded のうち start〜now の範囲外の日付は数えない
+// 除外した週の経過週数を除くため、decay・window の重み付けでも確率の分母は実際に観測できた週の重みの合計となる
+func calculateWeekAgesAt(start, now time.Time, dayOfWeek time.Weekday, excluded map[string]bool) []int {
+	first, last := start.In(lib.JST).Format("2006-01-02"), now.In(lib.JST).Format("2006-01-02")
+	var dates []time.Time
+	for date := range excluded {
+		if date < first || date > last {
+			continue
+		}
+		d, err := time.ParseInLocation("2006-01-02", date, lib.JST)
+		if err == nil && d.Weekday() == dayOfWeek {
+			dates = append(dates, d)
 		}
 	}
+	ages := prediction.WeekAges(now, weeksSince(start, now), dates)
+	if len(ages) == 0 {
+		return []int{0}
+	}
+	return ages
+}
 
-	if len(datetimeStrings) == 0 {
-		return 0.0, nil
+// oldestLogTime はログの中で最も古い発生時刻を返す
+func oldestLogTime(logs []model.Log) time.Time {
+	oldest := logs[0].EventTime
+	for _, log := range logs {
+		if log.EventTime.Before(oldest) {
+			oldest = log.EventTime
+		}
 	}
+	return oldest
+}
 
-	// 3. prediction パッケージで確率計算（日付重複を排除）
-	probability, err := prediction.GetProbabilityByUniqueDate(datetimeStrings, targetTime, weeks)
+// GetActivityProbability イベントごとの活動確率を lookback の期間のログから取得する
+// 日付重複を排除し、設定に応じて古いログの重みを減らす。当てはめたモデルはログが更新されるまで再利用する
+// 時刻を円周上で扱うイベント（circular）でも、固定の日の区切り 0:00 から targetTime までの確率を返す
+func GetActivityProbability(event model.Event, dayOfWeek time.Weekday, targetTime string, lookback Lookback) (float64, error) {
+	targetMinutes, err := lib.TimeToMinutes(targetTime)
 	if err != nil {
 		return 0.0, err
 	}
 
-	return probability, nil
+	m, err := startTimeModel(event, dayOfWeek, lookback, true)
+	if err != nil {
+		return 0.0, nil // データ取得失敗時は 0.0 を返す（データ不足時はモデルの確率が 0.0 となる）
+	}
+	return m.CDF(float64(targetMinutes)), nil
 }
 
 // getActivityTimeRange イベントの活動予測時刻範囲を取得する
-func getActivityTimeRange(eventID uint, dayOfWeek time.Weekday) (ActivityTimeRange, error) {
-	logs, err := model.ReadLogsByEventIDAndDayOfWeek(eventID, dayOfWeek)
+// 開始時刻を予測し、start/end ログを組にした過去の活動時間の中央値を足して終了時刻とする
+// 完了した活動が minDurationSamples 未満の場合は、終了時刻を個別に予測する（開始時刻より前にはしない）
+func getActivityTimeRange(event model.Event, dayOfWeek time.Weekday, lookback Lookback) (ActivityTimeRange, error) {
+	logs, ages, err := readPredictionLogs(event.ID, dayOfWeek, lookback)
 	if err != nil || len(logs) == 0 {
 		return ActivityTimeRange{Start: "00:00", End: "23:59"}, nil
 	}
+	return activityTimeRangeFromLogs(event, logs, ages, lib.NowJST())
+}
 
-	weeks := calculateWeeks(logs)
-
+// activityTimeRangeFromLogs は指定曜日のログと観測機会の週（経過週数）から活動予測時刻範囲を求める。now は進行中の活動を判定する基準時刻
+// 開始・終了時刻はイベントのモデルで当てはめた分布の最頻時刻とする
+// circular のイベントでは終了時刻が0時をまたいでもよく、その場合は End < Start の範囲を返す
+func activityTimeRangeFromLogs(event model.Event, logs []model.Log, ages []int, now time.Time) (ActivityTimeRange, error) {
 	// start と end のログを分離（DBはJSTなのでそのまま使用）
 	var startTimes []string
 	var endTimes []string
 	for _, log := range logs {
-		timeStr := lib.FormatTime(log.EventTime)
+		timeStr := log.EventTime.Format("2006-01-02 15:04")
 		switch log.Status.Name {
 		case "start":
 			startTimes = append(startTimes, timeStr)
@@ -94,22 +152,81 @@ func getActivityTimeRange(eventID uint, dayOfWeek time.Weekday) (ActivityTimeRan
 		}
 	}
 
-	startTime := predictTime(startTimes, weeks, "00:00")
-	endTime := predictTime(endTimes, weeks, "23:59")
+	startTime := predictTime(startTimes, ages, now, eventModelSpec(event), "00:00")
+	startMinutes, err := lib.TimeToMinutes(startTime)
+	if err != nil {
+		return ActivityTimeRange{}, err
+	}
 
-	return ActivityTimeRange{Start: startTime, End: endTime}, nil
+	wrap := event.TimeModel == prediction.TimeModelCircular
+	if durations := completedSessionDurations(logs, now); len(durations) >= minDurationSamples {
+		duration, err := prediction.PredictDuration(durations, durationLowerQuantile, durationUpperQuantile)
+		if err == nil {
+			return ActivityTimeRange{
+				Start:           startTime,
+				End:             addDurationMinutes(startMinutes, duration.Median, wrap),
+				EndLower:        addDurationMinutes(startMinutes, duration.Lower, wrap),
+				EndUpper:        addDurationMinutes(startMinutes, duration.Upper, wrap),
+				DurationMinutes: duration.Median,
+				DurationSamples: duration.Samples,
+				Method:          activityRangeMethodDuration,
+			}, nil
+		}
+	}
+
+	endTime := predictTime(endTimes, ages, now, eventModelSpec(event), "23:59")
+	if endMinutes, err := lib.TimeToMinutes(endTime); err != nil || (!wrap && endMinutes < startMinutes) {
+		endTime = "23:59"
+	}
+	return ActivityTimeRange{Start: startTime, End: endTime, Method: activityRangeMethodEndTime}, nil
+}
+
+// completedSessionDurations はログから再構成した完了済みの活動について、開始から終了までの経過時間（分）を返す
+func completedSessionDurations(logs []model.Log, now time.Time) []float64 {
+	var durations []float64
+	for _, s := range BuildSessions(logs, now) {
+		if s.Status != sessionStatusCompleted {
+			continue
+		}
+		durations = append(durations, s.End.Sub(s.Start).Minutes())
+	}
+	return durations
 }
 
-// predictTime は時刻リストから最尤時刻を予測する。データ不足やエラー時はデフォルト値を返す
-func predictTime(times []string, weeks int, defaultTime string) string {
-	if len(times) == 0 {
+// addDurationMinutes は開始時刻（分）に活動時間を足した時刻を "HH:MM" で返す
+// 日をまたぐ場合、wrap なら翌日の時刻（0時をまたいだ時刻）とし、そうでなければ 23:59 とする
+func addDurationMinutes(startMinutes int, duration float64, wrap bool) string {
+	end := startMinutes + int(math.Round(duration))
+	if end > lastMinuteOfDay {
+		if wrap && end-startMinutes <= lastMinuteOfDay {
+			return lib.MinutesToTime(end - (lastMinuteOfDay + 1))
+		}
+		end = lastMinuteOfDay
+	}
+	return lib.MinutesToTime(end)
+}
+
+// predictTime は日時リストに spec のモデルを当てはめ、最頻時刻を予測する。データ不足やエラー時はデフォルト値を返す
+// 活動確率の重み付け（PREDICTION_MODE）は適用せず、すべての観測を等しく扱う
+func predictTime(datetimes []string, ages []int, now time.Time, spec prediction.ModelSpec, defaultTime string) string {
+	if len(datetimes) == 0 {
 		return defaultTime
 	}
-	minutes, err := prediction.GetMostLikelyTime(times, weeks)
+	estimator := prediction.Estimator{Mode: prediction.EstimatorUniform, Now: now}
+	m, err := prediction.FitDatetimes(datetimes, ages, estimator, false, spec)
 	if err != nil {
 		return defaultTime
 	}
-	return lib.MinutesToTime(minutes)
+	mode, ok := m.Mode()
+	if !ok {
+		return defaultTime
+	}
+	return lib.MinutesToTime(int(math.Round(mode)) % (lastMinuteOfDay + 1))
+}
+
+// eventModelSpec はイベントに設定された活動開始時刻のモデルを返す
+func eventModelSpec(event model.Event) prediction.ModelSpec {
+	return prediction.ModelSpec{Kind: event.PredictionModel, TimeModel: event.TimeModel}
 }
 
 // getUserActivityEventIDs はユーザーが登録している活動のイベントIDセットを取得する
@@ -171,29 +288,18 @@ func extractStartDatetimes(logs []model.Log) []string {
 
 // calcHourlyProbabilities は各時間帯（JST 0〜23時）の確率を計算する
 // H時 = CDF(H:30) - CDF((H-1):30) で (H-1):30〜H:30 の確率密度合計を求める
-func calcHourlyProbabilities(datetimeStrings []string, weeks int) []float64 {
+func calcHourlyProbabilities(m prediction.Model) []float64 {
 	probabilities := make([]float64, 24)
 	for hour := 0; hour < 24; hour++ {
-		probabilities[hour] = calcHourProbability(datetimeStrings, hour, weeks)
+		probabilities[hour] = calcHourProbability(m, hour)
 	}
 	return probabilities
 }
 
 // calcHourProbability は指定時間帯の確率を計算する
-func calcHourProbability(datetimeStrings []string, hour int, weeks int) float64 {
-	endTimeJST := fmt.Sprintf("%02d:30", hour)
-	startTimeJST := fmt.Sprintf("%02d:30", (hour-1+24)%24)
-
-	cdfEnd, err := prediction.GetProbabilityFromDatetimes(datetimeStrings, endTimeJST, weeks)
-	if err != nil {
-		return 0.0
-	}
-	cdfStart, err := prediction.GetProbabilityFromDatetimes(datetimeStrings, startTimeJST, weeks)
-	if err != nil {
-		return 0.0
-	}
-
-	prob := cdfEnd - cdfStart
+func calcHourProbability(m prediction.Model, hour int) float64 {
+	// 0時の場合は前日の 23:30〜0:30 とする（circular では円周上の区間として求まる）
+	prob := m.Probability(float64(hour*60-30), float64(hour*60+30))
 	if math.IsNaN(prob) || math.IsInf(prob, 0) || prob < 0 {
 		return 0.0
 	}
@@ -204,28 +310,32 @@ func calcHourProbability(datetimeStrings []string, hour int, weeks int) float64
 }
 
 // calcEventProbability はイベント1件分の活動確率を計算する
-func calcEventProbability(ev model.Event, dayOfWeek time.Weekday) ActivityProbability {
-	logs, err := model.ReadLogsByEventIDAndDayOfWeek(ev.ID, dayOfWeek)
-	if err != nil || len(logs) == 0 {
-		return ActivityProbability{ActivityName: ev.Name, Probabilities: make([]float64, 24)}
-	}
-
-	weeks := calculateWeeks(logs)
-	datetimeStrings := extractStartDatetimes(logs)
-	if len(datetimeStrings) == 0 {
+// 当てはめたモデルはログが更新されるまで再利用するため、各時間帯の確率は CDF の評価のみで求まる
+// withDetails の場合はモデルの概要と、ブートストラップによる各時間帯の確率の信頼区間を加える
+func calcEventProbability(ev model.Event, dayOfWeek time.Weekday, lookback Lookback, withDetails bool) ActivityProbability {
+	fit, err := startTimeFit(ev, dayOfWeek, lookback, false)
+	if err != nil {
 		return ActivityProbability{ActivityName: ev.Name, Probabilities: make([]float64, 24)}
 	}
 
-	return ActivityProbability{
+	result := ActivityProbability{
 		ActivityName:  ev.Name,
-		Probabilities: calcHourlyProbabilities(datetimeStrings, weeks),
+		Probabilities: calcHourlyProbabilities(fit.model),
+	}
+	if withDetails {
+		if replicates, err := fit.bootstrap(); err == nil {
+			result.Details = newPredictionDetails(fit, len(replicates))
+			result.Details.HourlyIntervals = calcHourlyIntervals(replicates)
+		}
 	}
+	return result
 }
 
 // GetAllActivityProbabilities は全活動の1時間ごとの発生確率を取得する
 // 各時間帯（JST H時）について、(H-1):30〜H:30 の範囲の確率密度合計を計算する
 // 例: 12時の場合、CDF(12:30) - CDF(11:30) で 11:30〜12:30 の確率を求める
-func GetAllActivityProbabilities(dayOfWeek time.Weekday) ([]ActivityProbability, error) {
+// 確率は lookback の期間のログから計算する。withDetails の場合はモデルの概要と信頼区間を加える
+func GetAllActivityProbabilities(dayOfWeek time.Weekday, lookback Lookback, withDetails bool) ([]ActivityProbability, error) {
 	event := model.Event{}
 	events, err := event.ReadAll()
 	if err != nil {
@@ -234,7 +344,7 @@ func GetAllActivityProbabilities(dayOfWeek time.Weekday) ([]ActivityProbability,
 
 	var results []ActivityProbability
 	for _, ev := range events {
-		results = append(results, calcEventProbability(ev, dayOfWeek))
+		results = append(results, calcEventProbability(ev, dayOfWeek, lookback, withDetails))
 	}
 	return results, nil
 }
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+)
+
+func TestActivityTimeRangeMinuteSegments(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       ActivityTimeRange
+		want    [][2]int
+		wantErr bool
+	}{
+		{name: "within the day", r: ActivityTimeRange{Start: "10:00", End: "18:30"}, want: [][2]int{{600, 1110}}},
+		{name: "whole day", r: ActivityTimeRange{Start: "00:00", End: "23:59"}, want: [][2]int{{0, 1439}}},
+		{name: "single minute", r: ActivityTimeRange{Start: "12:00", End: "12:00"}, want: [][2]int{{720, 720}}},
+		{name: "across midnight", r: ActivityTimeRange{Start: "22:00", End: "01:30"}, want: [][2]int{{1320, 1439}, {0, 90}}},
+		{name: "ends at midnight", r: ActivityTimeRange{Start: "23:00", End: "00:00"}, want: [][2]int{{1380, 1439}, {0, 0}}},
+		{name: "starts one minute before midnight", r: ActivityTimeRange{Start: "23:59", End: "00:01"}, want: [][2]int{{1439, 1439}, {0, 1}}},
+		{name: "invalid start", r: ActivityTimeRange{Start: "22-00", End: "01:00"}, wantErr: true},
+		{name: "invalid end", r: ActivityTimeRange{Start: "10:00", End: ""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.r.minuteSegments()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("minuteSegments() error = nil, want error")
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("minuteSegments() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivityTimeRangeContains(t *testing.T) {
+	acrossMidnight := ActivityTimeRange{Start: "22:00", End: "01:30"}
+	daytime := ActivityTimeRange{Start: "10:00", End: "18:30"}
+
+	tests := []struct {
+		name    string
+		r       ActivityTimeRange
+		minutes int
+		want    bool
+	}{
+		{name: "before daytime range", r: daytime, minutes: 599, want: false},
+		{name: "daytime start", r: daytime, minutes: 600, want: true},
+		{name: "daytime end", r: daytime, minutes: 1110, want: true},
+		{name: "after daytime range", r: daytime, minutes: 1111, want: false},
+		{name: "before the night range", r: acrossMidnight, minutes: 1319, want: false},
+		{name: "night start", r: acrossMidnight, minutes: 1320, want: true},
+		{name: "23:59", r: acrossMidnight, minutes: 1439, want: true},
+		{name: "00:00", r: acrossMidnight, minutes: 0, want: true},
+		{name: "night end", r: acrossMidnight, minutes: 90, want: true},
+		{name: "after the night range", r: acrossMidnight, minutes: 91, want: false},
+		{name: "midday outside the night range", r: acrossMidnight, minutes: 720, want: false},
+		{name: "invalid range", r: ActivityTimeRange{Start: "x", End: "y"}, minutes: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.contains(tt.minutes); got != tt.want {
+				t.Errorf("contains(%d) = %v, want %v", tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddDurationMinutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    int
+		duration float64
+		wrap     bool
+		want     string
+	}{
+		{name: "within the day", start: 600, duration: 90, want: "11:30"},
+		{name: "rounded to the minute", start: 600, duration: 89.6, want: "11:30"},
+		{name: "ends at 23:59", start: 1380, duration: 59, want: "23:59"},
+		{name: "linear is clamped at 23:59", start: 1380, duration: 120, want: "23:59"},
+		{name: "circular wraps past midnight", start: 1380, duration: 120, wrap: true, want: "01:00"},
+		{name: "circular wraps to 00:00", start: 1380, duration: 60, wrap: true, want: "00:00"},
+		// 24時間以上の活動は翌日の同じ時刻と区別できないため 23:59 とする
+		{name: "circular longer than a day", start: 1380, duration: 1440, wrap: true, want: "23:59"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addDurationMinutes(tt.start, tt.duration, tt.wrap); got != tt.want {
+				t.Errorf("addDurationMinutes(%d, %v, %v) = %q, want %q", tt.start, tt.duration, tt.wrap, got, tt.want)
+			}
+		})
+	}
+}
+
+// weeklySessionLogs は毎週同じ曜日の startHour:startMinute に始まり、durations[i] 分後に終わる活動のログを作る
+// i 番目の活動は len(durations)-i 週前に行われたものとする
+func weeklySessionLogs(now time.Time, startHour, startMinute int, durations []int) []model.Log {
+	var logs []model.Log
+	for i, d := range durations {
+		day := now.AddDate(0, 0, -7*(len(durations)-i))
+		start := time.Date(day.Year(), day.Month(), day.Day(), startHour, startMinute, 0, 0, lib.JST)
+		logs = append(logs,
+			testLog(uint(2*i+1), statusNameStart, start),
+			testLog(uint(2*i+2), statusNameEnd, start.Add(time.Duration(d)*time.Minute)))
+	}
+	return logs
+}
+
+func TestActivityTimeRangeFromLogs(t *testing.T) {
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+	linear := model.Event{}
+	circular := model.Event{TimeModel: prediction.TimeModelCircular}
+
+	tests := []struct {
+		name  string
+		event model.Event
+		logs  []model.Log
+		want  ActivityTimeRange
+	}{
+		{
+			// 活動が minDurationSamples 未満の場合は終了時刻のログから個別に予測する
+			name:  "two sessions use the end time model",
+			event: linear,
+			logs:  weeklySessionLogs(now, 10, 0, []int{60, 60}),
+			want:  ActivityTimeRange{Start: "10:00", End: "11:00", Method: activityRangeMethodEndTime},
+		},
+		{
+			name:  "three sessions use start plus median duration",
+			event: linear,
+			logs:  weeklySessionLogs(now, 10, 0, []int{60, 90, 120}),
+			want: ActivityTimeRange{
+				Start: "10:00", End: "11:30", EndLower: "11:06", EndUpper: "11:54",
+				DurationMinutes: 90, DurationSamples: 3, Method: activityRangeMethodDuration,
+			},
+		},
+		{
+			// 終了していない活動は活動時間に数えない
+			name:  "ongoing session is not counted",
+			event: linear,
+			logs: append(weeklySessionLogs(now, 10, 0, []int{60, 60}),
+				testLog(100, statusNameStart, now.Add(-time.Hour))),
+			want: ActivityTimeRange{Start: "10:00", End: "11:00", Method: activityRangeMethodEndTime},
+		},
+		{
+			name:  "linear duration is clamped at 23:59",
+			event: linear,
+			logs:  weeklySessionLogs(now, 23, 0, []int{120, 120, 180}),
+			want: ActivityTimeRange{
+				Start: "23:00", End: "23:59", EndLower: "23:59", EndUpper: "23:59",
+				DurationMinutes: 120, DurationSamples: 3, Method: activityRangeMethodDuration,
+			},
+		},
+		{
+			name:  "circular duration wraps past midnight",
+			event: circular,
+			logs:  weeklySessionLogs(now, 23, 0, []int{120, 120, 180}),
+			want: ActivityTimeRange{
+				Start: "23:00", End: "01:00", EndLower: "01:00", EndUpper: "01:48",
+				DurationMinutes: 120, DurationSamples: 3, Method: activityRangeMethodDuration,
+			},
+		},
+		{
+			// linear では終了時刻が開始時刻より前になる場合は 23:59 とする
+			name:  "linear end time before the start",
+			event: linear,
+			logs:  weeklySessionLogs(now, 23, 0, []int{120, 120}),
+			want:  ActivityTimeRange{Start: "23:00", End: "23:59", Method: activityRangeMethodEndTime},
+		},
+		{
+			name:  "circular end time after midnight",
+			event: circular,
+			logs:  weeklySessionLogs(now, 23, 0, []int{120, 120}),
+			want:  ActivityTimeRange{Start: "23:00", End: "01:00", Method: activityRangeMethodEndTime},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := activityTimeRangeFromLogs(tt.event, tt.logs, []int{0, 1, 2, 3}, now)
+			if err != nil {
+				t.Fatalf("activityTimeRangeFromLogs() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("activityTimeRangeFromLogs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateWeekAgesAt(t *testing.T) {
+	// 2025-06-13 は金曜日
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+	start := time.Date(2025, 5, 16, 0, 0, 0, 0, lib.JST)
+
+	tests := []struct {
+		name      string
+		dayOfWeek time.Weekday
+		excluded  map[string]bool
+		want      []int
+	}{
+		{name: "no excluded days", dayOfWeek: time.Friday, want: []int{0, 1, 2, 3, 4}},
+		{name: "excluded friday", dayOfWeek: time.Friday, excluded: map[string]bool{"2025-06-06": true}, want: []int{0, 2, 3, 4}},
+		{name: "excluded other weekday", dayOfWeek: time.Friday, excluded: map[string]bool{"2025-06-05": true}, want: []int{0, 1, 2, 3, 4}},
+		{name: "excluded before start", dayOfWeek: time.Friday, excluded: map[string]bool{"2025-05-09": true}, want: []int{0, 1, 2, 3, 4}},
+		{name: "excluded today", dayOfWeek: time.Friday, excluded: map[string]bool{"2025-06-13": true, "2025-05-16": true}, want: []int{1, 2, 3}},
+		// すべての週が除外された場合も今週の1週を観測機会とする
+		{
+			name:      "all weeks excluded",
+			dayOfWeek: time.Friday,
+			excluded: map[string]bool{
+				"2025-05-16": true, "2025-05-23": true, "2025-05-30": true, "2025-06-06": true, "2025-06-13": true,
+			},
+			want: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateWeekAgesAt(start, now, tt.dayOfWeek, tt.excluded); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calculateWeekAgesAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// slackMentionPattern はスラッシュコマンドの本文に含まれるユーザーメンション（<@U123|name>）
+var slackMentionPattern = regexp.MustCompile(`^<@([A-Z0-9]+)(\|[^>]*)?>$`)
+
+// configuredAdminIDs は SLACK_ADMIN_IDS で設定された管理者の SlackID を返す
+func configuredAdminIDs() []string {
+	return splitList(getEnv("SLACK_ADMIN_IDS", ""))
+}
+
+// isConfiguredAdmin は SlackID が SLACK_ADMIN_IDS に含まれるかを判定する
+func isConfiguredAdmin(slackUserID string) bool {
+	for _, id := range configuredAdminIDs() {
+		if id == slackUserID {
+			return true
+		}
+	}
+	return false
+}
+
+// SeedAdmins は SLACK_ADMIN_IDS に設定された登録済みユーザーに管理者権限を付与する
+func SeedAdmins() {
+	ids := configuredAdminIDs()
+	if len(ids) == 0 {
+		return
+	}
+
+	var u model.User
+	users, err := u.ReadBySlackIDs(ids)
+	if err != nil {
+		log.Printf("failed to seed admins: %v", err)
+		return
+	}
+	for i := range users {
+		if users[i].IsAdmin {
+			continue
+		}
+		users[i].IsAdmin = true
+		if err := users[i].UpdateIsAdmin(); err != nil {
+			log.Printf("failed to seed admin %s: %v", users[i].SlackID, err)
+		}
+	}
+}
+
+// IsAdmin はSlackユーザーが管理者かを判定する
+// SLACK_ADMIN_IDS に含まれるか、DB上で管理者権限が付与されている場合に true を返す
+func IsAdmin(slackUserID string) bool {
+	if slackUserID == "" {
+		return false
+	}
+	if isConfiguredAdmin(slackUserID) {
+		return true
+	}
+	user := model.User{SlackID: slackUserID}
+	if err := user.ReadBySlackID(); err != nil || user.ID == 0 {
+		return false
+	}
+	return user.IsAdmin
+}
+
+// findUserByCommandText はコマンド本文（ユーザー名またはメンション）からユーザーを取得する
+func findUserByCommandText(text string) (model.User, error) {
+	text = strings.TrimSpace(text)
+	user := model.User{}
+	if m := slackMentionPattern.FindStringSubmatch(text); m != nil {
+		user.SlackID = m[1]
+		if err := user.ReadBySlackID(); err != nil {
+			return user, err
+		}
+	} else {
+		user.Name = text
+		if err := user.ReadByName(); err != nil {
+			return user, err
+		}
+	}
+	if user.ID == 0 {
+		return user, notFoundError("user not found")
+	}
+	return user, nil
+}
+
+// GrantAdmin はコマンド本文で指定したユーザーに管理者権限を付与する
+func GrantAdmin(text string) (model.User, error) {
+	user, err := findUserByCommandText(text)
+	if err != nil {
+		return user, err
+	}
+	if user.IsAdmin {
+		return user, errors.New("user is already an admin")
+	}
+	user.IsAdmin = true
+	if err := user.UpdateIsAdmin(); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// RevokeAdmin はコマンド本文で指定したユーザーの管理者権限を取り消す
+// SLACK_ADMIN_IDS で設定された管理者は取り消せない
+func RevokeAdmin(text string) (model.User, error) {
+	user, err := findUserByCommandText(text)
+	if err != nil {
+		return user, err
+	}
+	if isConfiguredAdmin(user.SlackID) {
+		return user, errors.New("configured admin cannot be revoked")
+	}
+	if !user.IsAdmin {
+		return user, errors.New("user is not an admin")
+	}
+	user.IsAdmin = false
+	if err := user.UpdateIsAdmin(); err != nil {
+		return user, err
+	}
+	return user, nil
+}
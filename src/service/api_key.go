@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// APIキーのスコープ
+const (
+	APIScopeRead      = "read"       // 参照系（GET）エンドポイント
+	APIScopeWriteLogs = "write-logs" // ログの登録
+	APIScopeAdmin     = "admin"      // すべての操作（他のスコープを含む）
+)
+
+// apiKeyPrefix は発行するAPIキーの接頭辞
+const apiKeyPrefix = "swk_"
+
+// apiKeyTouchInterval より短い間隔での使用では最終使用時刻を更新しない
+const apiKeyTouchInterval = time.Minute
+
+var (
+	// ErrAPIKeyInvalid はAPIキーが存在しない・失効している場合に返す
+	ErrAPIKeyInvalid = errors.New("invalid api key")
+	// ErrAPIKeyForbidden はAPIキーに必要なスコープがない場合に返す
+	ErrAPIKeyForbidden = errors.New("api key does not have the required scope")
+)
+
+// ValidAPIScopes は指定可能なスコープの一覧
+var ValidAPIScopes = []string{APIScopeRead, APIScopeWriteLogs, APIScopeAdmin}
+
+// IssueAPIKey はAPIキーを発行し、保存した記録と平文のキーを返す
+// scopes の各要素はカンマ区切りで複数のスコープを含んでもよい
+// 平文のキーは保存しないため、発行時にのみ取得できる
+func IssueAPIKey(name string, scopes []string, createdBy string) (model.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.APIKey{}, "", invalidInputError("api key name is required")
+	}
+	scopes = splitList(strings.Join(scopes, ","))
+	if len(scopes) == 0 {
+		return model.APIKey{}, "", invalidInputError("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !isValidAPIScope(scope) {
+			return model.APIKey{}, "", invalidInputError("invalid scope: %s", scope)
+		}
+	}
+
+	existing := model.APIKey{Name: name}
+	if err := existing.ReadActiveByName(); err != nil {
+		return model.APIKey{}, "", err
+	}
+	if existing.ID != 0 {
+		return existing, "", alreadyExistsError("api key already exists")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return model.APIKey{}, "", err
+	}
+	plain := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	key := model.APIKey{
+		Name:      name,
+		Prefix:    plain[:len(apiKeyPrefix)+6],
+		KeyHash:   hashAPIKey(plain),
+		Scopes:    strings.Join(scopes, ","),
+		CreatedBy: createdBy,
+	}
+	if err := key.Create(); err != nil {
+		return key, "", err
+	}
+	return key, plain, nil
+}
+
+// RevokeAPIKey は名前を指定して有効なAPIキーを失効させる
+func RevokeAPIKey(name string) (model.APIKey, error) {
+	key := model.APIKey{Name: strings.TrimSpace(name)}
+	if err := key.ReadActiveByName(); err != nil {
+		return key, err
+	}
+	if key.ID == 0 {
+		return key, notFoundError("api key not found")
+	}
+	if err := key.UpdateRevokedAt(lib.NowJST()); err != nil {
+		return key, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys は有効なAPIキーの一覧を取得する
+func ListAPIKeys() ([]model.APIKey, error) {
+	var k model.APIKey
+	return k.ReadAllActive()
+}
+
+// AuthenticateAPIKey は平文のAPIキーを検証し、必要なスコープを持つ場合にその記録を返す
+func AuthenticateAPIKey(plain string, requiredScope string) (model.APIKey, error) {
+	key := model.APIKey{KeyHash: hashAPIKey(plain)}
+	if err := key.ReadByKeyHash(); err != nil {
+		return key, err
+	}
+	if key.ID == 0 {
+		return key, ErrAPIKeyInvalid
+	}
+	if !HasAPIScope(key, requiredScope) {
+		return key, ErrAPIKeyForbidden
+	}
+
+	now := lib.NowJST()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := key.UpdateLastUsedAt(now); err != nil {
+			log.Printf("failed to update last_used_at for api key %s: %v", key.Name, err)
+		}
+	}
+	return key, nil
+}
+
+// HasAPIScope はAPIキーが指定したスコープを持つかを判定する。admin はすべてのスコープを含む
+func HasAPIScope(key model.APIKey, scope string) bool {
+	for _, s := range splitList(key.Scopes) {
+		if s == scope || s == APIScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidAPIScope は指定可能なスコープかを判定する
+func isValidAPIScope(scope string) bool {
+	for _, s := range ValidAPIScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAPIKey はAPIキーの SHA-256 を16進数で返す
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
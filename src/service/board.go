@@ -83,8 +83,8 @@ type boardPersonAssign struct {
 	departureMin int // -1 = 予測なし
 }
 
-// GetBoardData は共有モニター用の表示データを集約して返す
-func GetBoardData() (BoardData, error) {
+// GetBoardData は共有モニター用の表示データを集約して返す。活動確率は lookback の期間のログから計算する
+func GetBoardData(lookback Lookback) (BoardData, error) {
 	now := lib.NowJST()
 	weekday := now.Weekday()
 	nowMin := now.Hour()*60 + now.Minute()
@@ -97,7 +97,7 @@ func GetBoardData() (BoardData, error) {
 		return BoardData{}, err
 	}
 
-	activityProbs, err := GetAllActivityProbabilities(weekday)
+	activityProbs, err := GetAllActivityProbabilities(weekday, lookback, false)
 	if err != nil {
 		return BoardData{}, err
 	}
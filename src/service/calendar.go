@@ -0,0 +1,243 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"gorm.io/gorm"
+)
+
+// 学事暦の予定の種類
+const (
+	CalendarKindHoliday = "holiday" // 祝日
+	CalendarKindBreak   = "break"   // 長期休業（夏季休業など）
+	CalendarKindExam    = "exam"    // 試験期間
+	CalendarKindTerm    = "term"    // 授業期間（予測・通知から除外しない）
+)
+
+// maxCalendarTextLength calendar_entries の UID・Summary の最大長
+const maxCalendarTextLength = 255
+
+// CalendarImportIssue は取り込みに失敗した予定を表す
+type CalendarImportIssue struct {
+	Line    int    `json:"line"` // BEGIN:VEVENT の行番号
+	Message string `json:"message"`
+}
+
+// CalendarImportResult は学事暦の取り込み結果を表す
+type CalendarImportResult struct {
+	Imported int                   `json:"imported"` // 新たに登録した予定の数
+	Updated  int                   `json:"updated"`  // UID が一致する既存の予定を更新した数
+	Failed   int                   `json:"failed"`
+	Errors   []CalendarImportIssue `json:"errors"`
+}
+
+// CalendarDay は指定日の学事暦上の扱いを表す
+type CalendarDay struct {
+	Date     string                `json:"date"`
+	Excluded bool                  `json:"excluded"` // 祝日・休業・試験期間など、通常の曜日として扱わない日
+	Entries  []model.CalendarEntry `json:"entries"`
+}
+
+// IsCalendarKind は学事暦の予定の種類として有効かを判定する
+func IsCalendarKind(kind string) bool {
+	switch kind {
+	case CalendarKindHoliday, CalendarKindBreak, CalendarKindExam, CalendarKindTerm:
+		return true
+	}
+	return false
+}
+
+// isExcludedCalendarKind は予測・通知で通常の曜日として扱わない種類かを判定する
+func isExcludedCalendarKind(kind string) bool {
+	return kind != CalendarKindTerm
+}
+
+// ImportCalendar は iCalendar の予定を学事暦として取り込む
+// 種類は CATEGORIES に holiday / break / exam / term があればそれを使い、なければ defaultKind とする
+// UID が一致する予定は上書きするため、同じファイルを繰り返し取り込める
+func ImportCalendar(r io.Reader, defaultKind string) (CalendarImportResult, error) {
+	result := CalendarImportResult{Errors: []CalendarImportIssue{}}
+	if !IsCalendarKind(defaultKind) {
+		return result, fmt.Errorf("unknown calendar kind: %s", defaultKind)
+	}
+
+	reject := func(line int, err error) {
+		result.Failed++
+		result.Errors = append(result.Errors, CalendarImportIssue{Line: line, Message: err.Error()})
+	}
+
+	err := lib.ParseICalendar(r, func(e lib.ICalEvent, err error) {
+		if err != nil {
+			reject(e.Line, err)
+			return
+		}
+		if e.Recurring {
+			reject(e.Line, errors.New("recurring events (RRULE) are not supported"))
+			return
+		}
+
+		entry := model.CalendarEntry{UID: calendarUID(e)}
+		if len(entry.UID) > maxCalendarTextLength {
+			reject(e.Line, errors.New("UID is too long"))
+			return
+		}
+		if err := entry.ReadByUID(); err != nil {
+			reject(e.Line, err)
+			return
+		}
+		updating := entry.ID != 0 && !entry.DeletedAt.Valid
+
+		entry.Summary = truncateRunes(e.Summary, maxCalendarTextLength)
+		entry.Kind = calendarKind(e.Categories, defaultKind)
+		entry.StartDate = e.Start
+		entry.EndDate = e.End
+		entry.DeletedAt = gorm.DeletedAt{}
+		if entry.ID == 0 {
+			err = entry.Create()
+		} else {
+			err = entry.Update()
+		}
+		if err != nil {
+			reject(e.Line, err)
+			return
+		}
+		if updating {
+			result.Updated++
+		} else {
+			result.Imported++
+		}
+	})
+	// 除外する日が変わるため、当てはめたモデルを破棄する
+	if result.Imported+result.Updated > 0 {
+		invalidateModelCache()
+	}
+	return result, err
+}
+
+// calendarUID は予定の UID を返す。UID がない場合は開始日と件名から作る
+func calendarUID(e lib.ICalEvent) string {
+	if e.UID != "" {
+		return e.UID
+	}
+	return e.Start.Format("20060102") + "-" + e.Summary
+}
+
+// calendarKind は CATEGORIES から予定の種類を決める
+func calendarKind(categories []string, defaultKind string) string {
+	for _, c := range categories {
+		if kind := strings.ToLower(c); IsCalendarKind(kind) {
+			return kind
+		}
+	}
+	return defaultKind
+}
+
+// truncateRunes は文字列を先頭から max 文字までに切り詰める
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// GetCalendarEntries は期間が from〜until（日付、両端を含む）と重なる学事暦の予定を取得する
+func GetCalendarEntries(from, until time.Time) ([]model.CalendarEntry, error) {
+	return model.ReadCalendarEntriesBetween(from, until)
+}
+
+// DeleteCalendarEntry は学事暦の予定を削除する
+func DeleteCalendarEntry(id uint) error {
+	entry := model.CalendarEntry{}
+	entry.ID = id
+	if err := entry.ReadByID(); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFoundError("calendar entry not found")
+		}
+		return err
+	}
+	if err := entry.Delete(); err != nil {
+		return err
+	}
+	invalidateModelCache()
+	return nil
+}
+
+// GetCalendarDay は指定日に該当する学事暦の予定と、予測・通知から除外する日かどうかを返す
+func GetCalendarDay(date time.Time) (CalendarDay, error) {
+	date = truncateToDateJST(date)
+	day := CalendarDay{Date: date.Format("2006-01-02"), Entries: []model.CalendarEntry{}}
+
+	entries, err := model.ReadCalendarEntriesBetween(date, date)
+	if err != nil {
+		return day, err
+	}
+	day.Entries = entries
+	for _, e := range entries {
+		if isExcludedCalendarKind(e.Kind) {
+			day.Excluded = true
+		}
+	}
+	return day, nil
+}
+
+// excludedReason は除外する日の理由（予定の件名と種類）を返す
+func (d CalendarDay) excludedReason() string {
+	var names []string
+	for _, e := range d.Entries {
+		if isExcludedCalendarKind(e.Kind) {
+			names = append(names, fmt.Sprintf("%s (%s)", e.Summary, e.Kind))
+		}
+	}
+	return fmt.Sprintf("%s is excluded by the academic calendar: %s", d.Date, strings.Join(names, ", "))
+}
+
+// excludedCalendarDates は from〜until（日付、両端を含む）のうち予測から除外する日付（"2006-01-02"）の集合を返す
+func excludedCalendarDates(from, until time.Time) (map[string]bool, error) {
+	from = truncateToDateJST(from)
+	until = truncateToDateJST(until)
+	entries, err := model.ReadCalendarEntriesBetween(from, until)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]bool)
+	for _, e := range entries {
+		if !isExcludedCalendarKind(e.Kind) {
+			continue
+		}
+		start := truncateToDateJST(e.StartDate)
+		if start.Before(from) {
+			start = from
+		}
+		end := truncateToDateJST(e.EndDate)
+		if end.After(until) {
+			end = until
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			dates[d.Format("2006-01-02")] = true
+		}
+	}
+	return dates, nil
+}
+
+// excludeCalendarLogs は除外する日付のログを取り除く
+func excludeCalendarLogs(logs []model.Log, excluded map[string]bool) []model.Log {
+	if len(excluded) == 0 {
+		return logs
+	}
+	filtered := make([]model.Log, 0, len(logs))
+	for _, l := range logs {
+		if excluded[l.EventTime.In(lib.JST).Format("2006-01-02")] {
+			continue
+		}
+		filtered = append(filtered, l)
+	}
+	return filtered
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// サービス層のエラーの種類。コントローラーは errors.Is で判定してステータスコードを決める
+var (
+	ErrNotFound      = errors.New("not found")      // 対象が存在しない（404）
+	ErrAlreadyExists = errors.New("already exists") // 既存のものと重複する（409）
+	ErrInvalidInput  = errors.New("invalid input")  // 入力値が不正（400）
+)
+
+// 入力の形式は正しいが、参照先の外部のユーザーが見つからない場合のエラー（422）
+var (
+	// ErrUnknownStayWatchID はログの在室・参加メンバの stay_watch_id に一致するユーザーがいない場合に返す
+	ErrUnknownStayWatchID = errors.New("unknown stay_watch_id")
+	// ErrStayWatchUserNotFound はユーザー登録時に名前が一致する StayWatch のユーザーがいない場合に返す
+	ErrStayWatchUserNotFound = errors.New("staywatch user not found")
+)
+
+// kindError はメッセージを変えずに、エラーの種類（ErrNotFound など）を errors.Is で判定できるようにする
+type kindError struct {
+	kind    error
+	message string
+}
+
+func (e *kindError) Error() string { return e.message }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// notFoundError は ErrNotFound の種類のエラーを返す
+func notFoundError(format string, args ...any) error {
+	return &kindError{kind: ErrNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+// alreadyExistsError は ErrAlreadyExists の種類のエラーを返す
+func alreadyExistsError(format string, args ...any) error {
+	return &kindError{kind: ErrAlreadyExists, message: fmt.Sprintf(format, args...)}
+}
+
+// invalidInputError は ErrInvalidInput の種類のエラーを返す
+func invalidInputError(format string, args ...any) error {
+	return &kindError{kind: ErrInvalidInput, message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+)
+
+// 活動確率の重み付けの既定値
+const (
+	defaultHalfLifeWeeks = 8.0 // decay の半減期（週）
+	defaultWindowWeeks   = 15  // window の対象期間（週）。おおよそ1学期分
+)
+
+// estimatorSettings 活動確率の推定で過去のログをどう重み付けするか（起動時に環境変数から読み込む）
+var estimatorSettings = prediction.Estimator{Mode: prediction.EstimatorUniform}
+
+// loadEstimatorSettings は環境変数 PREDICTION_MODE / PREDICTION_HALF_LIFE_WEEKS / PREDICTION_WINDOW_WEEKS から
+// 重み付け方式を読み込む。不正な値の場合は uniform とする
+func loadEstimatorSettings() prediction.Estimator {
+	estimator := prediction.Estimator{
+		Mode:          getEnv("PREDICTION_MODE", prediction.EstimatorUniform),
+		HalfLifeWeeks: defaultHalfLifeWeeks,
+		WindowWeeks:   defaultWindowWeeks,
+	}
+	if s := getEnv("PREDICTION_HALF_LIFE_WEEKS", ""); s != "" {
+		halfLife, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			log.Printf("invalid PREDICTION_HALF_LIFE_WEEKS %q, using %v", s, defaultHalfLifeWeeks)
+		} else {
+			estimator.HalfLifeWeeks = halfLife
+		}
+	}
+	if s := getEnv("PREDICTION_WINDOW_WEEKS", ""); s != "" {
+		window, err := strconv.Atoi(s)
+		if err != nil {
+			log.Printf("invalid PREDICTION_WINDOW_WEEKS %q, using %d", s, defaultWindowWeeks)
+		} else {
+			estimator.WindowWeeks = window
+		}
+	}
+	if err := estimator.Validate(); err != nil {
+		log.Printf("invalid prediction settings (%v), falling back to %s", err, prediction.EstimatorUniform)
+		return prediction.Estimator{Mode: prediction.EstimatorUniform}
+	}
+
+	// 使わない設定値はAPIのレスポンスに含めない
+	switch estimator.Mode {
+	case prediction.EstimatorDecay:
+		estimator.WindowWeeks = 0
+	case prediction.EstimatorWindow:
+		estimator.HalfLifeWeeks = 0
+	default:
+		estimator.HalfLifeWeeks = 0
+		estimator.WindowWeeks = 0
+	}
+	return estimator
+}
+
+// CurrentEstimator は現在時刻を基準とした活動確率の重み付け方式を返す
+func CurrentEstimator() prediction.Estimator {
+	estimator := estimatorSettings
+	estimator.Now = lib.NowJST()
+	return estimator
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+)
+
+// 予測の評価（バックテスト）の設定
+const (
+	MaxEvaluationDays     = 180 // 1回の評価で対象とする最大日数
+	DefaultEvaluationDays = 56  // 期間を省略した場合の日数（8週間）
+	calibrationBins       = 10  // 較正曲線の区間数（0.1 刻み）
+
+	logLossEpsilon = 1e-6 // log loss を求める際に確率を [ε, 1-ε] に丸める（0 や 1 の予測で無限大にならないようにする）
+)
+
+// evaluationThresholds 通知の閾値の候補。閾値ごとの適合率・再現率を求める
+var evaluationThresholds = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// EvaluationReport は過去のログを1日ずつ再現した活動予測の評価結果を表す
+type EvaluationReport struct {
+	From                 string            `json:"from"`                  // 評価した期間の開始日（JST）
+	To                   string            `json:"to"`                    // 評価した期間の終了日（JST）
+	ProbabilityTime      string            `json:"probability_time"`      // 活動確率を評価する時刻（通知と同じ）
+	ProbabilityThreshold float64           `json:"probability_threshold"` // 現在の通知の閾値
+	Overall              EvaluationSummary `json:"overall"`               // 全イベントを合わせた評価
+	Events               []EventEvaluation `json:"events"`
+}
+
+// EventEvaluation はイベント1件分の評価結果を表す
+type EventEvaluation struct {
+	EventID         uint   `json:"event_id"`
+	EventName       string `json:"event_name"`
+	TimeModel       string `json:"time_model"`
+	PredictionModel string `json:"prediction_model"`
+	EvaluationSummary
+}
+
+// EvaluationSummary は活動確率と活動予測時刻範囲の評価指標を表す
+type EvaluationSummary struct {
+	Days                int                   `json:"days"`                  // 評価した日数
+	SkippedDays         int                   `json:"skipped_days"`          // 学事暦で除外された日・最初のログより前の日
+	Occurrences         int                   `json:"occurrences"`           // probability_time までに活動が始まった日数
+	BaseRate            float64               `json:"base_rate"`             // occurrences / days
+	BrierScore          float64               `json:"brier_score"`           // (確率 - 実績)^2 の平均。小さいほど良い
+	ReferenceBrierScore float64               `json:"reference_brier_score"` // 常に base_rate を予測した場合の Brier スコア
+	BrierSkillScore     float64               `json:"brier_skill_score"`     // 1 - brier_score / reference_brier_score。正なら base_rate より良い
+	LogLoss             float64               `json:"log_loss"`              // -(実績×log(確率) + (1-実績)×log(1-確率)) の平均。小さいほど良い
+	Calibration         []CalibrationBin      `json:"calibration"`
+	Thresholds          []ThresholdEvaluation `json:"thresholds"`
+	RangeSamples        int                   `json:"range_samples"`  // 活動予測時刻範囲を評価した日数（活動が始まった日）
+	RangeHits           int                   `json:"range_hits"`     // 実際の開始時刻が範囲に含まれた日数
+	RangeHitRate        float64               `json:"range_hit_rate"` // range_hits / range_samples
+
+	brierSum   float64
+	logLossSum float64
+}
+
+// CalibrationBin は較正曲線の1区間を表す。予測した確率が正しければ MeanPredicted と ObservedRate が一致する
+type CalibrationBin struct {
+	Lower         float64 `json:"lower"`
+	Upper         float64 `json:"upper"`
+	Count         int     `json:"count"`
+	MeanPredicted float64 `json:"mean_predicted"`
+	ObservedRate  float64 `json:"observed_rate"`
+
+	predictedSum float64
+	occurrences  int
+}
+
+// ThresholdEvaluation は活動確率が Threshold 以上の日に通知した場合の評価を表す
+type ThresholdEvaluation struct {
+	Threshold float64 `json:"threshold"`
+	Notified  int     `json:"notified"`  // 通知した日数
+	Hits      int     `json:"hits"`      // 通知した日のうち活動が始まった日数
+	Precision float64 `json:"precision"` // hits / notified
+	Recall    float64 `json:"recall"`    // hits / occurrences
+}
+
+// dayPrediction は1日分の予測と実績を表す
+type dayPrediction struct {
+	probability float64
+	occurred    bool
+	hasRange    bool
+	rangeHit    bool
+}
+
+// EvaluatePredictions は from〜to（JST、両端を含む）の各日について、その日より前のログだけで活動確率と活動予測時刻範囲を求め、
+// 実際の start ログと比べて評価する。eventID が 0 の場合は全イベントを評価する
+// predictionModel を指定した場合は、イベントの設定の代わりにそのモデルで評価する（モデルの比較に使う）
+// 確率の重み付けと lookback は現在の設定を使い、学事暦で除外された日は評価しない
+// 推奨時間帯のうち StayWatch の来訪予測（メンバーの在室時間帯）は過去の時点を再現できないため、活動予測時刻範囲のみを評価する
+func EvaluatePredictions(from, to time.Time, eventID uint, lookback Lookback, predictionModel string) (EvaluationReport, error) {
+	if predictionModel != "" && !prediction.IsModelKind(predictionModel) {
+		return EvaluationReport{}, invalidInputError("prediction model must be gmm, kde or histogram")
+	}
+	from, to = truncateToDateJST(from), truncateToDateJST(to)
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days < 1 || days > MaxEvaluationDays {
+		return EvaluationReport{}, invalidInputError("evaluation period must be between 1 and %d days", MaxEvaluationDays)
+	}
+
+	var events []model.Event
+	if eventID != 0 {
+		event, err := GetEvent(eventID)
+		if err != nil {
+			return EvaluationReport{}, err
+		}
+		events = []model.Event{event}
+	} else {
+		var e model.Event
+		all, err := e.ReadAll()
+		if err != nil {
+			return EvaluationReport{}, fmt.Errorf("failed to read events: %w", err)
+		}
+		events = all
+	}
+
+	report := EvaluationReport{
+		From:                 from.Format("2006-01-02"),
+		To:                   to.Format("2006-01-02"),
+		ProbabilityTime:      activityProbabilityTime,
+		ProbabilityThreshold: activityProbabilityThreshold,
+		Events:               make([]EventEvaluation, 0, len(events)),
+	}
+	var overall []dayPrediction
+	overallSkipped := 0
+	for _, event := range events {
+		if predictionModel != "" {
+			event.PredictionModel = predictionModel
+		}
+		predictions, skipped, err := backtestEvent(event, from, to, lookback)
+		if err != nil {
+			return report, fmt.Errorf("failed to evaluate event %d: %w", event.ID, err)
+		}
+		report.Events = append(report.Events, EventEvaluation{
+			EventID:           event.ID,
+			EventName:         event.Name,
+			TimeModel:         event.TimeModel,
+			PredictionModel:   event.PredictionModel,
+			EvaluationSummary: summarizePredictions(predictions, skipped),
+		})
+		overall = append(overall, predictions...)
+		overallSkipped += skipped
+	}
+	report.Overall = summarizePredictions(overall, overallSkipped)
+	return report, nil
+}
+
+// backtestEvent はイベントの過去のログを1日ずつ再現し、各日の予測と実績を返す
+// 対象日の0時を予測時刻とし、それより前のログのみで当てはめる（キャッシュは使わない）
+// 実績はモデルによらず、対象日の最初の start ログが 0:00〜probability_time の間にあったかとする
+func backtestEvent(event model.Event, from, to time.Time, lookback Lookback) ([]dayPrediction, int, error) {
+	query := model.Log{EventID: event.ID}
+	logs, err := query.ReadByEventID()
+	if err != nil {
+		return nil, 0, err
+	}
+	logs = sortLogsByEventTime(logs)
+
+	var first time.Time
+	if len(logs) > 0 {
+		first = logs[0].EventTime
+	}
+	var excluded map[string]bool
+	if !first.IsZero() {
+		if excluded, err = excludedCalendarDates(first, to); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	probabilityMinutes, err := lib.TimeToMinutes(activityProbabilityTime)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var predictions []dayPrediction
+	skipped := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if first.IsZero() || !first.Before(day) || excluded[day.Format("2006-01-02")] {
+			skipped++
+			continue
+		}
+		estimator := CurrentEstimator()
+		estimator.Now = day
+		p, err := backtestDay(event, logs, day, first, lookback, excluded, estimator, probabilityMinutes)
+		if err != nil {
+			return nil, 0, err
+		}
+		predictions = append(predictions, p)
+	}
+	return predictions, skipped, nil
+}
+
+// backtestDay は day（JST の0時）より前のログで予測した活動確率と活動予測時刻範囲を、day の実績と比べる
+// logs は EventTime の昇順で、estimator.Now は day とする
+func backtestDay(event model.Event, logs []model.Log, day, first time.Time, lookback Lookback, excluded map[string]bool, estimator prediction.Estimator, probabilityMinutes int) (dayPrediction, error) {
+	train, ages := backtestTrainingLogs(logs, day, first, lookback, excluded)
+	m, err := prediction.FitDatetimes(extractStartDatetimes(train), ages, estimator, true, eventModelSpec(event))
+	if err != nil {
+		return dayPrediction{}, err
+	}
+
+	start, started := firstStartMinutes(logs, day)
+	p := dayPrediction{
+		probability: math.Min(math.Max(m.CDF(float64(probabilityMinutes)), 0), 1),
+		occurred:    started && start <= probabilityMinutes,
+	}
+	if !started || len(train) == 0 {
+		return p, nil
+	}
+	r, err := activityTimeRangeFromLogs(event, train, ages, day)
+	if err != nil {
+		return p, nil
+	}
+	if _, err := r.minuteSegments(); err != nil {
+		return p, nil
+	}
+	p.hasRange = true
+	p.rangeHit = r.contains(start)
+	return p, nil
+}
+
+// backtestTrainingLogs は day の予測に使うログ（day より前の同じ曜日のログ）と確率の分母となる観測機会の週を返す
+// 期間と週の数え方は readPredictionLogs と同じで、現在時刻の代わりに day を基準とする
+func backtestTrainingLogs(logs []model.Log, day, first time.Time, lookback Lookback, excluded map[string]bool) ([]model.Log, []int) {
+	start := lookback.from(day)
+	var train []model.Log
+	for _, l := range logs {
+		t := l.EventTime.In(lib.JST)
+		if !t.Before(day) {
+			break
+		}
+		if t.Weekday() != day.Weekday() || t.Before(start) || excluded[t.Format("2006-01-02")] {
+			continue
+		}
+		train = append(train, l)
+	}
+	if len(train) == 0 {
+		return train, nil
+	}
+
+	if start.IsZero() {
+		start = train[0].EventTime
+	} else if first.After(start) {
+		start = first
+	}
+	return train, calculateWeekAgesAt(start, day, day.Weekday(), excluded)
+}
+
+// firstStartMinutes は day（JST）の最初の start ログの時刻（0時からの分）を返す
+func firstStartMinutes(logs []model.Log, day time.Time) (int, bool) {
+	next := day.AddDate(0, 0, 1)
+	for _, l := range logs {
+		t := l.EventTime.In(lib.JST)
+		if t.Before(day) || l.Status.Name != "start" {
+			continue
+		}
+		if !t.Before(next) {
+			break
+		}
+		return t.Hour()*60 + t.Minute(), true
+	}
+	return 0, false
+}
+
+// summarizePredictions は各日の予測と実績から評価指標を求める
+func summarizePredictions(predictions []dayPrediction, skipped int) EvaluationSummary {
+	s := EvaluationSummary{
+		Days:        len(predictions),
+		SkippedDays: skipped,
+		Calibration: make([]CalibrationBin, calibrationBins),
+		Thresholds:  make([]ThresholdEvaluation, len(evaluationThresholds)),
+	}
+	for i := range s.Calibration {
+		s.Calibration[i].Lower = float64(i) / calibrationBins
+		s.Calibration[i].Upper = float64(i+1) / calibrationBins
+	}
+	for i, th := range evaluationThresholds {
+		s.Thresholds[i].Threshold = th
+	}
+
+	for _, p := range predictions {
+		outcome := 0.0
+		if p.occurred {
+			outcome = 1
+			s.Occurrences++
+		}
+		s.brierSum += (p.probability - outcome) * (p.probability - outcome)
+		s.logLossSum += logLoss(p.probability, p.occurred)
+
+		bin := &s.Calibration[calibrationBin(p.probability)]
+		bin.Count++
+		bin.predictedSum += p.probability
+		if p.occurred {
+			bin.occurrences++
+		}
+
+		for i := range s.Thresholds {
+			if p.probability < s.Thresholds[i].Threshold {
+				continue
+			}
+			s.Thresholds[i].Notified++
+			if p.occurred {
+				s.Thresholds[i].Hits++
+			}
+		}
+
+		if p.hasRange {
+			s.RangeSamples++
+			if p.rangeHit {
+				s.RangeHits++
+			}
+		}
+	}
+
+	if s.Days > 0 {
+		s.BaseRate = float64(s.Occurrences) / float64(s.Days)
+		s.BrierScore = s.brierSum / float64(s.Days)
+		s.LogLoss = s.logLossSum / float64(s.Days)
+		s.ReferenceBrierScore = s.BaseRate * (1 - s.BaseRate)
+		if s.ReferenceBrierScore > 0 {
+			s.BrierSkillScore = 1 - s.BrierScore/s.ReferenceBrierScore
+		}
+	}
+	for i := range s.Calibration {
+		bin := &s.Calibration[i]
+		if bin.Count > 0 {
+			bin.MeanPredicted = bin.predictedSum / float64(bin.Count)
+			bin.ObservedRate = float64(bin.occurrences) / float64(bin.Count)
+		}
+	}
+	for i := range s.Thresholds {
+		th := &s.Thresholds[i]
+		if th.Notified > 0 {
+			th.Precision = float64(th.Hits) / float64(th.Notified)
+		}
+		if s.Occurrences > 0 {
+			th.Recall = float64(th.Hits) / float64(s.Occurrences)
+		}
+	}
+	if s.RangeSamples > 0 {
+		s.RangeHitRate = float64(s.RangeHits) / float64(s.RangeSamples)
+	}
+	return s
+}
+
+// logLoss は確率 probability を予測した日の log loss を返す
+func logLoss(probability float64, occurred bool) float64 {
+	p := math.Min(math.Max(probability, logLossEpsilon), 1-logLossEpsilon)
+	if occurred {
+		return -math.Log(p)
+	}
+	return -math.Log(1 - p)
+}
+
+// calibrationBin は確率が属する較正曲線の区間の番号を返す（1.0 は最後の区間に含める）
+func calibrationBin(probability float64) int {
+	i := int(probability * calibrationBins)
+	if i >= calibrationBins {
+		return calibrationBins - 1
+	}
+	if i < 0 {
+		return 0
+	}
+	return i
+}
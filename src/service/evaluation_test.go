@@ -0,0 +1,302 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+)
+
+const metricTolerance = 1e-9
+
+func TestSummarizePredictions(t *testing.T) {
+	tests := []struct {
+		name        string
+		predictions []dayPrediction
+		want        EvaluationSummary
+	}{
+		{
+			name: "no days",
+			want: EvaluationSummary{SkippedDays: 3},
+		},
+		{
+			// Brier = (0.2² + 0.2² + 0.6² + 1²) / 4 = 1.44 / 4
+			// log loss = (-ln 0.8 - ln 0.8 - ln 0.4 - ln 10⁻⁶) / 4（確率 0 は 10⁻⁶ に丸める）
+			name: "mixed outcomes",
+			predictions: []dayPrediction{
+				{probability: 0.8, occurred: true, hasRange: true, rangeHit: true},
+				{probability: 0.2},
+				{probability: 0.6},
+				{probability: 0, occurred: true, hasRange: true},
+			},
+			want: EvaluationSummary{
+				Days: 4, SkippedDays: 3, Occurrences: 2, BaseRate: 0.5,
+				BrierScore: 0.36, ReferenceBrierScore: 0.25, BrierSkillScore: 1 - 0.36/0.25,
+				LogLoss:      (-2*math.Log(0.8) - math.Log(0.4) - math.Log(1e-6)) / 4,
+				RangeSamples: 2, RangeHits: 1, RangeHitRate: 0.5,
+			},
+		},
+		{
+			name: "perfect predictions",
+			predictions: []dayPrediction{
+				{probability: 1, occurred: true},
+				{probability: 0},
+			},
+			want: EvaluationSummary{
+				Days: 2, SkippedDays: 3, Occurrences: 1, BaseRate: 0.5,
+				BrierScore: 0, ReferenceBrierScore: 0.25, BrierSkillScore: 1,
+				LogLoss: -math.Log(1 - 1e-6),
+			},
+		},
+		{
+			// 毎日活動した場合は base_rate を予測すれば誤差がないため、スキルスコアは求めない
+			name: "every day occurred",
+			predictions: []dayPrediction{
+				{probability: 0.5, occurred: true},
+				{probability: 0.5, occurred: true},
+			},
+			want: EvaluationSummary{
+				Days: 2, SkippedDays: 3, Occurrences: 2, BaseRate: 1,
+				BrierScore: 0.25, ReferenceBrierScore: 0, BrierSkillScore: 0,
+				LogLoss: math.Log(2),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizePredictions(tt.predictions, 3)
+			if got.Days != tt.want.Days || got.SkippedDays != tt.want.SkippedDays || got.Occurrences != tt.want.Occurrences ||
+				got.RangeSamples != tt.want.RangeSamples || got.RangeHits != tt.want.RangeHits {
+				t.Errorf("summarizePredictions() counts = %+v, want %+v", got, tt.want)
+			}
+			metrics := []struct {
+				name      string
+				got, want float64
+			}{
+				{"BaseRate", got.BaseRate, tt.want.BaseRate},
+				{"BrierScore", got.BrierScore, tt.want.BrierScore},
+				{"ReferenceBrierScore", got.ReferenceBrierScore, tt.want.ReferenceBrierScore},
+				{"BrierSkillScore", got.BrierSkillScore, tt.want.BrierSkillScore},
+				{"LogLoss", got.LogLoss, tt.want.LogLoss},
+				{"RangeHitRate", got.RangeHitRate, tt.want.RangeHitRate},
+			}
+			for _, m := range metrics {
+				if math.Abs(m.got-m.want) > metricTolerance {
+					t.Errorf("%s = %v, want %v", m.name, m.got, m.want)
+				}
+			}
+			if len(got.Calibration) != calibrationBins || len(got.Thresholds) != len(evaluationThresholds) {
+				t.Errorf("got %d calibration bins and %d thresholds", len(got.Calibration), len(got.Thresholds))
+			}
+		})
+	}
+}
+
+func TestSummarizePredictionsCalibration(t *testing.T) {
+	predictions := []dayPrediction{
+		{probability: 0.8, occurred: true},
+		{probability: 0.85},
+		{probability: 0.2},
+		{probability: 0, occurred: true},
+		{probability: 1, occurred: true},
+	}
+	// 区間は下端を含み上端を含まない（1.0 のみ最後の区間に含める）
+	want := map[int]CalibrationBin{
+		0: {Count: 1, MeanPredicted: 0, ObservedRate: 1},
+		2: {Count: 1, MeanPredicted: 0.2, ObservedRate: 0},
+		8: {Count: 2, MeanPredicted: 0.825, ObservedRate: 0.5},
+		9: {Count: 1, MeanPredicted: 1, ObservedRate: 1},
+	}
+
+	got := summarizePredictions(predictions, 0).Calibration
+	for i, bin := range got {
+		if math.Abs(bin.Lower-float64(i)/10) > metricTolerance || math.Abs(bin.Upper-float64(i+1)/10) > metricTolerance {
+			t.Errorf("bin %d = [%v, %v), want [%v, %v)", i, bin.Lower, bin.Upper, float64(i)/10, float64(i+1)/10)
+		}
+		w := want[i]
+		if bin.Count != w.Count || math.Abs(bin.MeanPredicted-w.MeanPredicted) > metricTolerance ||
+			math.Abs(bin.ObservedRate-w.ObservedRate) > metricTolerance {
+			t.Errorf("bin %d = count %d, mean %v, observed %v, want count %d, mean %v, observed %v",
+				i, bin.Count, bin.MeanPredicted, bin.ObservedRate, w.Count, w.MeanPredicted, w.ObservedRate)
+		}
+	}
+}
+
+func TestSummarizePredictionsThresholds(t *testing.T) {
+	predictions := []dayPrediction{
+		{probability: 0.8, occurred: true},
+		{probability: 0.2},
+		{probability: 0.6},
+		{probability: 0, occurred: true},
+	}
+	// 確率が閾値と等しい日も通知する
+	want := []ThresholdEvaluation{
+		{Threshold: 0.1, Notified: 3, Hits: 1, Precision: 1.0 / 3, Recall: 0.5},
+		{Threshold: 0.2, Notified: 3, Hits: 1, Precision: 1.0 / 3, Recall: 0.5},
+		{Threshold: 0.3, Notified: 2, Hits: 1, Precision: 0.5, Recall: 0.5},
+		{Threshold: 0.4, Notified: 2, Hits: 1, Precision: 0.5, Recall: 0.5},
+		{Threshold: 0.5, Notified: 2, Hits: 1, Precision: 0.5, Recall: 0.5},
+		{Threshold: 0.6, Notified: 2, Hits: 1, Precision: 0.5, Recall: 0.5},
+		{Threshold: 0.7, Notified: 1, Hits: 1, Precision: 1, Recall: 0.5},
+		{Threshold: 0.8, Notified: 1, Hits: 1, Precision: 1, Recall: 0.5},
+		{Threshold: 0.9, Notified: 0, Hits: 0, Precision: 0, Recall: 0},
+	}
+
+	got := summarizePredictions(predictions, 0).Thresholds
+	if len(got) != len(want) {
+		t.Fatalf("got %d thresholds, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Threshold != w.Threshold || g.Notified != w.Notified || g.Hits != w.Hits ||
+			math.Abs(g.Precision-w.Precision) > metricTolerance || math.Abs(g.Recall-w.Recall) > metricTolerance {
+			t.Errorf("threshold %v = %+v, want %+v", w.Threshold, g, w)
+		}
+	}
+}
+
+func TestCalibrationBin(t *testing.T) {
+	tests := []struct {
+		probability float64
+		want        int
+	}{
+		{probability: -0.1, want: 0},
+		{probability: 0, want: 0},
+		{probability: 0.09, want: 0},
+		{probability: 0.1, want: 1},
+		{probability: 0.55, want: 5},
+		{probability: 0.99, want: 9},
+		{probability: 1, want: 9},
+		{probability: 1.2, want: 9},
+	}
+
+	for _, tt := range tests {
+		if got := calibrationBin(tt.probability); got != tt.want {
+			t.Errorf("calibrationBin(%v) = %d, want %d", tt.probability, got, tt.want)
+		}
+	}
+}
+
+func TestLogLoss(t *testing.T) {
+	tests := []struct {
+		name        string
+		probability float64
+		occurred    bool
+		want        float64
+	}{
+		{name: "occurred", probability: 0.8, occurred: true, want: -math.Log(0.8)},
+		{name: "did not occur", probability: 0.8, want: -math.Log(0.2)},
+		{name: "even odds", probability: 0.5, occurred: true, want: math.Log(2)},
+		{name: "certain and correct", probability: 1, occurred: true, want: -math.Log(1 - logLossEpsilon)},
+		{name: "certain and wrong", probability: 0, occurred: true, want: -math.Log(logLossEpsilon)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logLoss(tt.probability, tt.occurred)
+			if math.IsInf(got, 0) || math.Abs(got-tt.want) > metricTolerance {
+				t.Errorf("logLoss(%v, %v) = %v, want %v", tt.probability, tt.occurred, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstStartMinutes(t *testing.T) {
+	day := time.Date(2025, 6, 13, 0, 0, 0, 0, lib.JST)
+	logs := []model.Log{
+		testLog(1, statusNameStart, day.Add(-time.Hour)),
+		testLog(2, statusNameEnd, day.Add(30*time.Minute)),
+		testLog(3, statusNameStart, day.Add(10*time.Hour+15*time.Minute)),
+		testLog(4, statusNameStart, day.Add(14*time.Hour)),
+		testLog(5, statusNameStart, day.Add(24*time.Hour+time.Minute)),
+	}
+
+	tests := []struct {
+		name   string
+		day    time.Time
+		want   int
+		wantOK bool
+	}{
+		// 前日から続く活動の end は数えず、その日の最初の start の時刻とする
+		{name: "first start of the day", day: day, want: 615, wantOK: true},
+		{name: "next day", day: day.AddDate(0, 0, 1), want: 1, wantOK: true},
+		{name: "no start", day: day.AddDate(0, 0, 2), want: 0, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := firstStartMinutes(logs, tt.day)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("firstStartMinutes() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBacktestDay(t *testing.T) {
+	day := time.Date(2025, 6, 13, 0, 0, 0, 0, lib.JST)
+	// 過去3週の同じ曜日に 10:00〜11:00 の活動があった
+	history := weeklySessionLogs(day, 10, 0, []int{60, 60, 60})
+	first := history[0].EventTime
+	estimator := prediction.Estimator{Mode: prediction.EstimatorUniform, Now: day}
+	const probabilityMinutes = 17*60 + 59
+
+	tests := []struct {
+		name         string
+		logs         []model.Log
+		wantOccurred bool
+		wantHasRange bool
+		wantRangeHit bool
+	}{
+		{
+			name:         "started within the range",
+			logs:         append(append([]model.Log{}, history...), testLog(100, statusNameStart, day.Add(10*time.Hour+30*time.Minute))),
+			wantOccurred: true, wantHasRange: true, wantRangeHit: true,
+		},
+		{
+			name:         "started outside the range",
+			logs:         append(append([]model.Log{}, history...), testLog(100, statusNameStart, day.Add(15*time.Hour))),
+			wantOccurred: true, wantHasRange: true, wantRangeHit: false,
+		},
+		{
+			// probability_time より後に始まった日は活動しなかった日とするが、範囲の評価には含める
+			name:         "started after the probability time",
+			logs:         append(append([]model.Log{}, history...), testLog(100, statusNameStart, day.Add(19*time.Hour))),
+			wantOccurred: false, wantHasRange: true, wantRangeHit: false,
+		},
+		{
+			name:         "did not start",
+			logs:         history,
+			wantOccurred: false, wantHasRange: false,
+		},
+	}
+
+	want, err := backtestDay(model.Event{}, history, day, first, Lookback{}, nil, estimator, probabilityMinutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3回 / 3週の活動は 17:59 までに必ず始まるとみなせる
+	if want.probability < 0.99 || want.probability > 1 {
+		t.Errorf("probability = %v, want about 1", want.probability)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backtestDay(model.Event{}, tt.logs, day, first, Lookback{}, nil, estimator, probabilityMinutes)
+			if err != nil {
+				t.Fatalf("backtestDay() error = %v", err)
+			}
+			// 当日以降のログは予測に使わない
+			if got.probability != want.probability {
+				t.Errorf("probability = %v, want %v regardless of the day's logs", got.probability, want.probability)
+			}
+			if got.occurred != tt.wantOccurred || got.hasRange != tt.wantHasRange || got.rangeHit != tt.wantRangeHit {
+				t.Errorf("backtestDay() = %+v, want occurred %v, hasRange %v, rangeHit %v",
+					got, tt.wantOccurred, tt.wantHasRange, tt.wantRangeHit)
+			}
+		})
+	}
+}
@@ -5,20 +5,39 @@ import (
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+	"gorm.io/gorm"
 )
 
-func RegisterEvent(name string, minNumber int, code string) (model.Event, error) {
+// ErrEventNotFound は指定したイベントが存在しない場合に返す
+var ErrEventNotFound = notFoundError("event not found")
+
+// RegisterEvent はイベントを登録する
+// timeModel（活動開始時刻の時間軸の扱い）が空の場合は linear、predictionModel（分布のモデル）が空の場合は gmm とする
+func RegisterEvent(name string, minNumber int, code string, timeModel string, predictionModel string) (model.Event, error) {
+	if timeModel == "" {
+		timeModel = prediction.TimeModelLinear
+	}
+	if !prediction.IsTimeModel(timeModel) {
+		return model.Event{}, invalidInputError("time model must be linear or circular")
+	}
+	if predictionModel == "" {
+		predictionModel = prediction.ModelGMM
+	}
+	if !prediction.IsModelKind(predictionModel) {
+		return model.Event{}, invalidInputError("prediction model must be gmm, kde or histogram")
+	}
 	event := model.Event{
-		Name:      name,
-		MinNumber: minNumber,
-		Code:      code,
+		Name:            name,
+		MinNumber:       minNumber,
+		Code:            code,
+		TimeModel:       timeModel,
+		PredictionModel: predictionModel,
 	}
 
 	if err := event.Create(); err != nil {
-		// MySQLのユニーク制約エラー（1062）を型安全に判定
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-			return event, errors.New("event already exists")
+		if isDuplicateEntry(err) {
+			return event, alreadyExistsError("event already exists")
 		}
 		return event, err
 	}
@@ -33,3 +52,77 @@ func GetEvents() ([]model.Event, error) {
 	}
 	return events, nil
 }
+
+// EventUpdateInput はREST APIからのイベント更新内容を表す（nilの項目は変更しない）
+type EventUpdateInput struct {
+	Name            *string
+	Code            *string
+	MinNumber       *int
+	TimeModel       *string
+	PredictionModel *string
+}
+
+// GetEvent はIDからイベントを取得する
+func GetEvent(id uint) (model.Event, error) {
+	event := model.Event{}
+	event.ID = id
+	if err := event.ReadByID(); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return event, ErrEventNotFound
+		}
+		return event, err
+	}
+	return event, nil
+}
+
+// UpdateEvent はイベントの名前・コード・最低必要人数・活動開始時刻のモデルを更新する
+func UpdateEvent(id uint, input EventUpdateInput) (model.Event, error) {
+	if input.TimeModel != nil && !prediction.IsTimeModel(*input.TimeModel) {
+		return model.Event{}, invalidInputError("time model must be linear or circular")
+	}
+	if input.PredictionModel != nil && !prediction.IsModelKind(*input.PredictionModel) {
+		return model.Event{}, invalidInputError("prediction model must be gmm, kde or histogram")
+	}
+	event, err := GetEvent(id)
+	if err != nil {
+		return event, err
+	}
+	if input.Name != nil {
+		event.Name = *input.Name
+	}
+	if input.Code != nil {
+		event.Code = *input.Code
+	}
+	if input.MinNumber != nil {
+		event.MinNumber = *input.MinNumber
+	}
+	if input.TimeModel != nil {
+		event.TimeModel = *input.TimeModel
+	}
+	if input.PredictionModel != nil {
+		event.PredictionModel = *input.PredictionModel
+	}
+
+	if err := event.Update(); err != nil {
+		if isDuplicateEntry(err) {
+			return event, alreadyExistsError("event already exists")
+		}
+		return event, err
+	}
+	return event, nil
+}
+
+// DeleteEvent はIDを指定してイベントを削除する
+func DeleteEvent(id uint) error {
+	event, err := GetEvent(id)
+	if err != nil {
+		return err
+	}
+	return event.Delete()
+}
+
+// isDuplicateEntry はMySQLのユニーク制約エラー（1062）かを型安全に判定する
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
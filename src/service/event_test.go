@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEventValidationErrors(t *testing.T) {
+	invalid := "weekly"
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantMsg string
+	}{
+		{
+			name: "register with unknown time model",
+			call: func() error {
+				_, err := RegisterEvent("スマブラ", 2, "1", invalid, "")
+				return err
+			},
+			wantMsg: "time model must be linear or circular",
+		},
+		{
+			name: "register with unknown prediction model",
+			call: func() error {
+				_, err := RegisterEvent("スマブラ", 2, "1", "", invalid)
+				return err
+			},
+			wantMsg: "prediction model must be gmm, kde or histogram",
+		},
+		{
+			name: "update with unknown time model",
+			call: func() error {
+				_, err := UpdateEvent(1, EventUpdateInput{TimeModel: &invalid})
+				return err
+			},
+			wantMsg: "time model must be linear or circular",
+		},
+		{
+			name: "update with unknown prediction model",
+			call: func() error {
+				_, err := UpdateEvent(1, EventUpdateInput{PredictionModel: &invalid})
+				return err
+			},
+			wantMsg: "prediction model must be gmm, kde or histogram",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 入力の検証は DB にアクセスする前に行う
+			err := tt.call()
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("error = %v, want ErrInvalidInput", err)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("error message = %q, want %q", err.Error(), tt.wantMsg)
+			}
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrAlreadyExists) {
+				t.Errorf("error %v has more than one kind", err)
+			}
+		})
+	}
+}
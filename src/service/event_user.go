@@ -31,3 +31,71 @@ func RegisterEventUser(eventName string, slackUserID string) (model.EventUser, e
 
 	return eventUser, nil
 }
+
+// GetEventMembers はイベントに登録されているユーザーの一覧を取得する
+func GetEventMembers(eventID uint) ([]model.User, error) {
+	if _, err := GetEvent(eventID); err != nil {
+		return nil, err
+	}
+
+	eu := model.EventUser{EventID: eventID}
+	eventUsers, err := eu.ReadByEventID()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]model.User, 0, len(eventUsers))
+	for _, eventUser := range eventUsers {
+		// 削除済みユーザーは Preload で読み込まれずゼロ値になる
+		if eventUser.User.ID == 0 {
+			continue
+		}
+		users = append(users, eventUser.User)
+	}
+	return users, nil
+}
+
+// AddEventMember はイベントにユーザーを登録する
+func AddEventMember(eventID uint, userID uint) (model.EventUser, error) {
+	event, err := GetEvent(eventID)
+	if err != nil {
+		return model.EventUser{}, err
+	}
+	user, err := GetUser(userID)
+	if err != nil {
+		return model.EventUser{}, err
+	}
+
+	eventUser := model.EventUser{
+		EventID: event.ID,
+		UserID:  user.ID,
+	}
+	if err := eventUser.Create(); err != nil {
+		if isDuplicateEntry(err) {
+			return eventUser, alreadyExistsError("event member already exists")
+		}
+		return eventUser, err
+	}
+	eventUser.Event = event
+	eventUser.User = user
+	return eventUser, nil
+}
+
+// RemoveEventMember はイベントからユーザーの登録を解除する
+func RemoveEventMember(eventID uint, userID uint) error {
+	if _, err := GetEvent(eventID); err != nil {
+		return err
+	}
+
+	eventUser := model.EventUser{
+		EventID: eventID,
+		UserID:  userID,
+	}
+	deleted, err := eventUser.DeleteByEventIDAndUserID()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return notFoundError("event member not found")
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// MaxForecastDays は1回の予測で対象とする最大日数
+const MaxForecastDays = 14
+
+// ForecastDay は1日分の活動予測を表す
+type ForecastDay struct {
+	Date     string          `json:"date"`    // "2006-01-02"（JST）
+	Weekday  int             `json:"weekday"` // MySQL WEEKDAY形式（0=月, 6=日）
+	Calendar CalendarDay     `json:"calendar"`
+	Events   []ForecastEvent `json:"events"`
+}
+
+// ForecastEvent は1日分のイベント1件の活動予測を表す
+// 判定結果は通知と同じ processEvent で求めるため、Included は対象日の通知に含まれるかを表す
+// 来訪予定者（Members）の予測は、活動確率が閾値以上のイベントのみ StayWatch から取得する
+type ForecastEvent struct {
+	EventDiagnosis
+	HourlyProbabilities []float64 `json:"hourly_probabilities"` // length 24, index = hour (0-23 JST)
+}
+
+// GetForecast は from（JST）から days 日分の、イベントごとの時間帯別の活動確率・来訪予定者・推奨時間帯を予測する
+// 学事暦で除外する日は通知と同様に推奨時間帯を求めず、活動確率は参考値として返す
+// 予測は曜日のみで決まるため、StayWatch への問い合わせを含めて曜日ごとに1度だけ行い、日付ごとには学事暦の除外のみを適用する
+func GetForecast(from time.Time, days int, lookback Lookback) ([]ForecastDay, error) {
+	if days < 1 || days > MaxForecastDays {
+		return nil, invalidInputError("days must be between 1 and %d", MaxForecastDays)
+	}
+
+	var e model.Event
+	events, err := e.ReadAllWithUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	// 時間帯別の活動確率と判定結果は曜日ごとに同じため、曜日ごとに1度だけ計算する
+	hourly := make(map[time.Weekday][]ActivityProbability)
+	weekdayDiagnoses := make(map[time.Weekday][]EventDiagnosis)
+
+	from = truncateToDateJST(from)
+	forecast := make([]ForecastDay, 0, days)
+	for i := 0; i < days; i++ {
+		date := from.AddDate(0, 0, i)
+		weekday := date.Weekday()
+		if _, ok := hourly[weekday]; !ok {
+			probabilities := make([]ActivityProbability, 0, len(events))
+			for _, ev := range events {
+				probabilities = append(probabilities, calcEventProbability(ev, weekday, lookback, false))
+			}
+			hourly[weekday] = probabilities
+		}
+
+		day, err := GetCalendarDay(date)
+		if err != nil {
+			log.Printf("failed to read academic calendar for %s: %v", day.Date, err)
+		}
+
+		var diagnoses []EventDiagnosis
+		if day.Excluded {
+			diagnoses = excludedDayDiagnoses(events, day)
+		} else {
+			if _, ok := weekdayDiagnoses[weekday]; !ok {
+				results := make([]EventDiagnosis, 0, len(events))
+				for _, ev := range events {
+					_, _, diagnosis := processEvent(ev, date, lookback)
+					results = append(results, diagnosis)
+				}
+				weekdayDiagnoses[weekday] = results
+			}
+			diagnoses = weekdayDiagnoses[weekday]
+		}
+
+		forecastEvents := make([]ForecastEvent, len(events))
+		for j := range events {
+			forecastEvents[j] = ForecastEvent{
+				EventDiagnosis:      diagnoses[j],
+				HourlyProbabilities: hourly[weekday][j].Probabilities,
+			}
+		}
+		forecast = append(forecast, ForecastDay{
+			Date:     date.Format("2006-01-02"),
+			Weekday:  (int(weekday) + 6) % 7,
+			Calendar: day,
+			Events:   forecastEvents,
+		})
+	}
+	return forecast, nil
+}
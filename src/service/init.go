@@ -86,4 +86,6 @@ func init() {
 
 	stayWatchClient = lib.NewStayWatchClient(staywatch.APIKey)
 	slackClient = slack.New(getEnv("SLACK_BOT_USER_OAUTH_TOKEN", ""))
+	estimatorSettings = loadEstimatorSettings()
+	defaultLookback = loadDefaultLookback()
 }
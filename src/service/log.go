@@ -1,17 +1,25 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
 	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"gorm.io/gorm"
 )
 
 // LogEntryInput はログ登録のための入力データを表す
+// イベントは EventID または EventCode、ステータスは StatusID または StatusName で指定する
 type LogEntryInput struct {
-	EventID                uint
-	StatusID               uint
-	EventTime              string  // RFC3339形式 JST (例: "2006-01-02T15:04:05+09:00")
+	EventID                 uint
+	EventCode               string // デバイスに設定されたイベントコード（例: NFCタグのID）
+	StatusID                uint
+	StatusName              string  // ステータス名（例: "start"）
+	IdempotencyKey          string  // 送信元が付与する重複排除キー（空可）
+	EventTime               string  // RFC3339形式 JST (例: "2006-01-02T15:04:05+09:00")
 	ParticipateStayWatchIDs []int64 // 参加メンバの stay_watch_id（空可）
 	RoomStayWatchIDs        []int64 // 在室メンバの stay_watch_id（空可）
 }
@@ -38,7 +46,7 @@ func resolveUserIDs(stayWatchIDs []int64) ([]uint, error) {
 				missing = append(missing, id)
 			}
 		}
-		return nil, fmt.Errorf("unknown stay_watch_id(s): %v", missing)
+		return nil, fmt.Errorf("%w(s): %v", ErrUnknownStayWatchID, missing)
 	}
 	userIDs := make([]uint, len(users))
 	for i, user := range users {
@@ -47,65 +55,371 @@ func resolveUserIDs(stayWatchIDs []int64) ([]uint, error) {
 	return userIDs, nil
 }
 
+// LogRegistration はログ1件の登録結果を表す
+type LogRegistration struct {
+	Log          model.Log
+	Deduplicated bool   // 重複と判定し、既存のログを返した場合 true
+	Warning      string // strict=false のため、状態遷移の検証に失敗したまま登録した場合にその内容が入る
+}
+
 // RegisterLog は単一のログを登録する（JST検証付き）
-func RegisterLog(input LogEntryInput) (model.Log, error) {
+// 同じ重複排除キー、または同じイベント・ステータス・発生時刻のログが既にある場合は
+// 新たに登録せず既存のログを返す
+// strict の場合、イベントの状態遷移として不正なログ（start の連続、start のない end など）は登録しない
+func RegisterLog(input LogEntryInput, strict bool) (LogRegistration, error) {
+	// 重複排除キーによる再送の判定（イベント等が削除されていても元のログを返す）
+	if input.IdempotencyKey != "" {
+		existing := model.Log{IdempotencyKey: &input.IdempotencyKey}
+		if err := existing.ReadByIdempotencyKey(); err != nil {
+			return LogRegistration{}, err
+		}
+		if existing.ID != 0 {
+			return LogRegistration{Log: existing, Deduplicated: true}, nil
+		}
+	}
+
 	// Event存在確認
-	event := model.Event{}
-	event.ID = input.EventID
-	if err := event.ReadByID(); err != nil {
-		return model.Log{}, fmt.Errorf("event_id %d not found", input.EventID)
+	event, err := resolveLogEvent(input)
+	if err != nil {
+		return LogRegistration{}, err
 	}
 
 	// Status存在確認
-	status := model.Status{}
-	status.ID = input.StatusID
-	if err := status.ReadByID(); err != nil {
-		return model.Log{}, fmt.Errorf("status_id %d not found", input.StatusID)
+	status, err := resolveLogStatus(input)
+	if err != nil {
+		return LogRegistration{}, err
 	}
 
 	// 時刻をパース（JSTのみ許可）
 	eventTimeJST, err := lib.ParseJST(input.EventTime)
 	if err != nil {
-		return model.Log{}, fmt.Errorf("invalid event_time: %v", err)
+		return LogRegistration{}, invalidInputError("invalid event_time: %v", err)
+	}
+
+	// 重複排除キーを持たない再送は、イベント・ステータス・発生時刻の一致で判定する
+	existing := model.Log{EventID: event.ID, StatusID: status.ID, EventTime: eventTimeJST}
+	if err := existing.ReadByNaturalKey(); err != nil {
+		return LogRegistration{}, err
+	}
+	if existing.ID != 0 {
+		return LogRegistration{Log: existing, Deduplicated: true}, nil
+	}
+
+	// イベントの状態遷移を検証（strict でない場合は警告として登録を続ける）
+	var warning string
+	if err := validateLogTransition(event.ID, status.Name, eventTimeJST); err != nil {
+		if strict {
+			return LogRegistration{}, err
+		}
+		warning = err.Error()
 	}
 
 	// stay_watch_id を内部 user_id に解決
 	roomUserIDs, err := resolveUserIDs(input.RoomStayWatchIDs)
 	if err != nil {
-		return model.Log{}, fmt.Errorf("room_users: %v", err)
+		return LogRegistration{}, fmt.Errorf("room_users: %w", err)
 	}
 	participateUserIDs, err := resolveUserIDs(input.ParticipateStayWatchIDs)
 	if err != nil {
-		return model.Log{}, fmt.Errorf("participate_users: %v", err)
+		return LogRegistration{}, fmt.Errorf("participate_users: %w", err)
 	}
 
 	// ログを作成（中間テーブル含めトランザクション）
 	log := model.Log{
-		EventID:   input.EventID,
-		StatusID:  input.StatusID,
+		EventID:   event.ID,
+		StatusID:  status.ID,
 		EventTime: eventTimeJST,
 	}
+	if input.IdempotencyKey != "" {
+		log.IdempotencyKey = &input.IdempotencyKey
+	}
 
 	if err := log.CreateWithUsers(roomUserIDs, participateUserIDs); err != nil {
-		return model.Log{}, err
+		// 同じキーの同時送信で先に登録された場合はユニーク制約エラー（1062）になる
+		if log.IdempotencyKey != nil && isDuplicateEntry(err) {
+			existing := model.Log{IdempotencyKey: log.IdempotencyKey}
+			if err := existing.ReadByIdempotencyKey(); err == nil && existing.ID != 0 {
+				return LogRegistration{Log: existing, Deduplicated: true}, nil
+			}
+		}
+		return LogRegistration{}, err
 	}
+	invalidateModelCache()
 
-	return log, nil
+	return LogRegistration{Log: log, Warning: warning}, nil
+}
+
+// resolveLogEvent はログのイベントを EventCode または EventID から取得する
+// 両方が指定された場合は同じイベントを指していることを確認する
+func resolveLogEvent(input LogEntryInput) (model.Event, error) {
+	event := model.Event{}
+	if input.EventCode != "" {
+		event.Code = input.EventCode
+		if err := event.ReadByCode(); err != nil {
+			return event, notFoundError("event_code %s not found", input.EventCode)
+		}
+		if input.EventID != 0 && input.EventID != event.ID {
+			return event, invalidInputError("event_id %d does not match event_code %s", input.EventID, input.EventCode)
+		}
+		return event, nil
+	}
+
+	event.ID = input.EventID
+	if err := event.ReadByID(); err != nil {
+		return event, notFoundError("event_id %d not found", input.EventID)
+	}
+	return event, nil
+}
+
+// resolveLogStatus はログのステータスを StatusName または StatusID から取得する
+// 両方が指定された場合は同じステータスを指していることを確認する
+func resolveLogStatus(input LogEntryInput) (model.Status, error) {
+	status := model.Status{}
+	if input.StatusName != "" {
+		status.Name = input.StatusName
+		if err := status.ReadByName(); err != nil {
+			// 一時停止は環境により "pose" と "pause" のどちらで登録されているか異なるため、もう一方でも検索する
+			if !isPauseStatus(input.StatusName) {
+				return status, notFoundError("status %s not found", input.StatusName)
+			}
+			status = model.Status{Name: statusNamePose}
+			if input.StatusName == statusNamePose {
+				status.Name = statusNamePause
+			}
+			if err := status.ReadByName(); err != nil {
+				return status, notFoundError("status %s not found", input.StatusName)
+			}
+		}
+		if input.StatusID != 0 && input.StatusID != status.ID {
+			return status, invalidInputError("status_id %d does not match status %s", input.StatusID, input.StatusName)
+		}
+		return status, nil
+	}
+
+	status.ID = input.StatusID
+	if err := status.ReadByID(); err != nil {
+		return status, notFoundError("status_id %d not found", input.StatusID)
+	}
+	return status, nil
+}
+
+// LogBatchResult はログ一括登録の結果を表す
+type LogBatchResult struct {
+	Logs         []model.Log       // 登録したログ（重複と判定した入力は既存のログ）
+	Errors       map[string]string // 登録に失敗した入力のインデックスとエラー内容
+	Deduplicated map[string]uint   // 重複と判定した入力のインデックスと、既存のログID
+	Warnings     map[string]string // strict=false で状態遷移の検証に失敗したまま登録した入力のインデックスと内容
 }
 
 // BatchRegisterLogs は複数のログを一括登録する
-func BatchRegisterLogs(inputs []LogEntryInput) ([]model.Log, map[string]string, error) {
-	var logs []model.Log
-	errors := make(map[string]string)
+// 状態遷移を正しく検証できるよう、入力の順序に関わらず発生時刻の早いものから登録する
+func BatchRegisterLogs(inputs []LogEntryInput, strict bool) (LogBatchResult, error) {
+	result := LogBatchResult{
+		Logs:         []model.Log{},
+		Errors:       make(map[string]string),
+		Deduplicated: make(map[string]uint),
+		Warnings:     make(map[string]string),
+	}
 
-	for i, input := range inputs {
-		log, err := RegisterLog(input)
+	for _, i := range orderByEventTime(inputs) {
+		key := fmt.Sprintf("%d", i)
+		registration, err := RegisterLog(inputs[i], strict)
 		if err != nil {
-			errors[fmt.Sprintf("%d", i)] = err.Error()
+			result.Errors[key] = err.Error()
 			continue
 		}
-		logs = append(logs, log)
+		if registration.Deduplicated {
+			result.Deduplicated[key] = registration.Log.ID
+		}
+		if registration.Warning != "" {
+			result.Warnings[key] = registration.Warning
+		}
+		result.Logs = append(result.Logs, registration.Log)
 	}
 
-	return logs, errors, nil
+	return result, nil
+}
+
+// orderByEventTime は入力のインデックスを発生時刻の早い順に並べて返す
+// 時刻を解釈できない入力は末尾に元の順序のまま置く（登録時にエラーとなる）
+func orderByEventTime(inputs []LogEntryInput) []int {
+	times := make([]time.Time, len(inputs))
+	for i, input := range inputs {
+		if t, err := lib.ParseJST(input.EventTime); err == nil {
+			times[i] = t
+		}
+	}
+
+	order := make([]int, len(inputs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ta, tb := times[order[a]], times[order[b]]
+		if ta.IsZero() || tb.IsZero() {
+			return !ta.IsZero() && tb.IsZero()
+		}
+		return ta.Before(tb)
+	})
+	return order
+}
+
+// LogUpdateInput はログ修正のための入力データを表す（nil の項目は変更しない）
+type LogUpdateInput struct {
+	EventID                 *uint
+	EventCode               *string
+	StatusID                *uint
+	StatusName              *string
+	EventTime               *string  // RFC3339形式 JST
+	ParticipateStayWatchIDs *[]int64 // 指定した場合は参加メンバを置き換える
+	RoomStayWatchIDs        *[]int64 // 指定した場合は在室メンバを置き換える
+}
+
+// ListLogs は条件に一致するログを発生時刻の新しい順に取得する
+// 続きがある場合は、次のページの取得に使うカーソル（このページの最後のログID）を返す
+func ListLogs(filter model.LogFilter) ([]model.Log, *uint, error) {
+	limit := filter.Limit
+	filter.Limit = limit + 1
+	logs, err := model.ReadLogsByFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(logs) <= limit {
+		return logs, nil, nil
+	}
+	logs = logs[:limit]
+	next := logs[limit-1].ID
+	return logs, &next, nil
+}
+
+// StatusNamesForFilter はステータス名での絞り込みに使う名前の一覧を返す
+// 一時停止は "pose" と "pause" のどちらで登録されていても一致させる
+func StatusNamesForFilter(statusName string) []string {
+	if isPauseStatus(statusName) {
+		return []string{statusNamePose, statusNamePause}
+	}
+	return []string{statusName}
+}
+
+// GetLog は論理削除済みのものも含めて、在室・参加ユーザーとともにログを取得する
+func GetLog(id uint) (model.Log, error) {
+	log := model.Log{ID: id}
+	if err := log.ReadByIDWithUsers(); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return log, notFoundError("log not found")
+		}
+		return log, err
+	}
+	return log, nil
+}
+
+// UpdateLog はログのイベント・ステータス・発生時刻・在室/参加メンバを修正する
+// 読み取り機の設定ミスなどで誤ったログを直すためのもので、状態遷移の検証は行わない
+func UpdateLog(id uint, input LogUpdateInput) (model.Log, error) {
+	log, err := GetLog(id)
+	if err != nil {
+		return log, err
+	}
+	if log.DeletedAt.Valid {
+		return log, notFoundError("log not found")
+	}
+
+	if input.EventID != nil || input.EventCode != nil {
+		entry := LogEntryInput{}
+		if input.EventID != nil {
+			entry.EventID = *input.EventID
+		}
+		if input.EventCode != nil {
+			entry.EventCode = *input.EventCode
+		}
+		event, err := resolveLogEvent(entry)
+		if err != nil {
+			return log, err
+		}
+		log.EventID = event.ID
+	}
+	if input.StatusID != nil || input.StatusName != nil {
+		entry := LogEntryInput{}
+		if input.StatusID != nil {
+			entry.StatusID = *input.StatusID
+		}
+		if input.StatusName != nil {
+			entry.StatusName = *input.StatusName
+		}
+		status, err := resolveLogStatus(entry)
+		if err != nil {
+			return log, err
+		}
+		log.StatusID = status.ID
+	}
+	if input.EventTime != nil {
+		eventTimeJST, err := lib.ParseJST(*input.EventTime)
+		if err != nil {
+			return log, invalidInputError("invalid event_time: %v", err)
+		}
+		log.EventTime = eventTimeJST
+	}
+
+	var roomUserIDs, participateUserIDs *[]uint
+	if input.RoomStayWatchIDs != nil {
+		ids, err := resolveUserIDs(*input.RoomStayWatchIDs)
+		if err != nil {
+			return log, fmt.Errorf("room_users: %w", err)
+		}
+		roomUserIDs = &ids
+	}
+	if input.ParticipateStayWatchIDs != nil {
+		ids, err := resolveUserIDs(*input.ParticipateStayWatchIDs)
+		if err != nil {
+			return log, fmt.Errorf("participate_users: %w", err)
+		}
+		participateUserIDs = &ids
+	}
+
+	// 修正の結果、別のログと同じイベント・ステータス・発生時刻になる場合は重複として扱う
+	existing := model.Log{EventID: log.EventID, StatusID: log.StatusID, EventTime: log.EventTime}
+	if err := existing.ReadByNaturalKey(); err != nil {
+		return log, err
+	}
+	if existing.ID != 0 && existing.ID != log.ID {
+		return log, alreadyExistsError("log already exists")
+	}
+
+	if err := log.UpdateWithUsers(roomUserIDs, participateUserIDs); err != nil {
+		return log, err
+	}
+	invalidateModelCache()
+	return GetLog(id)
+}
+
+// DeleteLog はログを論理削除する
+func DeleteLog(id uint) error {
+	log, err := GetLog(id)
+	if err != nil {
+		return err
+	}
+	if log.DeletedAt.Valid {
+		return notFoundError("log not found")
+	}
+	if err := log.Delete(); err != nil {
+		return err
+	}
+	invalidateModelCache()
+	return nil
+}
+
+// RestoreLog は論理削除したログを元に戻す。削除されていないログはそのまま返す
+func RestoreLog(id uint) (model.Log, error) {
+	log, err := GetLog(id)
+	if err != nil {
+		return log, err
+	}
+	if !log.DeletedAt.Valid {
+		return log, nil
+	}
+	if err := log.Restore(); err != nil {
+		return log, err
+	}
+	invalidateModelCache()
+	return GetLog(id)
 }
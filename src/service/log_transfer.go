@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// ログの一括取り込み・書き出しの形式
+const (
+	LogFormatCSV    = "csv"
+	LogFormatNDJSON = "ndjson"
+)
+
+// logExportBatchSize 書き出し時に1度に読み込むログの件数
+const logExportBatchSize = 500
+
+// maxNDJSONLineBytes NDJSON の1行の最大長
+const maxNDJSONLineBytes = 1024 * 1024
+
+// maxImportIdempotencyKeyLength 重複排除キーの最大長（logs.idempotency_key の長さ）
+const maxImportIdempotencyKeyLength = 255
+
+// logCSVHeader はCSVの列名。取り込み時は列の順序を問わず、id は無視する
+var logCSVHeader = []string{"id", "event_id", "event_code", "status", "event_time", "room_users", "participate_users", "idempotency_key"}
+
+// LogRecord は一括取り込み・書き出しでのログ1件を表す（NDJSON の1行に対応する）
+type LogRecord struct {
+	ID               uint    `json:"id,omitempty"` // 書き出し時のみ。取り込み時は無視する
+	EventID          uint    `json:"event_id,omitempty"`
+	EventCode        string  `json:"event_code,omitempty"`
+	Status           string  `json:"status"`
+	EventTime        string  `json:"event_time"`
+	RoomUsers        []int64 `json:"room_users"`        // 在室メンバの stay_watch_id
+	ParticipateUsers []int64 `json:"participate_users"` // 参加メンバの stay_watch_id
+	IdempotencyKey   string  `json:"idempotency_key,omitempty"`
+}
+
+// LogImportIssue は取り込みで失敗・警告となった行を表す
+type LogImportIssue struct {
+	Line    int    `json:"line"` // 入力の行番号（1始まり。CSVではヘッダーが1行目）
+	Message string `json:"message"`
+}
+
+// LogImportResult はログの一括取り込みの結果を表す
+type LogImportResult struct {
+	Imported     int              `json:"imported"`     // 新たに登録した行数
+	Deduplicated int              `json:"deduplicated"` // 既存のログと重複していた行数
+	Failed       int              `json:"failed"`       // 登録に失敗した行数
+	Errors       []LogImportIssue `json:"errors"`
+	Warnings     []LogImportIssue `json:"warnings"` // strict=false で状態遷移の検証に失敗したまま登録した行
+}
+
+// ImportLogs は CSV または NDJSON のログを1行ずつ読み込んで登録する
+// 入力全体をメモリに載せないよう、行ごとに RegisterLog で検証・登録し、失敗した行は結果に記録して続行する
+// 状態遷移の検証は行の順に行うため、入力は発生時刻順に並べておく必要がある
+func ImportLogs(r io.Reader, format string, strict bool) (LogImportResult, error) {
+	result := LogImportResult{
+		Errors:   []LogImportIssue{},
+		Warnings: []LogImportIssue{},
+	}
+
+	register := func(line int, record LogRecord) {
+		registration, err := RegisterLog(record.toLogEntryInput(), strict)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, LogImportIssue{Line: line, Message: err.Error()})
+			return
+		}
+		if registration.Deduplicated {
+			result.Deduplicated++
+		} else {
+			result.Imported++
+		}
+		if registration.Warning != "" {
+			result.Warnings = append(result.Warnings, LogImportIssue{Line: line, Message: registration.Warning})
+		}
+	}
+	reject := func(line int, err error) {
+		result.Failed++
+		result.Errors = append(result.Errors, LogImportIssue{Line: line, Message: err.Error()})
+	}
+
+	var err error
+	switch format {
+	case LogFormatCSV:
+		err = readLogCSV(r, register, reject)
+	case LogFormatNDJSON:
+		err = readLogNDJSON(r, register, reject)
+	default:
+		err = fmt.Errorf("unsupported format: %s", format)
+	}
+	return result, err
+}
+
+// readLogCSV はヘッダー付きのCSVを1行ずつ LogRecord に変換して register に渡す
+// 変換できない行は reject に渡し、読み込み自体ができなくなった場合のみエラーを返す
+func readLogCSV(r io.Reader, register func(int, LogRecord), reject func(int, error)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("empty input")
+		}
+		return fmt.Errorf("invalid csv header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["event_time"]; !ok {
+		return errors.New("csv header must contain event_time")
+	}
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			reject(parseErr.StartLine, parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		record := LogRecord{
+			EventCode:      field(row, "event_code"),
+			Status:         field(row, "status"),
+			EventTime:      field(row, "event_time"),
+			IdempotencyKey: field(row, "idempotency_key"),
+		}
+		if s := field(row, "event_id"); s != "" {
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				reject(line, fmt.Errorf("invalid event_id: %s", s))
+				continue
+			}
+			record.EventID = uint(id)
+		}
+		if record.RoomUsers, err = parseStayWatchIDList(field(row, "room_users")); err != nil {
+			reject(line, fmt.Errorf("invalid room_users: %v", err))
+			continue
+		}
+		if record.ParticipateUsers, err = parseStayWatchIDList(field(row, "participate_users")); err != nil {
+			reject(line, fmt.Errorf("invalid participate_users: %v", err))
+			continue
+		}
+		if err := record.validate(); err != nil {
+			reject(line, err)
+			continue
+		}
+		register(line, record)
+	}
+}
+
+// readLogNDJSON は1行1件の JSON を LogRecord に変換して register に渡す。空行は読み飛ばす
+func readLogNDJSON(r io.Reader, register func(int, LogRecord), reject func(int, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record LogRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			reject(line, fmt.Errorf("invalid json: %v", err))
+			continue
+		}
+		if err := record.validate(); err != nil {
+			reject(line, err)
+			continue
+		}
+		register(line, record)
+	}
+	return scanner.Err()
+}
+
+// parseStayWatchIDList は空白またはセミコロン区切りの stay_watch_id を解析する
+func parseStayWatchIDList(s string) ([]int64, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == ' '
+	})
+	ids := make([]int64, 0, len(fields))
+	for _, f := range fields {
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stay_watch_id: %s", f)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// validate は登録に必要な項目が揃っているかを確認する
+func (r LogRecord) validate() error {
+	if r.EventID == 0 && r.EventCode == "" {
+		return errors.New("event_id or event_code is required")
+	}
+	if r.Status == "" {
+		return errors.New("status is required")
+	}
+	if r.EventTime == "" {
+		return errors.New("event_time is required")
+	}
+	if len(r.IdempotencyKey) > maxImportIdempotencyKeyLength {
+		return errors.New("idempotency_key is too long")
+	}
+	return nil
+}
+
+// toLogEntryInput は RegisterLog の入力形式に変換する
+// 別の環境から書き出したログも取り込めるよう、event_code がある場合は環境ごとに異なる event_id を使わない
+func (r LogRecord) toLogEntryInput() LogEntryInput {
+	eventID := r.EventID
+	if r.EventCode != "" {
+		eventID = 0
+	}
+	return LogEntryInput{
+		EventID:                 eventID,
+		EventCode:               r.EventCode,
+		StatusName:              r.Status,
+		IdempotencyKey:          r.IdempotencyKey,
+		EventTime:               r.EventTime,
+		ParticipateStayWatchIDs: r.ParticipateUsers,
+		RoomStayWatchIDs:        r.RoomUsers,
+	}
+}
+
+// newLogRecord はログを書き出し用の LogRecord に変換する
+func newLogRecord(l model.Log) LogRecord {
+	record := LogRecord{
+		ID:               l.ID,
+		EventID:          l.EventID,
+		EventCode:        l.Event.Code,
+		Status:           l.Status.Name,
+		EventTime:        l.EventTime.In(lib.JST).Format(time.RFC3339),
+		RoomUsers:        make([]int64, 0, len(l.RoomUsers)),
+		ParticipateUsers: make([]int64, 0, len(l.ParticipateUsers)),
+	}
+	for _, u := range l.RoomUsers {
+		record.RoomUsers = append(record.RoomUsers, u.StayWatchID)
+	}
+	for _, u := range l.ParticipateUsers {
+		record.ParticipateUsers = append(record.ParticipateUsers, u.StayWatchID)
+	}
+	if l.IdempotencyKey != nil {
+		record.IdempotencyKey = *l.IdempotencyKey
+	}
+	return record
+}
+
+// ExportLogs は条件に一致するログを ID 順に CSV または NDJSON で w に書き出す
+// 一定件数ごとに読み込んで書き出すため、期間が長くても全件をメモリに載せない
+// flush が nil でない場合は、読み込みごとに呼び出してクライアントへ送信させる
+func ExportLogs(w io.Writer, format string, filter model.LogFilter, flush func()) error {
+	write, flushWriter, err := newLogRecordWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	err = model.FindLogsInBatches(filter, logExportBatchSize, func(logs []model.Log) error {
+		for _, l := range logs {
+			if err := write(newLogRecord(l)); err != nil {
+				return err
+			}
+		}
+		if err := flushWriter(); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flushWriter()
+}
+
+// newLogRecordWriter は LogRecord を1件ずつ w に書き出す関数と、書き出した内容を w に反映する関数を返す
+// CSV の場合はこの時点でヘッダーを書き出す
+func newLogRecordWriter(w io.Writer, format string) (func(LogRecord) error, func() error, error) {
+	switch format {
+	case LogFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(logCSVHeader); err != nil {
+			return nil, nil, err
+		}
+		write := func(r LogRecord) error {
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(r.ID), 10),
+				strconv.FormatUint(uint64(r.EventID), 10),
+				r.EventCode,
+				r.Status,
+				r.EventTime,
+				joinStayWatchIDs(r.RoomUsers),
+				joinStayWatchIDs(r.ParticipateUsers),
+				r.IdempotencyKey,
+			})
+		}
+		flush := func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		return write, flush, nil
+	case LogFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write := func(r LogRecord) error {
+			return encoder.Encode(r)
+		}
+		return write, func() error { return nil }, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+// joinStayWatchIDs は stay_watch_id を空白区切りの文字列にする
+func joinStayWatchIDs(ids []int64) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// importedLine は読み込みで register または reject に渡された1行分を表す
+type importedLine struct {
+	line   int
+	record LogRecord
+	err    string
+}
+
+// readLogs は format の入力を読み込み、register・reject に渡された行を順に返す
+func readLogs(t *testing.T, format, input string) ([]importedLine, error) {
+	t.Helper()
+	var lines []importedLine
+	register := func(line int, record LogRecord) {
+		lines = append(lines, importedLine{line: line, record: record})
+	}
+	reject := func(line int, err error) {
+		lines = append(lines, importedLine{line: line, err: err.Error()})
+	}
+
+	var err error
+	switch format {
+	case LogFormatCSV:
+		err = readLogCSV(strings.NewReader(input), register, reject)
+	case LogFormatNDJSON:
+		err = readLogNDJSON(strings.NewReader(input), register, reject)
+	default:
+		t.Fatalf("unknown format %q", format)
+	}
+	return lines, err
+}
+
+func TestReadLogCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []importedLine
+		wantErr string
+	}{
+		{
+			name: "BOM and reordered columns",
+			input: "\ufeffstatus, event_time ,EVENT_CODE,room_users,participate_users,id\n" +
+				"start,2025-11-24T17:40:26+09:00,0437ac48be2a81,1001 1002,1001,99\n",
+			want: []importedLine{{line: 2, record: LogRecord{
+				EventCode: "0437ac48be2a81", Status: "start", EventTime: "2025-11-24T17:40:26+09:00",
+				RoomUsers: []int64{1001, 1002}, ParticipateUsers: []int64{1001},
+			}}},
+		},
+		{
+			name: "event_id and idempotency_key",
+			input: "event_id,status,event_time,idempotency_key\n" +
+				"3,end,2025-11-24T18:42:26+09:00,logger-1:42\n",
+			want: []importedLine{{line: 2, record: LogRecord{
+				EventID: 3, Status: "end", EventTime: "2025-11-24T18:42:26+09:00", IdempotencyKey: "logger-1:42",
+				RoomUsers: []int64{}, ParticipateUsers: []int64{},
+			}}},
+		},
+		{
+			name: "semicolon separated members and short rows",
+			input: "event_code,status,event_time,room_users,participate_users\n" +
+				"a,start,2025-11-24T17:40:26+09:00,1001;1002; 1003\n",
+			want: []importedLine{{line: 2, record: LogRecord{
+				EventCode: "a", Status: "start", EventTime: "2025-11-24T17:40:26+09:00",
+				RoomUsers: []int64{1001, 1002, 1003}, ParticipateUsers: []int64{},
+			}}},
+		},
+		{
+			name: "invalid rows are rejected with their line numbers",
+			input: "event_id,status,event_time,room_users\n" +
+				"x,start,2025-11-24T17:40:26+09:00,\n" +
+				"1,start,2025-11-24T17:40:26+09:00,1001 abc\n" +
+				",start,2025-11-24T17:40:26+09:00,\n" +
+				"1,,2025-11-24T17:40:26+09:00,\n" +
+				"1,start,,\n" +
+				"1,end,2025-11-24T18:00:00+09:00,\n",
+			want: []importedLine{
+				{line: 2, err: "invalid event_id: x"},
+				{line: 3, err: "invalid room_users: invalid stay_watch_id: abc"},
+				{line: 4, err: "event_id or event_code is required"},
+				{line: 5, err: "status is required"},
+				{line: 6, err: "event_time is required"},
+				{line: 7, record: LogRecord{EventID: 1, Status: "end", EventTime: "2025-11-24T18:00:00+09:00", RoomUsers: []int64{}, ParticipateUsers: []int64{}}},
+			},
+		},
+		{
+			// 改行を含む値の後でも、行番号は入力の物理的な行を指す
+			name: "line numbers after a multi-line field",
+			input: "event_id,status,event_time,idempotency_key\n" +
+				"1,start,2025-11-24T17:40:26+09:00,\"multi\nline\"\n" +
+				"1,bad\"quote,2025-11-24T18:00:00+09:00,\n" +
+				"1,end,2025-11-24T18:42:26+09:00,\n",
+			want: []importedLine{
+				{line: 2, record: LogRecord{EventID: 1, Status: "start", EventTime: "2025-11-24T17:40:26+09:00", IdempotencyKey: "multi\nline", RoomUsers: []int64{}, ParticipateUsers: []int64{}}},
+				{line: 4, err: "bare \" in non-quoted-field"},
+				{line: 5, record: LogRecord{EventID: 1, Status: "end", EventTime: "2025-11-24T18:42:26+09:00", RoomUsers: []int64{}, ParticipateUsers: []int64{}}},
+			},
+		},
+		{
+			name: "too long idempotency_key",
+			input: "event_id,status,event_time,idempotency_key\n" +
+				"1,start,2025-11-24T17:40:26+09:00," + strings.Repeat("k", maxImportIdempotencyKeyLength+1) + "\n" +
+				"1,start,2025-11-24T17:40:26+09:00," + strings.Repeat("k", maxImportIdempotencyKeyLength) + "\n",
+			want: []importedLine{
+				{line: 2, err: "idempotency_key is too long"},
+				{line: 3, record: LogRecord{EventID: 1, Status: "start", EventTime: "2025-11-24T17:40:26+09:00", IdempotencyKey: strings.Repeat("k", maxImportIdempotencyKeyLength), RoomUsers: []int64{}, ParticipateUsers: []int64{}}},
+			},
+		},
+		{name: "empty input", input: "", wantErr: "empty input"},
+		{name: "header without event_time", input: "event_id,status\n1,start\n", wantErr: "csv header must contain event_time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLogs(t, LogFormatCSV, tt.input)
+			assertReadLogs(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestReadLogNDJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []importedLine
+		wantErr string
+	}{
+		{
+			name: "records and blank lines",
+			input: "\n" +
+				`{"event_code": "a", "status": "start", "event_time": "2025-11-24T17:40:26+09:00", "room_users": [1001, 1002], "participate_users": [1001]}` + "\n" +
+				"   \n" +
+				`{"event_id": 3, "status": "end", "event_time": "2025-11-24T18:42:26+09:00", "idempotency_key": "k"}`,
+			want: []importedLine{
+				{line: 2, record: LogRecord{EventCode: "a", Status: "start", EventTime: "2025-11-24T17:40:26+09:00", RoomUsers: []int64{1001, 1002}, ParticipateUsers: []int64{1001}}},
+				{line: 4, record: LogRecord{EventID: 3, Status: "end", EventTime: "2025-11-24T18:42:26+09:00", IdempotencyKey: "k"}},
+			},
+		},
+		{
+			name: "invalid lines are rejected with their line numbers",
+			input: `{"event_id": 1, "status": "start"` + "\n" +
+				`{"event_id": "1", "status": "start", "event_time": "2025-11-24T17:40:26+09:00"}` + "\n" +
+				`{"status": "start", "event_time": "2025-11-24T17:40:26+09:00"}` + "\n" +
+				`{"event_id": 1, "status": "start", "event_time": "2025-11-24T17:40:26+09:00", "idempotency_key": "` + strings.Repeat("k", maxImportIdempotencyKeyLength+1) + `"}` + "\n" +
+				`{"event_id": 1, "status": "start", "event_time": "2025-11-24T17:40:26+09:00"}` + "\n",
+			want: []importedLine{
+				{line: 1, err: "invalid json: unexpected end of JSON input"},
+				{line: 2, err: "invalid json: json: cannot unmarshal string into Go struct field LogRecord.event_id of type uint"},
+				{line: 3, err: "event_id or event_code is required"},
+				{line: 4, err: "idempotency_key is too long"},
+				{line: 5, record: LogRecord{EventID: 1, Status: "start", EventTime: "2025-11-24T17:40:26+09:00"}},
+			},
+		},
+		{
+			name:    "line longer than the limit",
+			input:   `{"event_id": 1, "status": "` + strings.Repeat("s", maxNDJSONLineBytes) + `"}` + "\n",
+			wantErr: "token too long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLogs(t, LogFormatNDJSON, tt.input)
+			assertReadLogs(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseStayWatchIDList(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int64
+		wantErr bool
+	}{
+		{input: "", want: []int64{}},
+		{input: "1001", want: []int64{1001}},
+		{input: "1001 1002", want: []int64{1001, 1002}},
+		{input: "1001;1002", want: []int64{1001, 1002}},
+		{input: "  1001 ;; 1002  ", want: []int64{1001, 1002}},
+		{input: "-1", want: []int64{-1}},
+		{input: "1001,1002", wantErr: true},
+		{input: "1001 x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseStayWatchIDList(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseStayWatchIDList(%q) error = nil, want error", tt.input)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStayWatchIDList(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogRecordToLogEntryInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		record        LogRecord
+		wantEventID   uint
+		wantEventCode string
+	}{
+		{name: "event_id only", record: LogRecord{EventID: 3}, wantEventID: 3},
+		{name: "event_code only", record: LogRecord{EventCode: "a"}, wantEventCode: "a"},
+		// 別の環境の event_id は使わず、event_code で対応付ける
+		{name: "event_code takes precedence", record: LogRecord{EventID: 3, EventCode: "a"}, wantEventCode: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.record.Status = "start"
+			tt.record.EventTime = "2025-11-24T17:40:26+09:00"
+			tt.record.IdempotencyKey = "k"
+			tt.record.RoomUsers = []int64{1001}
+			tt.record.ParticipateUsers = []int64{1002}
+
+			got := tt.record.toLogEntryInput()
+			want := LogEntryInput{
+				EventID:                 tt.wantEventID,
+				EventCode:               tt.wantEventCode,
+				StatusName:              "start",
+				IdempotencyKey:          "k",
+				EventTime:               "2025-11-24T17:40:26+09:00",
+				ParticipateStayWatchIDs: []int64{1002},
+				RoomStayWatchIDs:        []int64{1001},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("toLogEntryInput() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLogRecordRoundTrip(t *testing.T) {
+	key := "logger-1:42"
+	logs := []model.Log{
+		{
+			ID:               10,
+			EventTime:        time.Date(2025, 11, 24, 8, 40, 26, 0, time.UTC),
+			EventID:          3,
+			Event:            model.Event{Code: "0437ac48be2a81"},
+			Status:           model.Status{Name: "start"},
+			RoomUsers:        []model.User{{StayWatchID: 1001}, {StayWatchID: 1002}},
+			ParticipateUsers: []model.User{{StayWatchID: 1001}},
+			IdempotencyKey:   &key,
+		},
+		{
+			ID:        11,
+			EventTime: time.Date(2025, 11, 24, 18, 42, 26, 0, lib.JST),
+			EventID:   3,
+			Event:     model.Event{Code: "0437ac48be2a81"},
+			Status:    model.Status{Name: "end"},
+		},
+	}
+
+	want := make([]LogRecord, len(logs))
+	for i, l := range logs {
+		want[i] = newLogRecord(l)
+		// 取り込み時は id を使わない
+		want[i].ID = 0
+	}
+	if want[0].EventTime != "2025-11-24T17:40:26+09:00" {
+		t.Fatalf("newLogRecord() EventTime = %q, want JST RFC 3339", want[0].EventTime)
+	}
+
+	for _, format := range []string{LogFormatCSV, LogFormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			write, flush, err := newLogRecordWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range logs {
+				if err := write(newLogRecord(l)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readLogs(t, format, buf.String())
+			if err != nil {
+				t.Fatalf("read error = %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("read %d records, want %d: %+v", len(got), len(want), got)
+			}
+			for i := range got {
+				if got[i].err != "" {
+					t.Errorf("record %d rejected: %s", i, got[i].err)
+					continue
+				}
+				// NDJSON では id も読み込まれるが、登録時には使わない
+				got[i].record.ID = 0
+				if !reflect.DeepEqual(got[i].record, want[i]) {
+					t.Errorf("record %d = %+v, want %+v", i, got[i].record, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewLogRecordWriterUnsupportedFormat(t *testing.T) {
+	if _, _, err := newLogRecordWriter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("newLogRecordWriter() error = nil, want unsupported format")
+	}
+}
+
+// assertReadLogs は読み込んだ行とエラーが want・wantErr と一致するかを検証する
+func assertReadLogs(t *testing.T, got []importedLine, err error, want []importedLine, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("error = %v, want to contain %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range got {
+		if got[i].line != want[i].line {
+			t.Errorf("line[%d] = %d, want %d", i, got[i].line, want[i].line)
+		}
+		if want[i].err != "" {
+			if !strings.Contains(got[i].err, want[i].err) {
+				t.Errorf("line %d error = %q, want to contain %q", want[i].line, got[i].err, want[i].err)
+			}
+			continue
+		}
+		if got[i].err != "" {
+			t.Errorf("line %d rejected: %s", want[i].line, got[i].err)
+			continue
+		}
+		if !reflect.DeepEqual(got[i].record, want[i].record) {
+			t.Errorf("line %d record = %+v, want %+v", want[i].line, got[i].record, want[i].record)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// logStateLookback は新しいログの直前の状態を求めるために参照する過去のログの範囲
+const logStateLookback = 24 * time.Hour
+
+// validateLogTransition は新しいログがイベントの状態遷移（idle → active → paused → active → idle）として
+// 正しいかを、同じイベントの前後のログと照らして検証する
+// 直前の状態から遷移できない場合と、直後のログがこのログの挿入によって遷移できなくなる場合にエラーを返す
+func validateLogTransition(eventID uint, statusName string, eventTime time.Time) error {
+	logs, err := model.ReadLogsByEventIDAndDateRange(eventID, eventTime.Add(-logStateLookback), eventTime.Add(sessionOrphanAfter))
+	if err != nil {
+		return err
+	}
+	return checkLogTransition(logs, statusName, eventTime)
+}
+
+// checkLogTransition は同じイベントの既存のログ logs に対して、validateLogTransition と同じ検証を行う
+// 同時刻の既存のログは新しいログより前にあるものとして扱う
+func checkLogTransition(logs []model.Log, statusName string, eventTime time.Time) error {
+	var before, after []model.Log
+	for _, l := range sortLogsByEventTime(logs) {
+		if l.EventTime.After(eventTime) {
+			after = append(after, l)
+		} else {
+			before = append(before, l)
+		}
+	}
+
+	state := sessionStateAt(before, eventTime)
+	next, ok := NextSessionState(state, statusName)
+	if !ok {
+		return fmt.Errorf("invalid transition: %s while event is %s", statusName, state)
+	}
+
+	// 直後のログが現在は有効な遷移で、挿入後に無効となる場合も不正とする
+	if len(after) > 0 {
+		following := after[0]
+		if _, validBefore := NextSessionState(state, following.Status.Name); validBefore {
+			if _, validAfter := NextSessionState(next, following.Status.Name); !validAfter {
+				return fmt.Errorf("invalid transition: %s would invalidate the following %s at %s",
+					statusName, following.Status.Name, lib.FormatDateTime(following.EventTime))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+func TestCheckLogTransitionFollowsStateTable(t *testing.T) {
+	at := time.Date(2025, 6, 13, 12, 0, 0, 0, lib.JST)
+	// 各状態になるまでの既存のログ
+	logsFor := map[SessionState][]model.Log{
+		SessionIdle: {
+			testLog(1, statusNameStart, at.Add(-2*time.Hour)),
+			testLog(2, statusNameEnd, at.Add(-time.Hour)),
+		},
+		SessionActive: {
+			testLog(1, statusNameStart, at.Add(-time.Hour)),
+		},
+		SessionPaused: {
+			testLog(1, statusNameStart, at.Add(-2*time.Hour)),
+			testLog(2, statusNamePose, at.Add(-time.Hour)),
+		},
+	}
+
+	for _, tt := range sessionTransitionTests {
+		t.Run(tt.state.String()+"/"+tt.status, func(t *testing.T) {
+			err := checkLogTransition(logsFor[tt.state], tt.status, at)
+			if tt.ok && err != nil {
+				t.Errorf("checkLogTransition() error = %v, want nil", err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("checkLogTransition() error = nil, want invalid transition")
+			}
+		})
+	}
+}
+
+func TestCheckLogTransition(t *testing.T) {
+	base := time.Date(2025, 6, 13, 10, 0, 0, 0, lib.JST)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+	startEnd := []model.Log{
+		testLog(1, statusNameStart, at(0)),
+		testLog(2, statusNameEnd, at(2*time.Hour)),
+	}
+
+	tests := []struct {
+		name    string
+		logs    []model.Log
+		status  string
+		at      time.Time
+		wantErr string // 空の場合はエラーなし
+	}{
+		{name: "first start", status: statusNameStart, at: at(0)},
+		{name: "end without start", status: statusNameEnd, at: at(0), wantErr: "end while event is idle"},
+		{
+			name:   "legacy pose while active",
+			logs:   []model.Log{testLog(1, statusNameStart, at(0))},
+			status: statusNamePose,
+			at:     at(time.Hour),
+		},
+		{
+			name:    "pause after pose",
+			logs:    []model.Log{testLog(1, statusNameStart, at(0)), testLog(2, statusNamePose, at(time.Hour))},
+			status:  statusNamePause,
+			at:      at(90 * time.Minute),
+			wantErr: "pause while event is paused",
+		},
+		{
+			name:    "pose after pause",
+			logs:    []model.Log{testLog(1, statusNameStart, at(0)), testLog(2, statusNamePause, at(time.Hour))},
+			status:  statusNamePose,
+			at:      at(90 * time.Minute),
+			wantErr: "pose while event is paused",
+		},
+		{
+			name:   "out-of-order pause keeps the following end valid",
+			logs:   startEnd,
+			status: statusNamePause,
+			at:     at(time.Hour),
+		},
+		{
+			name:    "out-of-order end invalidates the following end",
+			logs:    startEnd,
+			status:  statusNameEnd,
+			at:      at(time.Hour),
+			wantErr: "would invalidate the following end at 2025-06-13 12:00",
+		},
+		{
+			name:    "out-of-order start invalidates the following start",
+			logs:    startEnd,
+			status:  statusNameStart,
+			at:      at(-time.Hour),
+			wantErr: "would invalidate the following start at 2025-06-13 10:00",
+		},
+		{
+			name:    "duplicate start while active",
+			logs:    startEnd,
+			status:  statusNameStart,
+			at:      at(time.Hour),
+			wantErr: "start while event is active",
+		},
+		{
+			name:   "start after the session ended",
+			logs:   startEnd,
+			status: statusNameStart,
+			at:     at(3 * time.Hour),
+		},
+		{
+			// 直後のログが既に無効な場合は、挿入によって無効になるわけではないため受け付ける
+			name:   "following log already invalid",
+			logs:   []model.Log{testLog(1, statusNameEnd, at(time.Hour))},
+			status: statusNameStart,
+			at:     at(0),
+		},
+		{
+			// 同時刻の既存のログは新しいログより前として扱う
+			name:   "end at the same time as the start",
+			logs:   []model.Log{testLog(1, statusNameStart, at(0))},
+			status: statusNameEnd,
+			at:     at(0),
+		},
+		{
+			name:    "end after an orphaned start",
+			logs:    []model.Log{testLog(1, statusNameStart, at(0))},
+			status:  statusNameEnd,
+			at:      at(sessionOrphanAfter + time.Minute),
+			wantErr: "end while event is idle",
+		},
+		{
+			name:   "start after an orphaned start",
+			logs:   []model.Log{testLog(1, statusNameStart, at(0))},
+			status: statusNameStart,
+			at:     at(sessionOrphanAfter + time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkLogTransition(tt.logs, tt.status, tt.at)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkLogTransition() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkLogTransition() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
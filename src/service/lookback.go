@@ -0,0 +1,112 @@
+package service
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// Lookback は活動確率の計算に使う過去のログの期間を表す。ゼロ値は全期間を表す
+type Lookback struct {
+	Weeks int    `json:"weeks,omitempty"` // 今日を含む直近 Weeks 週間
+	Since string `json:"since,omitempty"` // この日（JST, YYYY-MM-DD）以降
+}
+
+// defaultLookback 期間を指定しない場合に使う期間（起動時に環境変数から読み込む）
+var defaultLookback Lookback
+
+// NewLookback は週数または開始日から期間を作る。両方を指定した場合はエラーとする
+func NewLookback(weeks int, since string) (Lookback, error) {
+	if weeks != 0 && since != "" {
+		return Lookback{}, invalidInputError("specify either weeks or since")
+	}
+	if weeks < 0 {
+		return Lookback{}, invalidInputError("weeks must be positive: %d", weeks)
+	}
+	if since != "" {
+		if _, err := time.ParseInLocation("2006-01-02", since, lib.JST); err != nil {
+			return Lookback{}, invalidInputError("invalid since (expected YYYY-MM-DD): %s", since)
+		}
+	}
+	return Lookback{Weeks: weeks, Since: since}, nil
+}
+
+// loadDefaultLookback は環境変数 PREDICTION_LOOKBACK_WEEKS から既定の期間を読み込む。未設定・不正な値の場合は全期間とする
+func loadDefaultLookback() Lookback {
+	s := getEnv("PREDICTION_LOOKBACK_WEEKS", "")
+	if s == "" {
+		return Lookback{}
+	}
+	weeks, err := strconv.Atoi(s)
+	if err != nil || weeks < 0 {
+		log.Printf("invalid PREDICTION_LOOKBACK_WEEKS %q, using all history", s)
+		return Lookback{}
+	}
+	return Lookback{Weeks: weeks}
+}
+
+// DefaultLookback は期間を指定しない場合に使う期間を返す
+func DefaultLookback() Lookback {
+	return defaultLookback
+}
+
+// from は期間の開始時刻を返す。全期間の場合はゼロ値を返す
+func (l Lookback) from(now time.Time) time.Time {
+	if l.Since != "" {
+		since, err := time.ParseInLocation("2006-01-02", l.Since, lib.JST)
+		if err == nil {
+			return since
+		}
+	}
+	if l.Weeks > 0 {
+		return truncateToDateJST(now).AddDate(0, 0, -7*l.Weeks+1)
+	}
+	return time.Time{}
+}
+
+// readPredictionLogs は活動確率の計算に使う指定曜日のログと、確率の分母となる観測機会の週（経過週数）を取得する
+// 期間の絞り込みはSQLで行う。週数は期間の開始（全期間の場合やイベントの最初のログの方が新しい場合は最初のログ）から数える
+// 学事暦で祝日・休業などとされた日のログは使わず、その週を分母から除く
+func readPredictionLogs(eventID uint, dayOfWeek time.Weekday, lookback Lookback) ([]model.Log, []int, error) {
+	now := lib.NowJST()
+	from := lookback.from(now)
+
+	var logs []model.Log
+	var err error
+	if from.IsZero() {
+		logs, err = model.ReadLogsByEventIDAndDayOfWeek(eventID, dayOfWeek)
+	} else {
+		logs, err = model.ReadLogsByEventIDAndDayOfWeekSince(eventID, dayOfWeek, from)
+	}
+	if err != nil || len(logs) == 0 {
+		return logs, nil, err
+	}
+
+	if from.IsZero() {
+		from = oldestLogTime(logs)
+	} else {
+		// 記録を始める前の週は観測機会に含めない
+		first, err := model.ReadFirstLogTimeByEventID(eventID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if first.After(from) {
+			from = first
+		}
+	}
+
+	excluded, err := excludedCalendarDates(from, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return excludeCalendarLogs(logs, excluded), calculateWeekAges(from, dayOfWeek, excluded), nil
+}
+
+// weeksSince は t から now までの週数（t を含む週を1週目とする）を返す
+func weeksSince(t, now time.Time) int {
+	days := int(now.Sub(t).Hours() / 24)
+	return (days / 7) + 1
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+)
+
+// modelCacheTTL 当てはめたモデルを再利用する最大時間
+// ログの追加・修正時は即座に破棄するが、DBを直接更新した場合に備えて一定時間で当てはめ直す
+const modelCacheTTL = 10 * time.Minute
+
+// maxModelCacheEntries 保持するモデルの最大数
+// 期間（lookback）は API の呼び出し元が指定するため、超えた場合は最後に使われてから最も時間が経ったものから取り除く
+const maxModelCacheEntries = 256
+
+// logDataVersion はログ・学事暦が更新されるたびに増える版数
+var logDataVersion atomic.Uint64
+
+// modelCacheKey はモデルを当てはめた条件を表す
+// 重み付けと週数は日付によって変わるため、当てはめた日（JST）も含める
+// 期間は開始日に正規化し、同じ日から始まる weeks と since の指定、不正な since と全期間はそれぞれ同じキーとする
+type modelCacheKey struct {
+	eventID    uint
+	spec       prediction.ModelSpec
+	weekday    time.Weekday
+	version    uint64
+	date       string
+	from       string               // 期間の開始日（JST）。全期間は空文字列
+	estimator  prediction.Estimator // Now はゼロ値とする
+	uniqueDate bool
+}
+
+// modelCacheEntry は当てはめたモデルを表す
+type modelCacheEntry struct {
+	fit      *modelFit
+	fittedAt time.Time
+	usedAt   time.Time // 最後に使われた時刻（件数が上限を超えた場合に取り除く順を決める）
+}
+
+// modelFit は当てはめたモデルと、当てはめに使ったデータを表す
+type modelFit struct {
+	model      prediction.Model
+	ages       []int // 確率の分母とした観測機会の週（経過週数）
+	datetimes  []string
+	estimator  prediction.Estimator
+	uniqueDate bool
+
+	bootstrapOnce sync.Once
+	replicates    []prediction.Model
+	bootstrapErr  error
+}
+
+// bootstrap はブートストラップで当てはめ直したモデルを返す
+// 計算に時間がかかるため、詳細を要求された場合に初めて計算し、以降はモデルと共に再利用する
+func (f *modelFit) bootstrap() ([]prediction.Model, error) {
+	f.bootstrapOnce.Do(func() {
+		f.replicates, f.bootstrapErr = prediction.BootstrapDatetimes(f.model, f.datetimes, f.ages, f.estimator, f.uniqueDate, prediction.DefaultBootstrapReplicates)
+	})
+	return f.replicates, f.bootstrapErr
+}
+
+// modelCache イベント・曜日ごとの活動開始時刻のモデル（プロセス内で共有する）
+var modelCache = struct {
+	sync.Mutex
+	entries map[modelCacheKey]modelCacheEntry
+}{entries: make(map[modelCacheKey]modelCacheEntry)}
+
+// invalidateModelCache はログ・学事暦の更新時に、当てはめたモデルをすべて破棄する
+func invalidateModelCache() {
+	logDataVersion.Add(1)
+	modelCache.Lock()
+	defer modelCache.Unlock()
+	modelCache.entries = make(map[modelCacheKey]modelCacheEntry)
+}
+
+// startTimeModel はイベントの指定曜日の活動開始時刻のモデルを返す
+// 同じ条件で当てはめたモデルがあれば再利用し、なければ lookback の期間の start ログから当てはめる
+// uniqueDate の場合は日付ごとに最初の開始時刻のみを使う。モデルの種類と時刻の扱いはイベントの設定に従う
+func startTimeModel(event model.Event, dayOfWeek time.Weekday, lookback Lookback, uniqueDate bool) (prediction.Model, error) {
+	fit, err := startTimeFit(event, dayOfWeek, lookback, uniqueDate)
+	if err != nil {
+		return nil, err
+	}
+	return fit.model, nil
+}
+
+// startTimeFit は startTimeModel と同じ条件で当てはめたモデルを、当てはめに使ったデータと共に返す
+func startTimeFit(event model.Event, dayOfWeek time.Weekday, lookback Lookback, uniqueDate bool) (*modelFit, error) {
+	estimator := CurrentEstimator()
+	now := estimator.Now
+	keyEstimator := estimator
+	keyEstimator.Now = time.Time{}
+	key := modelCacheKey{
+		eventID:    event.ID,
+		spec:       eventModelSpec(event),
+		weekday:    dayOfWeek,
+		version:    logDataVersion.Load(),
+		date:       now.Format("2006-01-02"),
+		from:       lookbackCacheFrom(lookback, now),
+		estimator:  keyEstimator,
+		uniqueDate: uniqueDate,
+	}
+
+	if fit, ok := loadModelCache(key, now); ok {
+		return fit, nil
+	}
+
+	logs, ages, err := readPredictionLogs(event.ID, dayOfWeek, lookback)
+	if err != nil {
+		return nil, err
+	}
+	datetimes := extractStartDatetimes(logs)
+	spec := eventModelSpec(event)
+	m, err := prediction.FitDatetimes(datetimes, ages, estimator, uniqueDate, spec)
+	if err != nil {
+		return nil, err
+	}
+	fit := &modelFit{model: m, ages: ages, datetimes: datetimes, estimator: estimator, uniqueDate: uniqueDate}
+	storeModelCache(key, fit, lib.NowJST())
+	return fit, nil
+}
+
+// lookbackCacheFrom は now の時点の期間の開始日をキャッシュのキーに使う形で返す。全期間は空文字列とする
+func lookbackCacheFrom(lookback Lookback, now time.Time) string {
+	from := lookback.from(now)
+	if from.IsZero() {
+		return ""
+	}
+	return from.In(lib.JST).Format("2006-01-02")
+}
+
+// loadModelCache は key で保存した TTL 内のモデルを返し、使われた時刻を now に更新する
+func loadModelCache(key modelCacheKey, now time.Time) (*modelFit, bool) {
+	modelCache.Lock()
+	defer modelCache.Unlock()
+	entry, ok := modelCache.entries[key]
+	if !ok || now.Sub(entry.fittedAt) >= modelCacheTTL {
+		return nil, false
+	}
+	entry.usedAt = now
+	modelCache.entries[key] = entry
+	return entry.fit, true
+}
+
+// storeModelCache は fittedAt に当てはめたモデルを key で保存する
+// 当てはめ中にログが更新された場合、古い版数のキーで保存したモデルは参照されない
+// 参照されなくなったモデル（古い版数・前日の日付）は TTL を過ぎたものから取り除き、
+// それでも maxModelCacheEntries 件に達している場合は最後に使われてから最も時間が経ったものを取り除く
+func storeModelCache(key modelCacheKey, fit *modelFit, fittedAt time.Time) {
+	modelCache.Lock()
+	defer modelCache.Unlock()
+	for k, e := range modelCache.entries {
+		if fittedAt.Sub(e.fittedAt) >= modelCacheTTL {
+			delete(modelCache.entries, k)
+		}
+	}
+	if _, ok := modelCache.entries[key]; !ok {
+		for len(modelCache.entries) >= maxModelCacheEntries {
+			var oldest modelCacheKey
+			var oldestUsedAt time.Time
+			first := true
+			for k, e := range modelCache.entries {
+				if first || e.usedAt.Before(oldestUsedAt) {
+					oldest, oldestUsedAt, first = k, e.usedAt, false
+				}
+			}
+			delete(modelCache.entries, oldest)
+		}
+	}
+	modelCache.entries[key] = modelCacheEntry{fit: fit, fittedAt: fittedAt, usedAt: fittedAt}
+}
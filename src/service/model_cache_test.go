@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+)
+
+func TestLookbackCacheFrom(t *testing.T) {
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+
+	tests := []struct {
+		name     string
+		lookback Lookback
+		want     string
+	}{
+		{name: "all history", lookback: Lookback{}, want: ""},
+		{name: "weeks", lookback: Lookback{Weeks: 4}, want: "2025-05-17"},
+		// 同じ日から始まる weeks と since は同じキーになる
+		{name: "since", lookback: Lookback{Since: "2025-05-17"}, want: "2025-05-17"},
+		{name: "invalid since is all history", lookback: Lookback{Since: "yesterday"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookbackCacheFrom(tt.lookback, now); got != tt.want {
+				t.Errorf("lookbackCacheFrom(%+v) = %q, want %q", tt.lookback, got, tt.want)
+			}
+		})
+	}
+}
+
+// resetModelCache はテストの間だけモデルのキャッシュを空にする
+func resetModelCache(t *testing.T) {
+	t.Helper()
+	modelCache.Lock()
+	original := modelCache.entries
+	modelCache.entries = make(map[modelCacheKey]modelCacheEntry)
+	modelCache.Unlock()
+	t.Cleanup(func() {
+		modelCache.Lock()
+		modelCache.entries = original
+		modelCache.Unlock()
+	})
+}
+
+func TestModelCacheTTL(t *testing.T) {
+	resetModelCache(t)
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+	key := modelCacheKey{eventID: 1}
+	fit := &modelFit{}
+	storeModelCache(key, fit, now)
+
+	if got, ok := loadModelCache(key, now.Add(modelCacheTTL-time.Second)); !ok || got != fit {
+		t.Errorf("loadModelCache() before TTL = %v, %v, want the stored fit", got, ok)
+	}
+	if _, ok := loadModelCache(key, now.Add(modelCacheTTL)); ok {
+		t.Error("loadModelCache() after TTL ok = true, want false")
+	}
+	if _, ok := loadModelCache(modelCacheKey{eventID: 2}, now); ok {
+		t.Error("loadModelCache() for another key ok = true, want false")
+	}
+}
+
+func TestModelCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	resetModelCache(t)
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, lib.JST)
+	for i := 0; i < maxModelCacheEntries; i++ {
+		storeModelCache(modelCacheKey{eventID: uint(i)}, &modelFit{}, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	// 最も古く保存したモデルも、使われれば残る
+	if _, ok := loadModelCache(modelCacheKey{eventID: 0}, now.Add(time.Second)); !ok {
+		t.Fatal("loadModelCache() ok = false for a stored key")
+	}
+
+	// 呼び出し元が任意の期間を指定しても、件数は上限を超えない
+	for i := 0; i < 10; i++ {
+		storeModelCache(modelCacheKey{eventID: 1, from: time.Date(2024, 1, 1+i, 0, 0, 0, 0, lib.JST).Format("2006-01-02")},
+			&modelFit{}, now.Add(2*time.Second))
+	}
+	modelCache.Lock()
+	size := len(modelCache.entries)
+	_, keptUsed := modelCache.entries[modelCacheKey{eventID: 0}]
+	_, keptOldest := modelCache.entries[modelCacheKey{eventID: 1}]
+	_, keptNewer := modelCache.entries[modelCacheKey{eventID: 11}]
+	modelCache.Unlock()
+
+	if size != maxModelCacheEntries {
+		t.Errorf("cache size = %d, want %d", size, maxModelCacheEntries)
+	}
+	if !keptUsed {
+		t.Error("recently used entry was evicted")
+	}
+	if keptOldest {
+		t.Error("least recently used entry was kept")
+	}
+	if !keptNewer {
+		t.Error("entry used more recently than the evicted ones was evicted")
+	}
+}
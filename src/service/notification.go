@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"log"
 	"sort"
 	"strings"
 	"time"
@@ -13,26 +14,93 @@ import (
 type EventActivity struct {
 	EventID           uint
 	EventName         string
+	Probability       float64
 	RecommendedRanges []TimeRange
 	FilteredUsers     []model.User
 	Predictions       []Prediction
 }
 
-// NotifyByEvent はイベントベースの通知を生成する
-func NotifyByEvent(targetWeekday time.Weekday) ([]model.User, map[int]map[int][]string) {
+// 通知対象を決める閾値
+const (
+	activityProbabilityThreshold = 0.30    // この確率未満の活動は通知しない
+	activityProbabilityTime      = "17:59" // 活動確率を評価する時刻
+	visitProbabilityThreshold    = 0.3     // この来訪確率未満のメンバーは来訪予定者に含めない
+)
+
+// EventDiagnosis は通知生成時のイベント1件分の判定結果を表す
+// Included が false の場合、Reason に通知対象外となった理由が入る
+type EventDiagnosis struct {
+	EventID                   uint               `json:"event_id"`
+	EventName                 string             `json:"event_name"`
+	Included                  bool               `json:"included"`
+	Reason                    string             `json:"reason,omitempty"`
+	Probability               float64            `json:"probability"`
+	ProbabilityThreshold      float64            `json:"probability_threshold"`
+	ActivityRange             *ActivityTimeRange `json:"activity_range,omitempty"`
+	MinNumber                 int                `json:"min_number"`
+	VisitProbabilityThreshold float64            `json:"visit_probability_threshold"`
+	Members                   []MemberDiagnosis  `json:"members"`
+	OccupancyRanges           []TimeRange        `json:"occupancy_ranges"`
+	RecommendedRanges         []TimeRange        `json:"recommended_ranges"`
+}
+
+// MemberDiagnosis はイベント登録メンバー1人分の来訪予測を表す
+type MemberDiagnosis struct {
+	UserID           uint    `json:"user_id"`
+	Name             string  `json:"name"`
+	VisitProbability float64 `json:"visit_probability"`
+	AboveThreshold   bool    `json:"above_threshold"` // filterByThreshold を通過したか
+	Visit            string  `json:"visit,omitempty"`
+	Departure        string  `json:"departure,omitempty"`
+}
+
+// NotifyByEvent は対象日（JST）のイベントベースの通知を生成する
+// 学事暦で祝日・休業などとされた日は通知しない
+func NotifyByEvent(targetDate time.Time) ([]model.User, map[int]map[int][]string) {
+	users, userMessages, _, _ := buildNotifications(targetDate)
+	return users, userMessages
+}
+
+// buildNotifications はユーザーごとの通知メッセージを生成し、イベントごとの判定結果と
+// 通知設定により通知しないユーザーの理由（ユーザーID → 理由）とともに返す
+func buildNotifications(targetDate time.Time) ([]model.User, map[int]map[int][]string, []EventDiagnosis, map[uint]string) {
 	userMessages := make(map[int]map[int][]string)
+	skipReasons := make(map[uint]string)
+	targetWeekday := truncateToDateJST(targetDate).Weekday()
 
 	var e model.Event
 	events, err := e.ReadAllWithUsers()
 	if err != nil {
 		var u model.User
 		users, _ := u.ReadAll()
-		return users, userMessages
+		return users, userMessages, nil, skipReasons
+	}
+
+	// 祝日・休業などの日は通常の曜日のログから予測できないため、すべてのイベントを通知対象外とする
+	day, err := GetCalendarDay(targetDate)
+	if err != nil {
+		log.Printf("failed to read academic calendar for %s: %v", day.Date, err)
+	} else if day.Excluded {
+		var u model.User
+		users, _ := u.ReadAll()
+		return users, userMessages, excludedDayDiagnoses(events, day), skipReasons
 	}
 
-	userEventActivities := collectUserEventActivities(events, targetWeekday)
+	userEventActivities, diagnoses := collectUserEventActivities(events, targetDate)
+	preferences := readNotificationPreferences()
 
 	for userID, activities := range userEventActivities {
+		preference := preferences[userID]
+		if reason := preferenceSkipReason(preference, targetWeekday); reason != "" {
+			skipReasons[userID] = reason
+			continue
+		}
+		activities = filterActivitiesByPreference(activities, preference)
+		if len(activities) == 0 {
+			skipReasons[userID] = "all recommended events are muted or below the personal probability threshold"
+			continue
+		}
+
 		msg := buildUserNotificationMessage(userID, activities)
 		if msg == "" {
 			continue
@@ -46,16 +114,18 @@ func NotifyByEvent(targetWeekday time.Weekday) ([]model.User, map[int]map[int][]
 
 	var u model.User
 	users, _ := u.ReadAll()
-	return users, userMessages
+	return users, userMessages, diagnoses, skipReasons
 }
 
-// collectUserEventActivities は各イベントを処理し、ユーザーごとの活動情報を収集する
-func collectUserEventActivities(events []model.Event, targetWeekday time.Weekday) map[uint][]EventActivity {
+// collectUserEventActivities は各イベントを処理し、ユーザーごとの活動情報とイベントごとの判定結果を収集する
+func collectUserEventActivities(events []model.Event, targetDate time.Time) (map[uint][]EventActivity, []EventDiagnosis) {
 	userEventActivities := make(map[uint][]EventActivity)
+	diagnoses := make([]EventDiagnosis, 0, len(events))
 
 	for _, event := range events {
-		activity, eventUsers, ok := processEvent(event, targetWeekday)
-		if !ok {
+		activity, eventUsers, diagnosis := processEvent(event, targetDate, DefaultLookback())
+		diagnoses = append(diagnoses, diagnosis)
+		if !diagnosis.Included {
 			continue
 		}
 		for _, eventUser := range eventUsers {
@@ -63,57 +133,139 @@ func collectUserEventActivities(events []model.Event, targetWeekday time.Weekday
 		}
 	}
 
-	return userEventActivities
+	return userEventActivities, diagnoses
 }
 
-// processEvent は1イベントの活動確率・推奨時間を計算し、EventActivityを返す
-func processEvent(event model.Event, targetWeekday time.Weekday) (EventActivity, []model.User, bool) {
-	probability, err := GetActivityProbability(event.ID, targetWeekday, "17:59")
-	if err != nil || probability < 0.30 {
-		return EventActivity{}, nil, false
+// excludedDayDiagnoses は学事暦で除外する日の、イベントごとの判定結果を作る
+func excludedDayDiagnoses(events []model.Event, day CalendarDay) []EventDiagnosis {
+	diagnoses := make([]EventDiagnosis, 0, len(events))
+	for _, event := range events {
+		diagnoses = append(diagnoses, EventDiagnosis{
+			EventID:                   event.ID,
+			EventName:                 event.Name,
+			Reason:                    day.excludedReason(),
+			ProbabilityThreshold:      activityProbabilityThreshold,
+			MinNumber:                 event.MinNumber,
+			VisitProbabilityThreshold: visitProbabilityThreshold,
+			Members:                   []MemberDiagnosis{},
+			OccupancyRanges:           []TimeRange{},
+			RecommendedRanges:         []TimeRange{},
+		})
+	}
+	return diagnoses
+}
+
+// processEvent は対象日（JST）の1イベントの活動確率・推奨時間を lookback の期間のログから計算し、EventActivityと判定結果を返す
+// 通知対象外の場合は判定結果の Included が false となる
+func processEvent(event model.Event, targetDate time.Time, lookback Lookback) (EventActivity, []model.User, EventDiagnosis) {
+	targetWeekday := truncateToDateJST(targetDate).Weekday()
+	diagnosis := EventDiagnosis{
+		EventID:                   event.ID,
+		EventName:                 event.Name,
+		ProbabilityThreshold:      activityProbabilityThreshold,
+		MinNumber:                 event.MinNumber,
+		VisitProbabilityThreshold: visitProbabilityThreshold,
+		Members:                   []MemberDiagnosis{},
+		OccupancyRanges:           []TimeRange{},
+		RecommendedRanges:         []TimeRange{},
+	}
+
+	probability, err := GetActivityProbability(event, targetWeekday, activityProbabilityTime, lookback)
+	diagnosis.Probability = probability
+	if err != nil {
+		diagnosis.Reason = fmt.Sprintf("failed to calculate activity probability: %v", err)
+		return EventActivity{}, nil, diagnosis
+	}
+	if probability < activityProbabilityThreshold {
+		diagnosis.Reason = fmt.Sprintf("activity probability %.2f is below threshold %.2f", probability, activityProbabilityThreshold)
+		return EventActivity{}, nil, diagnosis
 	}
 
-	activityRange, err := getActivityTimeRange(event.ID, targetWeekday)
+	activityRange, err := getActivityTimeRange(event, targetWeekday, lookback)
 	if err != nil {
-		return EventActivity{}, nil, false
+		diagnosis.Reason = fmt.Sprintf("failed to predict activity time range: %v", err)
+		return EventActivity{}, nil, diagnosis
 	}
+	diagnosis.ActivityRange = &activityRange
 
 	var eventUsers []model.User
 	for _, eu := range event.EventUsers {
 		eventUsers = append(eventUsers, eu.User)
 	}
 	if len(eventUsers) == 0 {
-		return EventActivity{}, nil, false
+		diagnosis.Reason = "no users registered for this event"
+		return EventActivity{}, nil, diagnosis
 	}
 
 	probs := GetStayWatchProbability(eventUsers, targetWeekday)
-	filtered := filterByThreshold(probs, 0.3)
+	filtered := filterByThreshold(probs, visitProbabilityThreshold)
+	diagnosis.Members = diagnoseMembers(eventUsers, probs)
 	if len(filtered) == 0 {
-		return EventActivity{}, nil, false
+		diagnosis.Reason = fmt.Sprintf("no members with visit probability >= %.2f", visitProbabilityThreshold)
+		return EventActivity{}, nil, diagnosis
 	}
 
 	visitTimes := fetchPredictionTime(filtered, targetWeekday, "visit")
 	departureTimes := fetchPredictionTime(filtered, targetWeekday, "departure")
 	predictions := mergePredictions(visitTimes, departureTimes)
+	applyPredictionsToMembers(diagnosis.Members, eventUsers, predictions)
 
 	occupancyRanges := findOverlappingRanges(predictions, eventUsers, event.MinNumber)
 	if len(occupancyRanges) == 0 {
-		return EventActivity{}, nil, false
+		diagnosis.Reason = fmt.Sprintf("no time range with at least %d members predicted to be present", event.MinNumber)
+		return EventActivity{}, nil, diagnosis
 	}
+	diagnosis.OccupancyRanges = occupancyRanges
 
 	recommendedRanges := calculateRecommendedTimeRanges(activityRange, occupancyRanges)
 	if len(recommendedRanges) == 0 {
-		return EventActivity{}, nil, false
+		diagnosis.Reason = fmt.Sprintf("activity range %s〜%s does not overlap any occupancy range", activityRange.Start, activityRange.End)
+		return EventActivity{}, nil, diagnosis
 	}
+	diagnosis.RecommendedRanges = recommendedRanges
+	diagnosis.Included = true
 
 	activity := EventActivity{
 		EventID:           event.ID,
 		EventName:         event.Name,
+		Probability:       probability,
 		RecommendedRanges: recommendedRanges,
 		FilteredUsers:     filtered,
 		Predictions:       predictions,
 	}
-	return activity, eventUsers, true
+	return activity, eventUsers, diagnosis
+}
+
+// diagnoseMembers はイベント登録メンバーごとの来訪確率と閾値判定結果を作る
+func diagnoseMembers(users []model.User, probs []Probability) []MemberDiagnosis {
+	probByStayWatchID := make(map[int64]float64, len(probs))
+	for _, p := range probs {
+		probByStayWatchID[int64(p.UserID)] = p.Probability
+	}
+
+	members := make([]MemberDiagnosis, 0, len(users))
+	for _, user := range users {
+		p := probByStayWatchID[user.StayWatchID]
+		members = append(members, MemberDiagnosis{
+			UserID:           user.ID,
+			Name:             user.Name,
+			VisitProbability: p,
+			AboveThreshold:   p >= visitProbabilityThreshold,
+		})
+	}
+	return members
+}
+
+// applyPredictionsToMembers はメンバーの判定結果に予測来訪・退室時刻を反映する
+func applyPredictionsToMembers(members []MemberDiagnosis, users []model.User, predictions []Prediction) {
+	for i := range members {
+		visit, departure, found := findPredictionForUser(users[i].StayWatchID, predictions)
+		if !found {
+			continue
+		}
+		members[i].Visit = visit
+		members[i].Departure = departure
+	}
 }
 
 // aggregateActivities は複数のEventActivityからヘッダー・ユーザー・予測情報を集約する
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// NotificationPreferenceInput は通知設定の更新内容を表す
+type NotificationPreferenceInput struct {
+	Muted          bool
+	MutedWeekdays  []int  // MySQL WEEKDAY形式（0=月〜6=日）
+	MutedEventIDs  []uint // 通知しないイベントID
+	DeliveryTime   string // "HH:MM"（空なら通知実行時に送信）
+	MinProbability float64
+}
+
+// GetNotificationPreference はSlackユーザーIDからユーザーと通知設定を取得する
+// 通知設定が未作成の場合はゼロ値（すべて通知する設定）を返す
+func GetNotificationPreference(slackUserID string) (model.User, model.NotificationPreference, error) {
+	user := model.User{SlackID: slackUserID}
+	if err := user.ReadBySlackID(); err != nil {
+		return user, model.NotificationPreference{}, err
+	}
+	if user.ID == 0 {
+		return user, model.NotificationPreference{}, notFoundError("user not found")
+	}
+
+	preference := model.NotificationPreference{UserID: user.ID}
+	if err := preference.ReadByUserID(); err != nil {
+		return user, preference, err
+	}
+	return user, preference, nil
+}
+
+// UpdateNotificationPreference はSlackユーザーIDのユーザーの通知設定を更新する
+func UpdateNotificationPreference(slackUserID string, input NotificationPreferenceInput) (model.NotificationPreference, error) {
+	if input.DeliveryTime != "" {
+		if _, err := time.Parse("15:04", input.DeliveryTime); err != nil {
+			return model.NotificationPreference{}, invalidInputError("invalid delivery time: %s", input.DeliveryTime)
+		}
+	}
+	if input.MinProbability < 0 || input.MinProbability > 1 {
+		return model.NotificationPreference{}, invalidInputError("min probability must be between 0 and 1")
+	}
+	for _, w := range input.MutedWeekdays {
+		if w < 0 || w > 6 {
+			return model.NotificationPreference{}, invalidInputError("invalid weekday: %d", w)
+		}
+	}
+
+	_, preference, err := GetNotificationPreference(slackUserID)
+	if err != nil {
+		return preference, err
+	}
+
+	weekdays := make([]string, len(input.MutedWeekdays))
+	for i, w := range input.MutedWeekdays {
+		weekdays[i] = strconv.Itoa(w)
+	}
+	preference.Muted = input.Muted
+	preference.MutedWeekdays = strings.Join(weekdays, ",")
+	preference.MutedEventIDs = joinEventIDs(input.MutedEventIDs)
+	preference.DeliveryTime = input.DeliveryTime
+	preference.MinProbability = input.MinProbability
+
+	if err := preference.Save(); err != nil {
+		return preference, err
+	}
+	return preference, nil
+}
+
+// ParseMutedWeekdays は通知設定の通知しない曜日を MySQL WEEKDAY形式のスライスにする
+func ParseMutedWeekdays(preference model.NotificationPreference) []int {
+	var weekdays []int
+	for _, part := range splitList(preference.MutedWeekdays) {
+		if w, err := strconv.Atoi(part); err == nil {
+			weekdays = append(weekdays, w)
+		}
+	}
+	return weekdays
+}
+
+// ParseMutedEventIDs は通知設定の通知しないイベントIDをスライスにする
+func ParseMutedEventIDs(preference model.NotificationPreference) []uint {
+	var eventIDs []uint
+	for _, part := range splitList(preference.MutedEventIDs) {
+		if id, err := strconv.ParseUint(part, 10, 32); err == nil {
+			eventIDs = append(eventIDs, uint(id))
+		}
+	}
+	return eventIDs
+}
+
+// splitList はカンマ区切りの文字列を空要素を除いて分割する
+func splitList(s string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// readNotificationPreferences は全ユーザーの通知設定をユーザーIDごとのマップで取得する
+func readNotificationPreferences() map[uint]model.NotificationPreference {
+	var p model.NotificationPreference
+	preferences, err := p.ReadAll()
+	if err != nil {
+		return map[uint]model.NotificationPreference{}
+	}
+	m := make(map[uint]model.NotificationPreference, len(preferences))
+	for _, preference := range preferences {
+		m[preference.UserID] = preference
+	}
+	return m
+}
+
+// preferenceSkipReason は通知設定により対象日の通知をすべて送らない場合、その理由を返す
+func preferenceSkipReason(preference model.NotificationPreference, targetWeekday time.Weekday) string {
+	if preference.Muted {
+		return "muted by user preference"
+	}
+	mysqlWeekday := (int(targetWeekday) + 6) % 7
+	for _, w := range ParseMutedWeekdays(preference) {
+		if w == mysqlWeekday {
+			return "target weekday is muted by user preference"
+		}
+	}
+	return ""
+}
+
+// filterActivitiesByPreference は通知設定で除外されたイベントや、
+// 個人の確率下限に満たないイベントを取り除く
+func filterActivitiesByPreference(activities []EventActivity, preference model.NotificationPreference) []EventActivity {
+	muted := make(map[uint]bool)
+	for _, id := range ParseMutedEventIDs(preference) {
+		muted[id] = true
+	}
+
+	var filtered []EventActivity
+	for _, activity := range activities {
+		if muted[activity.EventID] {
+			continue
+		}
+		if activity.Probability < preference.MinProbability {
+			continue
+		}
+		filtered = append(filtered, activity)
+	}
+	return filtered
+}
+
+// scheduledDeliveryTime は希望送信時刻のうち now 以降で最も早い時刻を返す
+// 希望送信時刻が未設定の場合はゼロ値を返す
+func scheduledDeliveryTime(preference model.NotificationPreference, now time.Time) time.Time {
+	if preference.DeliveryTime == "" {
+		return time.Time{}
+	}
+	minutes, err := lib.TimeToMinutes(preference.DeliveryTime)
+	if err != nil {
+		return time.Time{}
+	}
+	at := truncateToDateJST(now).Add(time.Duration(minutes) * time.Minute)
+	if at.Before(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
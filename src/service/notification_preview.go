@@ -0,0 +1,92 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// NotificationPreview は送信せずに生成した通知内容を表す
+type NotificationPreview struct {
+	TargetDate string             `json:"target_date"`
+	Calendar   CalendarDay        `json:"calendar"` // 対象日の学事暦（除外する日は通知しない）
+	Events     []EventDiagnosis   `json:"events"`
+	Recipients []RecipientPreview `json:"recipients"`
+}
+
+// RecipientPreview はイベント登録ユーザー1人分の通知内容を表す
+// Message が空の場合、Reason にDMが送信されない理由が入る
+type RecipientPreview struct {
+	UserID   uint   `json:"user_id"`
+	Name     string `json:"name"`
+	SlackID  string `json:"slack_id"`
+	EventIDs []uint `json:"event_ids"`
+	Message  string `json:"message"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// PreviewNotification は対象日の通知を送信・記録せずに生成し、判定結果とともに返す
+func PreviewNotification(targetDate time.Time) NotificationPreview {
+	targetDate = truncateToDateJST(targetDate)
+	users, userMessages, diagnoses, skipReasons := buildNotifications(targetDate)
+
+	includedEventIDs := make(map[uint]bool)
+	for _, d := range diagnoses {
+		if d.Included {
+			includedEventIDs[d.EventID] = true
+		}
+	}
+
+	recipients := []RecipientPreview{}
+	for _, user := range users {
+		if len(user.EventUsers) == 0 {
+			continue
+		}
+		eventIDs, message := buildMessageForUser(userMessages[int(user.ID)], user.EventUsers)
+		recipient := RecipientPreview{
+			UserID:   user.ID,
+			Name:     user.Name,
+			SlackID:  user.SlackID,
+			EventIDs: eventIDs,
+			Message:  message,
+		}
+		if message == "" {
+			switch {
+			case !hasIncludedEvent(user.EventUsers, includedEventIDs):
+				recipient.Reason = "none of the registered events passed the notification checks"
+			case skipReasons[user.ID] != "":
+				recipient.Reason = skipReasons[user.ID]
+			default:
+				recipient.Reason = "no common-activity members are predicted to visit"
+			}
+		}
+		recipients = append(recipients, recipient)
+	}
+	sort.Slice(recipients, func(i, j int) bool {
+		return recipients[i].UserID < recipients[j].UserID
+	})
+
+	calendar, err := GetCalendarDay(targetDate)
+	if err != nil {
+		log.Printf("failed to read academic calendar for %s: %v", calendar.Date, err)
+	}
+
+	return NotificationPreview{
+		TargetDate: targetDate.Format("2006-01-02"),
+		Calendar:   calendar,
+		Events:     diagnoses,
+		Recipients: recipients,
+	}
+}
+
+// hasIncludedEvent はユーザーの登録イベントに通知対象となったものがあるかを判定する
+func hasIncludedEvent(eventUsers []model.EventUser, includedEventIDs map[uint]bool) bool {
+	for _, eu := range eventUsers {
+		if includedEventIDs[eu.EventID] {
+			return true
+		}
+	}
+	return false
+}
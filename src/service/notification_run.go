@@ -0,0 +1,359 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/slack-go/slack"
+)
+
+// 通知実行の起動元
+const (
+	NotificationTriggerScheduler = "scheduler"
+	NotificationTriggerHTTP      = "http"
+)
+
+// 通知実行のステータス
+const (
+	notificationStatusRunning   = "running"
+	notificationStatusCompleted = "completed"
+	notificationStatusFailed    = "failed"
+)
+
+// notificationStaleAfter を超えて running のままの実行記録はプロセス停止などで
+// 中断されたとみなし、再実行を許可する
+const notificationStaleAfter = time.Hour
+
+var (
+	// ErrNotificationAlreadyCompleted は対象日の通知が既に完了している場合に返す
+	ErrNotificationAlreadyCompleted = errors.New("notification already completed for target date")
+	// ErrNotificationInProgress は対象日の通知が実行中の場合に返す
+	ErrNotificationInProgress = errors.New("notification already in progress for target date")
+)
+
+// NotificationResult は通知実行1回分の結果を表す
+type NotificationResult struct {
+	NotificationID uint   `json:"notification_id"`
+	TargetDate     string `json:"target_date"`
+	Recipients     int    `json:"recipients"`
+	Sent           int    `json:"sent"`
+	Scheduled      int    `json:"scheduled"` // 希望送信時刻まで送信を保留した件数
+	Failed         int    `json:"failed"`    // 初回送信に失敗し再送待ちとなった件数
+	Skipped        int    `json:"skipped"`   // 中断した実行で送信記録が作成済みのため、新たに送信しなかった件数
+}
+
+// NextNotificationDate は基準時刻の翌日以降で指定曜日となる最初の日付（JST 0時）を返す
+func NextNotificationDate(now time.Time, weekday time.Weekday) time.Time {
+	tomorrow := truncateToDateJST(now).AddDate(0, 0, 1)
+	offset := (int(weekday) - int(tomorrow.Weekday()) + 7) % 7
+	return tomorrow.AddDate(0, 0, offset)
+}
+
+// truncateToDateJST は時刻をJSTの日付（0時）に切り捨てる
+func truncateToDateJST(t time.Time) time.Time {
+	t = t.In(lib.JST)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, lib.JST)
+}
+
+// RunNotification は対象日の活動通知を生成し、該当ユーザーにDMを送信する
+// 同じ対象日の通知が既に完了・実行中の場合は送信せずにエラーを返す
+func RunNotification(targetDate time.Time, trigger string) (NotificationResult, error) {
+	targetDate = truncateToDateJST(targetDate)
+	result := NotificationResult{TargetDate: targetDate.Format("2006-01-02")}
+
+	notification, err := acquireNotification(targetDate, trigger)
+	if err != nil {
+		return result, err
+	}
+	result.NotificationID = notification.ID
+
+	// 中断した実行の再実行では作成済みの送信記録を引き継ぐ
+	// 記録が分からないまま送信すると二重送信になるため、取得できない場合は実行を失敗とする
+	existing, err := readExistingDeliveries(notification.ID)
+	if err != nil {
+		finishedAt := lib.NowJST()
+		notification.FinishedAt = &finishedAt
+		notification.Status = notificationStatusFailed
+		notification.Error = err.Error()
+		if err := notification.Update(); err != nil {
+			log.Printf("failed to update notification %d: %v", notification.ID, err)
+		}
+		return result, fmt.Errorf("failed to read deliveries of notification %d: %w", notification.ID, err)
+	}
+
+	users, userMessages := NotifyByEvent(targetDate)
+	counts := sendNotificationDMs(notification.ID, existing, users, userMessages)
+	result.Recipients = counts.sent + counts.failed + counts.scheduled
+	result.Sent = counts.sent
+	result.Failed = counts.failed
+	result.Scheduled = counts.scheduled
+	result.Skipped = counts.skipped
+
+	finishedAt := lib.NowJST()
+	notification.FinishedAt = &finishedAt
+	notification.Status = notificationStatusCompleted
+	if err := notification.Update(); err != nil {
+		log.Printf("failed to update notification %d: %v", notification.ID, err)
+	}
+
+	return result, nil
+}
+
+// acquireNotification は対象日の実行記録を running 状態で確保する
+func acquireNotification(targetDate time.Time, trigger string) (model.Notification, error) {
+	now := lib.NowJST()
+
+	var notification model.Notification
+	if err := notification.ReadByTargetDate(targetDate); err != nil {
+		return notification, err
+	}
+
+	if notification.ID == 0 {
+		notification = model.Notification{
+			TargetDate:  targetDate,
+			TriggeredBy: trigger,
+			Status:      notificationStatusRunning,
+			StartedAt:   now,
+		}
+		if err := notification.Create(); err != nil {
+			// 同時実行で先に作成された場合はユニーク制約エラー（1062）になる
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				return notification, ErrNotificationInProgress
+			}
+			return notification, err
+		}
+		return notification, nil
+	}
+
+	switch notification.Status {
+	case notificationStatusCompleted:
+		return notification, ErrNotificationAlreadyCompleted
+	case notificationStatusRunning:
+		if now.Sub(notification.StartedAt) < notificationStaleAfter {
+			return notification, ErrNotificationInProgress
+		}
+	}
+
+	// 失敗・中断した実行は再実行する
+	notification.TriggeredBy = trigger
+	notification.Status = notificationStatusRunning
+	notification.StartedAt = now
+	notification.FinishedAt = nil
+	notification.Error = ""
+	if err := notification.Update(); err != nil {
+		return notification, err
+	}
+	return notification, nil
+}
+
+// 送信記録のステータス
+const (
+	deliveryStatusPending  = "pending" // 未送信（希望送信時刻待ちを含む）
+	deliveryStatusSent     = "sent"
+	deliveryStatusRetrying = "retrying"
+	deliveryStatusFailed   = "failed"
+)
+
+// 再送の設定。n回目の失敗後は deliveryRetryBaseDelay * 2^(n-1) 後に再送する
+const (
+	deliveryMaxAttempts    = 5
+	deliveryRetryBaseDelay = time.Minute
+)
+
+// deliveryCounts は sendNotificationDMs の送信結果の件数を表す
+type deliveryCounts struct {
+	sent, failed, scheduled, skipped int
+}
+
+// sendNotificationDMs は各ユーザーの送信記録を作成してDMを送信し、結果の件数を返す
+// 送信記録の作成や送信に失敗したユーザーがいても、失敗数に数えて残りのユーザーへの送信は継続する
+// 希望送信時刻が設定されたユーザーは pending として記録し、その時刻に送信する
+// existing（ユーザーIDごとの作成済みの送信記録）にあるユーザーには送信せず、記録のないユーザーにのみ送信する
+func sendNotificationDMs(notificationID uint, existing map[uint]*model.NotificationDelivery, users []model.User, userMessages map[int]map[int][]string) deliveryCounts {
+	preferences := readNotificationPreferences()
+	now := lib.NowJST()
+	var counts deliveryCounts
+
+	for _, user := range users {
+		eventIDs, message := buildMessageForUser(userMessages[int(user.ID)], user.EventUsers)
+		if message == "" {
+			continue
+		}
+		if d, ok := existing[user.ID]; ok {
+			resumeDelivery(d, now)
+			counts.skipped++
+			continue
+		}
+
+		delivery := model.NotificationDelivery{
+			NotificationID: notificationID,
+			UserID:         user.ID,
+			User:           user,
+			EventIDs:       joinEventIDs(eventIDs),
+			Message:        message,
+			Status:         deliveryStatusPending,
+		}
+		scheduledAt := scheduledDeliveryTime(preferences[user.ID], now)
+		if !scheduledAt.IsZero() && scheduledAt.Sub(now) >= time.Minute {
+			delivery.NextAttemptAt = &scheduledAt
+		}
+		if err := delivery.Create(); err != nil {
+			// 同時に再実行された場合など、既に記録がある場合はユニーク制約エラー（1062）になる
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				counts.skipped++
+				continue
+			}
+			// 送信記録がないと再送も一覧もできないため、DMは送らずに失敗として数える
+			log.Printf("failed to record delivery for user %d: %v", user.ID, err)
+			counts.failed++
+			continue
+		}
+		if delivery.NextAttemptAt != nil {
+			counts.scheduled++
+			continue
+		}
+
+		if attemptDelivery(&delivery) {
+			counts.sent++
+		} else {
+			counts.failed++
+		}
+	}
+	return counts
+}
+
+// readExistingDeliveries は通知実行で作成済みの送信記録をユーザーIDごとに取得する
+func readExistingDeliveries(notificationID uint) (map[uint]*model.NotificationDelivery, error) {
+	deliveries, err := model.ReadNotificationDeliveries(model.NotificationDeliveryFilter{NotificationID: notificationID})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[uint]*model.NotificationDelivery, len(deliveries))
+	for i := range deliveries {
+		existing[deliveries[i].UserID] = &deliveries[i]
+	}
+	return existing, nil
+}
+
+// resumeDelivery は中断した実行で作成済みの送信記録を引き継ぐ
+// sent・failed の記録と、送信予定時刻のある pending・retrying の記録はそのまま残す（未送信分は再送処理が送信する）
+// 送信予定時刻のない pending は送信前に中断した記録のため、再送処理で送信されるよう予定時刻を設定する
+func resumeDelivery(delivery *model.NotificationDelivery, now time.Time) {
+	if delivery.Status != deliveryStatusPending || delivery.NextAttemptAt != nil {
+		return
+	}
+	delivery.NextAttemptAt = &now
+	if err := delivery.Update(); err != nil {
+		log.Printf("failed to resume delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// SendDueNotificationDeliveries は送信予定時刻を過ぎた未送信のDM（希望送信時刻待ち・再送待ち）を
+// 送信し、成功数を返す
+func SendDueNotificationDeliveries() (int, error) {
+	deliveries, err := model.ReadDueNotificationDeliveries(lib.NowJST())
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range deliveries {
+		if attemptDelivery(&deliveries[i]) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// attemptDelivery は送信記録1件のDM送信を試行し、結果を記録する
+// 失敗時は試行回数に応じて再送予定時刻を設定し、上限に達した場合は failed とする
+func attemptDelivery(delivery *model.NotificationDelivery) bool {
+	delivery.Attempts++
+	now := lib.NowJST()
+
+	channelID, ts, err := postDM(delivery.User.SlackID, delivery.Message)
+	if err != nil {
+		log.Printf("failed to send DM to %s (SlackID: %s, attempt %d): %v",
+			delivery.User.Name, delivery.User.SlackID, delivery.Attempts, err)
+		delivery.Error = err.Error()
+		if delivery.Attempts >= deliveryMaxAttempts {
+			delivery.Status = deliveryStatusFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(deliveryRetryBaseDelay << (delivery.Attempts - 1))
+			delivery.Status = deliveryStatusRetrying
+			delivery.NextAttemptAt = &next
+		}
+	} else {
+		delivery.Status = deliveryStatusSent
+		delivery.SlackChannel = channelID
+		delivery.SlackTS = ts
+		delivery.Error = ""
+		delivery.NextAttemptAt = nil
+		delivery.SentAt = &now
+	}
+
+	if err := delivery.Update(); err != nil {
+		log.Printf("failed to update delivery %d: %v", delivery.ID, err)
+	}
+	return delivery.Status == deliveryStatusSent
+}
+
+// postDM はSlackユーザーとのDMチャンネルを開いてメッセージを送信する
+func postDM(slackUserID string, message string) (string, string, error) {
+	channel, _, _, err := slackClient.OpenConversation(&slack.OpenConversationParameters{
+		ReturnIM: true,
+		Users:    []string{slackUserID},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open conversation: %w", err)
+	}
+
+	channelID, ts, err := slackClient.PostMessage(channel.ID, slack.MsgOptionText(message, false))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to send message: %w", err)
+	}
+	return channelID, ts, nil
+}
+
+// buildMessageForUser はユーザーが登録しているイベントのメッセージを連結し、
+// 含まれるイベントIDとともに返す
+func buildMessageForUser(eventMessages map[int][]string, eventUsers []model.EventUser) ([]uint, string) {
+	var eventIDs []uint
+	var b strings.Builder
+	for _, eu := range eventUsers {
+		m, ok := eventMessages[int(eu.EventID)]
+		if !ok {
+			continue
+		}
+		eventIDs = append(eventIDs, eu.EventID)
+		for _, v := range m {
+			b.WriteString(v)
+			b.WriteByte('\n')
+		}
+	}
+	return eventIDs, b.String()
+}
+
+// joinEventIDs はイベントIDをカンマ区切りの文字列にする
+func joinEventIDs(eventIDs []uint) string {
+	parts := make([]string, len(eventIDs))
+	for i, id := range eventIDs {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// GetNotificationDeliveries は条件に一致する送信記録を取得する
+func GetNotificationDeliveries(filter model.NotificationDeliveryFilter) ([]model.NotificationDelivery, error) {
+	return model.ReadNotificationDeliveries(filter)
+}
@@ -14,13 +14,14 @@ type TimeRange struct {
 }
 
 // calculateRecommendedTimeRanges 活動推奨時間を計算する
+// 活動の予測時間帯（開始時刻〜開始時刻 + 予測活動時間）と、規定人数在室時間の重なりを推奨時間とする
+// 予測時間帯が0時をまたぐ場合は、0時の前後それぞれとの重なりを求める
 func calculateRecommendedTimeRanges(activityRange ActivityTimeRange, occupancyRanges []TimeRange) []TimeRange {
 	var recommendedRanges []TimeRange
 
-	// activityRange を分単位に変換
-	activityStartMinutes, err1 := lib.TimeToMinutes(activityRange.Start)
-	activityEndMinutes, err2 := lib.TimeToMinutes(activityRange.End)
-	if err1 != nil || err2 != nil {
+	// activityRange を分単位の区間に変換
+	segments, err := activityRange.minuteSegments()
+	if err != nil {
 		return recommendedRanges
 	}
 
@@ -32,15 +33,17 @@ func calculateRecommendedTimeRanges(activityRange ActivityTimeRange, occupancyRa
 			continue
 		}
 
-		// 重なりの計算
-		overlapStart := lib.Max(activityStartMinutes, occupancyStartMinutes)
-		overlapEnd := lib.Min(activityEndMinutes, occupancyEndMinutes)
-
-		if overlapStart < overlapEnd {
-			recommendedRanges = append(recommendedRanges, TimeRange{
-				Start: lib.MinutesToTime(overlapStart),
-				End:   lib.MinutesToTime(overlapEnd),
-			})
+		for _, seg := range segments {
+			// 重なりの計算
+			overlapStart := lib.Max(seg[0], occupancyStartMinutes)
+			overlapEnd := lib.Min(seg[1], occupancyEndMinutes)
+
+			if overlapStart < overlapEnd {
+				recommendedRanges = append(recommendedRanges, TimeRange{
+					Start: lib.MinutesToTime(overlapStart),
+					End:   lib.MinutesToTime(overlapEnd),
+				})
+			}
 		}
 	}
 
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"github.com/kajiLabTeam/stay-watch-slackbot/prediction"
+)
+
+// PredictionDetails は確率の計算に使ったモデルの概要と、ブートストラップによる確率の信頼区間を表す
+// 観測数（samples）や週数（weeks）が少ない予測は信頼区間が広くなるため、表示側で区別できる
+type PredictionDetails struct {
+	prediction.ModelDetails
+	Weeks               int                   `json:"weeks"`                      // 確率の分母とした観測機会の週数
+	ConfidenceLevel     float64               `json:"confidence_level"`           // 信頼区間の水準
+	BootstrapReplicates int                   `json:"bootstrap_replicates"`       // 当てはめ直した回数
+	Interval            *ProbabilityInterval  `json:"interval,omitempty"`         // 指定時刻までの確率の信頼区間
+	HourlyIntervals     []ProbabilityInterval `json:"hourly_intervals,omitempty"` // 各時間帯（0〜23時）の確率の信頼区間
+}
+
+// ProbabilityInterval は確率の信頼区間を表す
+type ProbabilityInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// newPredictionDetails はモデルの概要に週数と信頼区間の設定を加える
+func newPredictionDetails(fit *modelFit, replicates int) *PredictionDetails {
+	return &PredictionDetails{
+		ModelDetails:        fit.model.Details(),
+		Weeks:               len(fit.ages),
+		ConfidenceLevel:     prediction.DefaultConfidenceLevel,
+		BootstrapReplicates: replicates,
+	}
+}
+
+// GetActivityProbabilityWithDetails は GetActivityProbability と同じ確率を、モデルの概要と信頼区間と共に取得する
+// GetActivityProbability と異なり、ログの取得やモデルの当てはめに失敗した場合はエラーを返す
+func GetActivityProbabilityWithDetails(event model.Event, dayOfWeek time.Weekday, targetTime string, lookback Lookback) (float64, *PredictionDetails, error) {
+	targetMinutes, err := lib.TimeToMinutes(targetTime)
+	if err != nil {
+		return 0.0, nil, err
+	}
+
+	fit, err := startTimeFit(event, dayOfWeek, lookback, true)
+	if err != nil {
+		return 0.0, nil, fmt.Errorf("failed to fit start time model: %w", err)
+	}
+	replicates, err := fit.bootstrap()
+	if err != nil {
+		return 0.0, nil, fmt.Errorf("failed to bootstrap start time model: %w", err)
+	}
+
+	values := make([]float64, 0, len(replicates))
+	for _, m := range replicates {
+		values = append(values, m.CDF(float64(targetMinutes)))
+	}
+	lower, upper := prediction.PercentileInterval(values, prediction.DefaultConfidenceLevel)
+	details := newPredictionDetails(fit, len(replicates))
+	details.Interval = &ProbabilityInterval{Lower: lower, Upper: upper}
+	return fit.model.CDF(float64(targetMinutes)), details, nil
+}
+
+// calcHourlyIntervals は当てはめ直したモデルから、各時間帯（JST 0〜23時）の確率の信頼区間を計算する
+func calcHourlyIntervals(replicates []prediction.Model) []ProbabilityInterval {
+	intervals := make([]ProbabilityInterval, 24)
+	values := make([]float64, len(replicates))
+	for hour := 0; hour < 24; hour++ {
+		for i, m := range replicates {
+			values[i] = calcHourProbability(m, hour)
+		}
+		lower, upper := prediction.PercentileInterval(values, prediction.DefaultConfidenceLevel)
+		intervals[hour] = ProbabilityInterval{Lower: lower, Upper: upper}
+	}
+	return intervals
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// ログのステータス名
+// 一時停止は既存データの "pose" と、APIドキュメントの "pause" の両方を受け付ける
+const (
+	statusNameStart = "start"
+	statusNameEnd   = "end"
+	statusNamePose  = "pose"
+	statusNamePause = "pause"
+)
+
+// sessionOrphanAfter 最後のログからこの時間を超えて end がない活動は、終了ログが欠落したとみなす
+const sessionOrphanAfter = 12 * time.Hour
+
+// SessionState は活動ログの状態遷移における状態を表す
+type SessionState int
+
+const (
+	SessionIdle   SessionState = iota // 活動していない
+	SessionActive                     // 活動中
+	SessionPaused                     // 一時停止中
+)
+
+// String は状態名を返す
+func (s SessionState) String() string {
+	switch s {
+	case SessionActive:
+		return "active"
+	case SessionPaused:
+		return "paused"
+	default:
+		return "idle"
+	}
+}
+
+// NextSessionState は状態 state でステータス statusName のログを受け取った後の状態を返す
+// 遷移として不正な場合（活動していないときの end や、活動中の start など）は ok=false を返し、状態は変えない
+//
+//	idle   --start--> active
+//	active --pause--> paused
+//	paused --start--> active（再開）
+//	active/paused --end--> idle
+func NextSessionState(state SessionState, statusName string) (next SessionState, ok bool) {
+	switch {
+	case statusName == statusNameStart && (state == SessionIdle || state == SessionPaused):
+		return SessionActive, true
+	case isPauseStatus(statusName) && state == SessionActive:
+		return SessionPaused, true
+	case statusName == statusNameEnd && (state == SessionActive || state == SessionPaused):
+		return SessionIdle, true
+	}
+	return state, false
+}
+
+// sessionStateAt は時刻順に並んだログを状態遷移させ、時刻 t 時点の状態を返す
+// 不正な遷移のログは無視し、最後の有効なログから sessionOrphanAfter を超えた活動は終了したとみなす
+func sessionStateAt(sortedLogs []model.Log, t time.Time) SessionState {
+	state := SessionIdle
+	var lastEventTime time.Time
+	for _, l := range sortedLogs {
+		if state != SessionIdle && l.EventTime.Sub(lastEventTime) > sessionOrphanAfter {
+			state = SessionIdle
+		}
+		if next, ok := NextSessionState(state, l.Status.Name); ok {
+			state = next
+			lastEventTime = l.EventTime
+		}
+	}
+	if state != SessionIdle && t.Sub(lastEventTime) > sessionOrphanAfter {
+		return SessionIdle
+	}
+	return state
+}
+
+// sortLogsByEventTime はログを発生時刻順（同時刻は ID 順）に並べ替えたコピーを返す
+func sortLogsByEventTime(logs []model.Log) []model.Log {
+	sorted := make([]model.Log, len(logs))
+	copy(sorted, logs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].EventTime.Equal(sorted[j].EventTime) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].EventTime.Before(sorted[j].EventTime)
+	})
+	return sorted
+}
+
+// isPauseStatus は一時停止のステータス名かを判定する
+func isPauseStatus(statusName string) bool {
+	return statusName == statusNamePose || statusName == statusNamePause
+}
+
+// 活動セッションの状態
+const (
+	sessionStatusCompleted = "completed" // end で終了した
+	sessionStatusOngoing   = "ongoing"   // 現在も活動中（end 未到着）
+	sessionStatusOrphaned  = "orphaned"  // end が記録されずに途切れた
+)
+
+// Session は start から end までの1回分の活動を表す
+type Session struct {
+	EventID       uint           `json:"event_id"`
+	Start         time.Time      `json:"start"`
+	End           *time.Time     `json:"end"` // end が記録されていない場合は null
+	Pauses        []SessionPause `json:"pauses"`
+	ActiveMinutes float64        `json:"active_minutes"` // 一時停止を除いた活動時間（分）
+	Status        string         `json:"status"`         // completed / ongoing / orphaned
+	LogIDs        []uint         `json:"log_ids"`
+	Warnings      []string       `json:"warnings,omitempty"` // 無視したログなど、再構成時の注意
+}
+
+// SessionPause は活動中の一時停止1回分を表す
+type SessionPause struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end"` // 再開されないまま終了した場合は活動の終了時刻（活動中は null）
+}
+
+// SessionSummary はセッション一覧の集計を表す
+type SessionSummary struct {
+	Count                int     `json:"count"`
+	Completed            int     `json:"completed"`
+	MedianActiveMinutes  float64 `json:"median_active_minutes"`  // completed のみで集計
+	AverageActiveMinutes float64 `json:"average_active_minutes"` // completed のみで集計
+}
+
+// GetEventSessions はイベントのログを活動セッションに再構成し、開始日が [from, to] のものを返す
+// weekday が nil でない場合は開始日の曜日で絞り込む
+func GetEventSessions(eventID uint, from, to time.Time, weekday *time.Weekday) ([]Session, SessionSummary, error) {
+	if _, err := GetEvent(eventID); err != nil {
+		return nil, SessionSummary{}, err
+	}
+
+	from = truncateToDateJST(from)
+	until := truncateToDateJST(to).AddDate(0, 0, 1)
+
+	// from より前に始まった活動のログを誤って孤立した end と扱わないよう、前後に余裕を持たせて取得する
+	logs, err := model.ReadLogsByEventIDAndDateRange(eventID, from.Add(-sessionOrphanAfter), until.Add(sessionOrphanAfter))
+	if err != nil {
+		return nil, SessionSummary{}, err
+	}
+
+	var sessions []Session
+	for _, s := range BuildSessions(logs, lib.NowJST()) {
+		if s.Start.Before(from) || !s.Start.Before(until) {
+			continue
+		}
+		if weekday != nil && s.Start.In(lib.JST).Weekday() != *weekday {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	if sessions == nil {
+		sessions = []Session{}
+	}
+	return sessions, summarizeSessions(sessions), nil
+}
+
+// BuildSessions は1イベント分のログを時刻順に状態遷移させ、活動セッションに畳み込む
+// 不正な遷移のログは無視し、直近のセッションに警告として記録する
+// 最後のログから sessionOrphanAfter を超えて end のない活動は orphaned とし、
+// その後の start は新しいセッションとして扱う
+func BuildSessions(logs []model.Log, now time.Time) []Session {
+	sorted := sortLogsByEventTime(logs)
+
+	var sessions []Session
+	var current *Session
+	state := SessionIdle
+	var lastEventTime time.Time
+
+	closeOrphan := func() {
+		finalizeSession(current, lastEventTime, sessionStatusOrphaned)
+		sessions = append(sessions, *current)
+		current = nil
+		state = SessionIdle
+	}
+
+	for _, l := range sorted {
+		if current != nil && l.EventTime.Sub(lastEventTime) > sessionOrphanAfter {
+			closeOrphan()
+		}
+
+		name := l.Status.Name
+		next, ok := NextSessionState(state, name)
+		if !ok {
+			warning := fmt.Sprintf("ignored %s at %s while %s", name, lib.FormatDateTime(l.EventTime), state)
+			switch {
+			case current != nil:
+				current.Warnings = append(current.Warnings, warning)
+				current.LogIDs = append(current.LogIDs, l.ID)
+			case len(sessions) > 0:
+				last := &sessions[len(sessions)-1]
+				last.Warnings = append(last.Warnings, warning)
+			}
+			continue
+		}
+
+		switch {
+		case state == SessionIdle && next == SessionActive:
+			current = &Session{
+				EventID: l.EventID,
+				Start:   l.EventTime,
+				Pauses:  []SessionPause{},
+			}
+		case next == SessionPaused:
+			current.Pauses = append(current.Pauses, SessionPause{Start: l.EventTime})
+		case state == SessionPaused && next == SessionActive:
+			resumedAt := l.EventTime
+			current.Pauses[len(current.Pauses)-1].End = &resumedAt
+		}
+		current.LogIDs = append(current.LogIDs, l.ID)
+		lastEventTime = l.EventTime
+		state = next
+
+		if next == SessionIdle {
+			finalizeSession(current, l.EventTime, sessionStatusCompleted)
+			sessions = append(sessions, *current)
+			current = nil
+		}
+	}
+
+	if current != nil {
+		if now.Sub(lastEventTime) > sessionOrphanAfter {
+			closeOrphan()
+		} else {
+			finalizeSession(current, now, sessionStatusOngoing)
+			sessions = append(sessions, *current)
+		}
+	}
+	return sessions
+}
+
+// finalizeSession はセッションを時刻 end で締めて活動時間を計算する
+// completed と orphaned では未再開の一時停止を end で閉じる。end を記録するのは completed のみ
+func finalizeSession(s *Session, end time.Time, status string) {
+	paused := 0.0
+	for i := range s.Pauses {
+		pauseEnd := end
+		if s.Pauses[i].End != nil {
+			pauseEnd = *s.Pauses[i].End
+		} else if status != sessionStatusOngoing {
+			s.Pauses[i].End = &pauseEnd
+		}
+		paused += pauseEnd.Sub(s.Pauses[i].Start).Minutes()
+	}
+	s.ActiveMinutes = end.Sub(s.Start).Minutes() - paused
+	if s.ActiveMinutes < 0 {
+		s.ActiveMinutes = 0
+	}
+	s.Status = status
+	if status == sessionStatusCompleted {
+		s.End = &end
+	}
+}
+
+// summarizeSessions は completed のセッションの活動時間を集計する
+func summarizeSessions(sessions []Session) SessionSummary {
+	summary := SessionSummary{Count: len(sessions)}
+	var minutes []float64
+	total := 0.0
+	for _, s := range sessions {
+		if s.Status != sessionStatusCompleted {
+			continue
+		}
+		minutes = append(minutes, s.ActiveMinutes)
+		total += s.ActiveMinutes
+	}
+	summary.Completed = len(minutes)
+	if len(minutes) == 0 {
+		return summary
+	}
+	sort.Float64s(minutes)
+	mid := len(minutes) / 2
+	if len(minutes)%2 == 0 {
+		summary.MedianActiveMinutes = (minutes[mid-1] + minutes[mid]) / 2
+	} else {
+		summary.MedianActiveMinutes = minutes[mid]
+	}
+	summary.AverageActiveMinutes = total / float64(len(minutes))
+	return summary
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kajiLabTeam/stay-watch-slackbot/lib"
+	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+)
+
+// sessionTransitionTests は状態とステータス名の組ごとの遷移先を表す
+// ログの検証（log_validation_test.go）でも同じ表を使う
+var sessionTransitionTests = []struct {
+	state  SessionState
+	status string
+	want   SessionState
+	ok     bool
+}{
+	{SessionIdle, statusNameStart, SessionActive, true},
+	{SessionIdle, statusNamePause, SessionIdle, false},
+	{SessionIdle, statusNamePose, SessionIdle, false},
+	{SessionIdle, statusNameEnd, SessionIdle, false},
+	{SessionActive, statusNameStart, SessionActive, false},
+	{SessionActive, statusNamePause, SessionPaused, true},
+	{SessionActive, statusNamePose, SessionPaused, true},
+	{SessionActive, statusNameEnd, SessionIdle, true},
+	{SessionPaused, statusNameStart, SessionActive, true},
+	{SessionPaused, statusNamePause, SessionPaused, false},
+	{SessionPaused, statusNamePose, SessionPaused, false},
+	{SessionPaused, statusNameEnd, SessionIdle, true},
+	{SessionIdle, "unknown", SessionIdle, false},
+	{SessionActive, "unknown", SessionActive, false},
+}
+
+func TestNextSessionState(t *testing.T) {
+	for _, tt := range sessionTransitionTests {
+		t.Run(tt.state.String()+"/"+tt.status, func(t *testing.T) {
+			got, ok := NextSessionState(tt.state, tt.status)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("NextSessionState(%s, %q) = (%s, %v), want (%s, %v)", tt.state, tt.status, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSessionStateAt(t *testing.T) {
+	base := time.Date(2025, 6, 13, 10, 0, 0, 0, lib.JST)
+
+	tests := []struct {
+		name string
+		logs []model.Log
+		at   time.Time
+		want SessionState
+	}{
+		{name: "no logs", at: base, want: SessionIdle},
+		{
+			name: "started",
+			logs: []model.Log{testLog(1, statusNameStart, base)},
+			at:   base.Add(time.Hour),
+			want: SessionActive,
+		},
+		{
+			name: "paused with legacy pose",
+			logs: []model.Log{testLog(1, statusNameStart, base), testLog(2, statusNamePose, base.Add(time.Hour))},
+			at:   base.Add(2 * time.Hour),
+			want: SessionPaused,
+		},
+		{
+			name: "ended",
+			logs: []model.Log{testLog(1, statusNameStart, base), testLog(2, statusNameEnd, base.Add(time.Hour))},
+			at:   base.Add(2 * time.Hour),
+			want: SessionIdle,
+		},
+		{
+			name: "invalid logs are ignored",
+			logs: []model.Log{testLog(1, statusNameEnd, base), testLog(2, statusNameStart, base.Add(time.Hour)), testLog(3, statusNameStart, base.Add(2*time.Hour))},
+			at:   base.Add(3 * time.Hour),
+			want: SessionActive,
+		},
+		{
+			name: "orphaned start at the boundary is still active",
+			logs: []model.Log{testLog(1, statusNameStart, base)},
+			at:   base.Add(sessionOrphanAfter),
+			want: SessionActive,
+		},
+		{
+			name: "orphaned start past the boundary",
+			logs: []model.Log{testLog(1, statusNameStart, base)},
+			at:   base.Add(sessionOrphanAfter + time.Minute),
+			want: SessionIdle,
+		},
+		{
+			name: "orphaned start is replaced by the next start",
+			logs: []model.Log{testLog(1, statusNameStart, base), testLog(2, statusNameStart, base.Add(24*time.Hour))},
+			at:   base.Add(25 * time.Hour),
+			want: SessionActive,
+		},
+		{
+			name: "end after an orphaned start is invalid",
+			logs: []model.Log{testLog(1, statusNameStart, base), testLog(2, statusNameEnd, base.Add(24*time.Hour))},
+			at:   base.Add(24 * time.Hour),
+			want: SessionIdle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionStateAt(tt.logs, tt.at); got != tt.want {
+				t.Errorf("sessionStateAt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSessions(t *testing.T) {
+	base := time.Date(2025, 6, 13, 10, 0, 0, 0, lib.JST)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+	ptr := func(d time.Duration) *time.Time { t := at(d); return &t }
+
+	tests := []struct {
+		name     string
+		logs     []model.Log
+		now      time.Time
+		want     []Session
+		warnings [][]string // セッションごとの警告に含まれる文字列
+	}{
+		{
+			name: "completed",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNameEnd, at(2*time.Hour)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(2 * time.Hour), Pauses: []SessionPause{},
+				ActiveMinutes: 120, Status: sessionStatusCompleted, LogIDs: []uint{1, 2},
+			}},
+		},
+		{
+			name: "pause and resume",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNamePause, at(time.Hour)),
+				testLog(3, statusNameStart, at(90*time.Minute)),
+				testLog(4, statusNameEnd, at(3*time.Hour)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(3 * time.Hour),
+				Pauses:        []SessionPause{{Start: at(time.Hour), End: ptr(90 * time.Minute)}},
+				ActiveMinutes: 150, Status: sessionStatusCompleted, LogIDs: []uint{1, 2, 3, 4},
+			}},
+		},
+		{
+			name: "pause without resume is closed by end",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNamePose, at(time.Hour)),
+				testLog(3, statusNameEnd, at(2*time.Hour)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(2 * time.Hour),
+				Pauses:        []SessionPause{{Start: at(time.Hour), End: ptr(2 * time.Hour)}},
+				ActiveMinutes: 60, Status: sessionStatusCompleted, LogIDs: []uint{1, 2, 3},
+			}},
+		},
+		{
+			name: "open at now",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+			},
+			now: at(90 * time.Minute),
+			want: []Session{{
+				Start: at(0), Pauses: []SessionPause{},
+				ActiveMinutes: 90, Status: sessionStatusOngoing, LogIDs: []uint{1},
+			}},
+		},
+		{
+			name: "paused at now",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNamePause, at(time.Hour)),
+			},
+			now: at(2 * time.Hour),
+			want: []Session{{
+				Start:         at(0),
+				Pauses:        []SessionPause{{Start: at(time.Hour)}},
+				ActiveMinutes: 60, Status: sessionStatusOngoing, LogIDs: []uint{1, 2},
+			}},
+		},
+		{
+			name: "orphaned at now",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNamePause, at(time.Hour)),
+			},
+			now: at(time.Hour + sessionOrphanAfter + time.Minute),
+			want: []Session{{
+				Start:         at(0),
+				Pauses:        []SessionPause{{Start: at(time.Hour), End: ptr(time.Hour)}},
+				ActiveMinutes: 60, Status: sessionStatusOrphaned, LogIDs: []uint{1, 2},
+			}},
+		},
+		{
+			name: "orphaned start is followed by a new session",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNameStart, at(23*time.Hour)),
+				testLog(3, statusNameEnd, at(24*time.Hour)),
+			},
+			now: at(48 * time.Hour),
+			want: []Session{
+				{
+					Start: at(0), Pauses: []SessionPause{},
+					ActiveMinutes: 0, Status: sessionStatusOrphaned, LogIDs: []uint{1},
+				},
+				{
+					Start: at(23 * time.Hour), End: ptr(24 * time.Hour), Pauses: []SessionPause{},
+					ActiveMinutes: 60, Status: sessionStatusCompleted, LogIDs: []uint{2, 3},
+				},
+			},
+		},
+		{
+			name: "end exactly at the orphan boundary completes the session",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNameEnd, at(sessionOrphanAfter)),
+			},
+			now: at(48 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(sessionOrphanAfter), Pauses: []SessionPause{},
+				ActiveMinutes: sessionOrphanAfter.Minutes(), Status: sessionStatusCompleted, LogIDs: []uint{1, 2},
+			}},
+		},
+		{
+			name: "end without start before any session is dropped",
+			logs: []model.Log{
+				testLog(1, statusNameEnd, at(0)),
+				testLog(2, statusNameStart, at(time.Hour)),
+				testLog(3, statusNameEnd, at(2*time.Hour)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(time.Hour), End: ptr(2 * time.Hour), Pauses: []SessionPause{},
+				ActiveMinutes: 60, Status: sessionStatusCompleted, LogIDs: []uint{2, 3},
+			}},
+		},
+		{
+			name: "duplicate end is recorded on the previous session",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNameEnd, at(time.Hour)),
+				testLog(3, statusNameEnd, at(time.Hour+time.Minute)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(time.Hour), Pauses: []SessionPause{},
+				ActiveMinutes: 60, Status: sessionStatusCompleted, LogIDs: []uint{1, 2},
+			}},
+			warnings: [][]string{{"ignored end at 2025-06-13 11:01 while idle"}},
+		},
+		{
+			name: "duplicate start is kept in the current session",
+			logs: []model.Log{
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNameStart, at(5*time.Minute)),
+				testLog(3, statusNameEnd, at(time.Hour)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(time.Hour), Pauses: []SessionPause{},
+				ActiveMinutes: 60, Status: sessionStatusCompleted, LogIDs: []uint{1, 2, 3},
+			}},
+			warnings: [][]string{{"ignored start at 2025-06-13 10:05 while active"}},
+		},
+		{
+			name: "logs are sorted by event time",
+			logs: []model.Log{
+				testLog(3, statusNameEnd, at(2*time.Hour)),
+				testLog(1, statusNameStart, at(0)),
+				testLog(2, statusNamePause, at(time.Hour)),
+			},
+			now: at(24 * time.Hour),
+			want: []Session{{
+				Start: at(0), End: ptr(2 * time.Hour),
+				Pauses:        []SessionPause{{Start: at(time.Hour), End: ptr(2 * time.Hour)}},
+				ActiveMinutes: 60, Status: sessionStatusCompleted, LogIDs: []uint{1, 2, 3},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildSessions(tt.logs, tt.now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("BuildSessions() returned %d sessions, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				var wantWarnings []string
+				if i < len(tt.warnings) {
+					wantWarnings = tt.warnings[i]
+				}
+				assertSession(t, i, got[i], tt.want[i], wantWarnings)
+			}
+		})
+	}
+}
+
+func TestSummarizeSessions(t *testing.T) {
+	sessions := []Session{
+		{ActiveMinutes: 30, Status: sessionStatusCompleted},
+		{ActiveMinutes: 90, Status: sessionStatusCompleted},
+		{ActiveMinutes: 60, Status: sessionStatusCompleted},
+		{ActiveMinutes: 100, Status: sessionStatusCompleted},
+		{ActiveMinutes: 500, Status: sessionStatusOngoing},
+		{ActiveMinutes: 0, Status: sessionStatusOrphaned},
+	}
+	want := SessionSummary{Count: 6, Completed: 4, MedianActiveMinutes: 75, AverageActiveMinutes: 70}
+	if got := summarizeSessions(sessions); got != want {
+		t.Errorf("summarizeSessions() = %+v, want %+v", got, want)
+	}
+
+	if got := summarizeSessions(nil); got != (SessionSummary{}) {
+		t.Errorf("summarizeSessions(nil) = %+v, want zero", got)
+	}
+}
+
+// testLog はイベント1のログを作る
+func testLog(id uint, status string, eventTime time.Time) model.Log {
+	return model.Log{ID: id, EventID: 1, EventTime: eventTime, Status: model.Status{Name: status}}
+}
+
+// assertSession はセッションが want と一致し、警告に wantWarnings の文字列がそれぞれ含まれるかを検証する
+func assertSession(t *testing.T, i int, got, want Session, wantWarnings []string) {
+	t.Helper()
+	if got.EventID != 1 {
+		t.Errorf("session %d: EventID = %d, want 1", i, got.EventID)
+	}
+	if !got.Start.Equal(want.Start) {
+		t.Errorf("session %d: Start = %s, want %s", i, got.Start, want.Start)
+	}
+	if !equalTimePtr(got.End, want.End) {
+		t.Errorf("session %d: End = %v, want %v", i, got.End, want.End)
+	}
+	if len(got.Pauses) != len(want.Pauses) {
+		t.Errorf("session %d: Pauses = %+v, want %+v", i, got.Pauses, want.Pauses)
+	} else {
+		for j := range got.Pauses {
+			if !got.Pauses[j].Start.Equal(want.Pauses[j].Start) || !equalTimePtr(got.Pauses[j].End, want.Pauses[j].End) {
+				t.Errorf("session %d: Pauses[%d] = %+v, want %+v", i, j, got.Pauses[j], want.Pauses[j])
+			}
+		}
+	}
+	if got.ActiveMinutes != want.ActiveMinutes {
+		t.Errorf("session %d: ActiveMinutes = %v, want %v", i, got.ActiveMinutes, want.ActiveMinutes)
+	}
+	if got.Status != want.Status {
+		t.Errorf("session %d: Status = %q, want %q", i, got.Status, want.Status)
+	}
+	if !reflect.DeepEqual(got.LogIDs, want.LogIDs) {
+		t.Errorf("session %d: LogIDs = %v, want %v", i, got.LogIDs, want.LogIDs)
+	}
+	if len(got.Warnings) != len(wantWarnings) {
+		t.Errorf("session %d: Warnings = %q, want %q", i, got.Warnings, wantWarnings)
+		return
+	}
+	for j, w := range wantWarnings {
+		if !strings.Contains(got.Warnings[j], w) {
+			t.Errorf("session %d: Warnings[%d] = %q, want to contain %q", i, j, got.Warnings[j], w)
+		}
+	}
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
@@ -15,7 +15,7 @@ func RegisterStatus(name string) (model.Status, error) {
 		// MySQLのユニーク制約エラー（1062）を型安全に判定
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-			return status, errors.New("status already exists")
+			return status, alreadyExistsError("status already exists")
 		}
 		return status, err
 	}
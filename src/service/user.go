@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/kajiLabTeam/stay-watch-slackbot/model"
+	"gorm.io/gorm"
 )
 
 func RegisterUser(slackUserID string, userName string) (model.User, error) {
@@ -17,12 +18,13 @@ func RegisterUser(slackUserID string, userName string) (model.User, error) {
 		Name:        userName,
 		SlackID:     slackUserID,
 		StayWatchID: int64(0),
+		IsAdmin:     isConfiguredAdmin(slackUserID),
 	}
 	if err := user.ReadByName(); err != nil {
 		return user, err
 	}
 	if user.StayWatchID != 0 {
-		err := errors.New("user already exists")
+		err := alreadyExistsError("user already exists")
 		return user, err
 	}
 	for i, u := range users {
@@ -31,7 +33,7 @@ func RegisterUser(slackUserID string, userName string) (model.User, error) {
 			break
 		}
 		if i == len(users)-1 {
-			err := errors.New("user not found")
+			err := notFoundError("user not found")
 			return user, err
 		}
 	}
@@ -39,19 +41,23 @@ func RegisterUser(slackUserID string, userName string) (model.User, error) {
 		return user, err
 	}
 
-	// アイコンURLをSlackから取得してDBに保存（失敗しても登録自体は成功とする）
-	if iconURL, err := fetchSlackIconURL(slackUserID); err == nil {
-		user.IconURL = iconURL
-		if err := user.UpdateIconURL(); err != nil {
-			log.Printf("failed to save icon_url for user %s: %v", slackUserID, err)
-		}
-	} else {
-		log.Printf("failed to fetch icon URL for user %s: %v", slackUserID, err)
-	}
-
+	saveSlackIconURL(&user)
 	return user, nil
 }
 
+// saveSlackIconURL はアイコンURLをSlackから取得してDBに保存する（失敗しても登録自体は成功とする）
+func saveSlackIconURL(user *model.User) {
+	iconURL, err := fetchSlackIconURL(user.SlackID)
+	if err != nil {
+		log.Printf("failed to fetch icon URL for user %s: %v", user.SlackID, err)
+		return
+	}
+	user.IconURL = iconURL
+	if err := user.UpdateIconURL(); err != nil {
+		log.Printf("failed to save icon_url for user %s: %v", user.SlackID, err)
+	}
+}
+
 // fetchSlackIconURL は Slack API からユーザのアイコン画像 URL を取得する
 func fetchSlackIconURL(slackUserID string) (string, error) {
 	slackUser, err := slackClient.GetUserInfo(slackUserID)
@@ -67,6 +73,131 @@ func ListAllUsers() ([]model.User, error) {
 	return u.ReadAll()
 }
 
+// UserInput はREST APIからのユーザー登録内容を表す
+// StayWatchID が0の場合は名前から滞在ウォッチのユーザーを検索する
+type UserInput struct {
+	Name        string
+	SlackID     string
+	StayWatchID int64
+}
+
+// UserUpdateInput はREST APIからのユーザー更新内容を表す（nilの項目は変更しない）
+type UserUpdateInput struct {
+	Name        *string
+	SlackID     *string
+	StayWatchID *int64
+}
+
+// GetUser はIDからユーザーを登録イベントを含めて取得する
+func GetUser(id uint) (model.User, error) {
+	user := model.User{}
+	user.ID = id
+	if err := user.ReadByIDWithEvents(); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, notFoundError("user not found")
+		}
+		return user, err
+	}
+	return user, nil
+}
+
+// CreateUser はREST APIからユーザーを登録する
+// SlackID・StayWatchID が既存ユーザーと重複する場合は登録しない
+func CreateUser(input UserInput) (model.User, error) {
+	user := model.User{
+		Name:        input.Name,
+		SlackID:     input.SlackID,
+		StayWatchID: input.StayWatchID,
+		IsAdmin:     isConfiguredAdmin(input.SlackID),
+	}
+
+	if user.StayWatchID == 0 {
+		members, err := GetStayWatchMember()
+		if err != nil {
+			return user, err
+		}
+		for _, m := range members {
+			if m.Name == input.Name {
+				user.StayWatchID = m.ID
+				break
+			}
+		}
+		if user.StayWatchID == 0 {
+			return user, ErrStayWatchUserNotFound
+		}
+	}
+
+	if err := checkUserConflict(0, user.SlackID, user.StayWatchID); err != nil {
+		return user, err
+	}
+	if err := user.Create(); err != nil {
+		return user, err
+	}
+
+	saveSlackIconURL(&user)
+	return user, nil
+}
+
+// UpdateUser はREST APIからユーザーの名前・SlackID・StayWatchIDを更新する
+func UpdateUser(id uint, input UserUpdateInput) (model.User, error) {
+	user, err := GetUser(id)
+	if err != nil {
+		return user, err
+	}
+
+	slackChanged := input.SlackID != nil && *input.SlackID != user.SlackID
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.SlackID != nil {
+		user.SlackID = *input.SlackID
+	}
+	if input.StayWatchID != nil {
+		user.StayWatchID = *input.StayWatchID
+	}
+
+	if err := checkUserConflict(user.ID, user.SlackID, user.StayWatchID); err != nil {
+		return user, err
+	}
+	if err := user.Update(); err != nil {
+		return user, err
+	}
+	if slackChanged {
+		saveSlackIconURL(&user)
+	}
+	return user, nil
+}
+
+// DeleteUser はIDを指定してユーザーを削除する
+func DeleteUser(id uint) error {
+	user, err := GetUser(id)
+	if err != nil {
+		return err
+	}
+	return user.Delete()
+}
+
+// checkUserConflict は SlackID・StayWatchID が自分以外のユーザーと重複していないかを確認する
+// users テーブルには UNIQUE 制約がないため、サービス層で重複を判定する
+func checkUserConflict(selfID uint, slackID string, stayWatchID int64) error {
+	bySlack := model.User{SlackID: slackID}
+	if err := bySlack.ReadBySlackID(); err != nil {
+		return err
+	}
+	if bySlack.ID != 0 && bySlack.ID != selfID {
+		return alreadyExistsError("user already exists")
+	}
+
+	byStayWatch := model.User{StayWatchID: stayWatchID}
+	if err := byStayWatch.ReadByStayWatchID(); err != nil {
+		return err
+	}
+	if byStayWatch.ID != 0 && byStayWatch.ID != selfID {
+		return alreadyExistsError("user already exists")
+	}
+	return nil
+}
+
 // DeleteUserByName は指定した名前のユーザを削除する
 func DeleteUserByName(name string) error {
 	user := model.User{Name: name}
@@ -74,7 +205,7 @@ func DeleteUserByName(name string) error {
 		return err
 	}
 	if user.ID == 0 {
-		return errors.New("user not found")
+		return notFoundError("user not found")
 	}
 	return user.Delete()
 }